@@ -10,7 +10,9 @@ import (
 	"time"
 
 	"github.com/manu/octo/pkg/api"
+	"github.com/manu/octo/pkg/checker"
 	"github.com/manu/octo/pkg/config"
+	"github.com/manu/octo/pkg/push"
 	"github.com/manu/octo/pkg/satellite"
 	"github.com/manu/octo/pkg/scheduler"
 	"github.com/manu/octo/pkg/storage/postgres"
@@ -77,6 +79,8 @@ func main() {
 
 	// 3. Initialize Scheduler
 	sched := scheduler.NewScheduler(cfgMgr, store)
+	pushMgr := push.NewManager(cfgMgr)
+	sched.RegisterProber(checker.TypePush, pushMgr)
 
 	// 4. Start Scheduler
 	go sched.Start()
@@ -91,7 +95,7 @@ func main() {
 		log.Fatalf("Failed to get embedded frontend: %v", err)
 	}
 
-	apiServer := api.NewServer(cfgMgr, store, satMgr, distFS)
+	apiServer := api.NewServer(cfgMgr, store, satMgr, pushMgr, sched, distFS)
 	srv := &http.Server{
 		Addr:    ":8080",
 		Handler: apiServer.Handler(),
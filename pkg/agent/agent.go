@@ -6,10 +6,12 @@ import (
 	"encoding/json"
 	"log"
 	"net/http"
+	"slices"
 	"time"
 
 	"github.com/manu/octo/pkg/checker"
 	"github.com/manu/octo/pkg/config"
+	"github.com/manu/octo/pkg/satellite"
 )
 
 type Agent struct {
@@ -79,9 +81,34 @@ func (a *Agent) sendHeartbeat() {
 
 	if resp.StatusCode != http.StatusOK {
 		log.Printf("Heartbeat failed with status: %d", resp.StatusCode)
+		return
+	}
+
+	// Older masters reply with an empty body
+	var reply satellite.HeartbeatResponse
+	if err := json.NewDecoder(resp.Body).Decode(&reply); err == nil && len(reply.Run) > 0 {
+		go a.runNow(reply.Run)
 	}
 }
 
+// runNow checks the given endpoints out of schedule, as requested by the master
+func (a *Agent) runNow(ids []string) {
+	endpoints, err := a.fetchConfig()
+	if err != nil {
+		log.Printf("Failed to fetch config: %v", err)
+		return
+	}
+
+	var selected []config.EndpointConfig
+	for _, ep := range endpoints {
+		if slices.Contains(ids, ep.ID) {
+			selected = append(selected, ep)
+		}
+	}
+	log.Printf("Running %d checks requested by the master...", len(selected))
+	a.check(selected)
+}
+
 func (a *Agent) executionLoop() {
 	// Initial delay
 	time.Sleep(2 * time.Second)
@@ -110,24 +137,35 @@ func (a *Agent) runChecks() {
 		return
 	}
 
+	var ids []string
+	for _, ep := range endpoints {
+		ids = append(ids, ep.ID)
+	}
+	a.Checker.RetainCookieJars(ids)
+
 	if len(endpoints) == 0 {
 		log.Println("Fetched 0 endpoints from master. Nothing to do.")
 		return
 	}
 
 	log.Printf("Running %d checks...", len(endpoints))
+	a.check(endpoints)
+}
 
-	// 2. Execute Checks (Sequential for MVP, could be parallel)
+// check runs the endpoints and pushes their results to the master
+func (a *Agent) check(endpoints []config.EndpointConfig) {
+	// Execute Checks (Sequential for MVP, could be parallel)
 	var results []checker.Result
 	ctx := context.Background()
 
 	for _, ep := range endpoints {
-		res := a.Checker.Check(ctx, ep)
-		res.SatelliteID = a.ID // Tag result with our ID
-		results = append(results, res)
+		for _, res := range a.Checker.CheckAll(ctx, ep) {
+			res.SatelliteID = a.ID // Tag result with our ID
+			results = append(results, res)
+		}
 	}
 
-	// 3. Push Results
+	// Push Results
 	if len(results) > 0 {
 		if err := a.pushResults(results); err != nil {
 			log.Printf("Failed to push results: %v", err)
@@ -4,6 +4,8 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"slices"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -82,6 +84,81 @@ func (m *Manager) Evaluate(ctx context.Context, endpoint config.EndpointConfig,
 	}
 }
 
+// defaultDomainAlertDays are the days before a domain expires at which
+// domain_expiry alerts are sent
+var defaultDomainAlertDays = []int{30, 14, 7, 1}
+
+// EvaluateDomainExpiry alerts when the domain of a domain check comes within
+// one of the domain_expiry alert_days of its expiration. Each threshold
+// fires once, only the closest one when several are crossed at once, and
+// they are reset when the domain is renewed.
+func (m *Manager) EvaluateDomainExpiry(ctx context.Context, endpoint config.EndpointConfig, result *checker.Result) {
+	if result.DomainExpiry.IsZero() {
+		return
+	}
+	cfg := m.cfgManager.GetConfig()
+
+	thresholds := slices.Clone(cfg.DomainExpiry.AlertDays)
+	if len(thresholds) == 0 {
+		thresholds = defaultDomainAlertDays
+	}
+	slices.Sort(thresholds)
+	days := domainExpiryDays(result)
+
+	crossed := -1
+	m.mu.Lock()
+	for _, threshold := range slices.Backward(thresholds) {
+		alertKey := fmt.Sprintf("%s-domain-expiry-%d", endpoint.ID, threshold)
+		if days > threshold {
+			delete(m.activeAlerts, alertKey)
+			continue
+		}
+		if !m.activeAlerts[alertKey] {
+			m.activeAlerts[alertKey] = true
+			crossed = threshold
+		}
+	}
+	m.mu.Unlock()
+	if crossed < 0 {
+		return
+	}
+
+	rule := config.AlertRule{
+		Name:      fmt.Sprintf("Domain expires within %d days", crossed),
+		Condition: fmt.Sprintf("domain_expiry_days <= %d", crossed),
+		Severity:  cfg.DomainExpiry.Severity,
+		Channels:  cfg.DomainExpiry.Channels,
+	}
+	log.Printf("Alert Triggered: %s for %s (%d days left)", rule.Name, endpoint.Name, days)
+	m.triggerChannels(ctx, rule, endpoint, result, cfg.AlertChannels)
+}
+
+// EvaluateCertTransparency alerts the cert_transparency channels when a
+// Certificate Transparency log check found certificates issued by a CA
+// outside the allow-list. Every such check alerts, as each one reports new
+// certificates.
+func (m *Manager) EvaluateCertTransparency(ctx context.Context, endpoint config.EndpointConfig, result *checker.Result) {
+	if !result.CertMismatch {
+		return
+	}
+	cfg := m.cfgManager.GetConfig()
+
+	rule := config.AlertRule{
+		Name:      "Certificate issued by an unexpected CA",
+		Condition: "cert_mismatch == true",
+		Severity:  cfg.CertTransparency.Severity,
+		Channels:  cfg.CertTransparency.Channels,
+	}
+	log.Printf("Alert Triggered: %s for %s: %s", rule.Name, endpoint.Name, result.Error)
+	m.triggerChannels(ctx, rule, endpoint, result, cfg.AlertChannels)
+}
+
+// domainExpiryDays returns the whole days left before the domain expires,
+// negative once expired
+func domainExpiryDays(result *checker.Result) int {
+	return int(time.Until(result.DomainExpiry).Hours() / 24)
+}
+
 // matchTags checks if the endpoint has all the tags defined in the rule
 func (m *Manager) matchTags(endpointTags, ruleTags map[string]string) bool {
 	if len(ruleTags) == 0 {
@@ -97,20 +174,90 @@ func (m *Manager) matchTags(endpointTags, ruleTags map[string]string) bool {
 }
 
 // checkCondition evaluates the condition string against the result
-// Supported: "success == false", "duration > 5s" (simple parsing)
+// Supported: "<field> <op> <value>", e.g. "success == false",
+// "ocsp_status == revoked", "duration > 5s", "status_code >= 500",
+// "domain_expiry_days < 30", "content_drift == true", "cert_mismatch == true"
+// or "ct_backlog > 10000"
 func (m *Manager) checkCondition(condition string, result *checker.Result) bool {
 	// Very basic parser for MVP
 	// In a real system, use an expression engine
-	cond := strings.TrimSpace(condition)
+	parts := strings.Fields(condition)
+	if len(parts) != 3 {
+		return false
+	}
+	field, op, want := parts[0], parts[1], parts[2]
 
-	if cond == "success == false" {
-		return !result.Success
+	switch field {
+	case "success":
+		return compareStrings(strconv.FormatBool(result.Success), op, want)
+	case "ocsp_status":
+		return compareStrings(result.OCSPStatus, op, want)
+	case "status_code":
+		return compareNumbers(float64(result.StatusCode), op, want, strconv.ParseFloat)
+	case "duration":
+		return compareNumbers(float64(result.Duration), op, want, parseDuration)
+	case "cert_mismatch":
+		return compareStrings(strconv.FormatBool(result.CertMismatch), op, want)
+	case "content_drift":
+		return compareStrings(strconv.FormatBool(result.ContentDrift), op, want)
+	case "content_similarity":
+		if result.ContentHash == "" {
+			return false
+		}
+		return compareNumbers(result.ContentSimilarity, op, want, strconv.ParseFloat)
+	case "ct_backlog":
+		if result.Type != checker.TypeCT {
+			return false
+		}
+		return compareNumbers(float64(result.CTBacklog), op, want, strconv.ParseFloat)
+	case "domain_expiry_days":
+		if result.DomainExpiry.IsZero() {
+			return false
+		}
+		return compareNumbers(float64(domainExpiryDays(result)), op, want, strconv.ParseFloat)
 	}
 
-	// TODO: Add more conditions (duration, etc.)
+	log.Printf("Warning: Unsupported alert condition field '%s'", field)
 	return false
 }
 
+func compareStrings(got, op, want string) bool {
+	switch op {
+	case "==":
+		return got == want
+	case "!=":
+		return got != want
+	}
+	return false
+}
+
+func compareNumbers(got float64, op, want string, parse func(string, int) (float64, error)) bool {
+	value, err := parse(want, 64)
+	if err != nil {
+		return false
+	}
+	switch op {
+	case "==":
+		return got == value
+	case "!=":
+		return got != value
+	case ">":
+		return got > value
+	case ">=":
+		return got >= value
+	case "<":
+		return got < value
+	case "<=":
+		return got <= value
+	}
+	return false
+}
+
+func parseDuration(s string, _ int) (float64, error) {
+	d, err := time.ParseDuration(s)
+	return float64(d), err
+}
+
 func (m *Manager) triggerChannels(ctx context.Context, rule config.AlertRule, endpoint config.EndpointConfig, result *checker.Result, channels []config.AlertChannel) {
 	// Map channel names to config
 	channelMap := make(map[string]config.AlertChannel)
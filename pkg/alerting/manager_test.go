@@ -110,3 +110,120 @@ alert_rules:
 		t.Errorf("Expected sent count to remain 1 (tag mismatch), got %d", mockProvider.SentCount)
 	}
 }
+
+func TestManager_CheckCondition(t *testing.T) {
+	m := &Manager{}
+	result := &checker.Result{
+		Success:    false,
+		StatusCode: 503,
+		Duration:   6 * time.Second,
+		OCSPStatus: "revoked",
+		// Half a day of margin so the count of whole days stays at 20
+		DomainExpiry: time.Now().Add(20*24*time.Hour + 12*time.Hour),
+
+		ContentHash:       "c7b7c66c45667322",
+		ContentSimilarity: 0.6,
+		ContentDrift:      true,
+		CertMismatch:      true,
+	}
+
+	tests := []struct {
+		condition string
+		want      bool
+	}{
+		{"success == false", true},
+		{"success == true", false},
+		{"ocsp_status == revoked", true},
+		{"ocsp_status == good", false},
+		{"status_code >= 500", true},
+		{"status_code < 500", false},
+		{"duration > 5s", true},
+		{"duration <= 5s", false},
+		{"domain_expiry_days < 30", true},
+		{"domain_expiry_days == 20", true},
+		{"domain_expiry_days < 7", false},
+		{"content_drift == true", true},
+		{"content_similarity < 0.8", true},
+		{"content_similarity >= 0.8", false},
+		{"cert_mismatch == true", true},
+		{"cert_mismatch == false", false},
+		{"unknown_field == 1", false},
+		{"malformed", false},
+	}
+
+	for _, tt := range tests {
+		if got := m.checkCondition(tt.condition, result); got != tt.want {
+			t.Errorf("checkCondition(%q) = %v, want %v", tt.condition, got, tt.want)
+		}
+	}
+
+	// The backlog only applies to Certificate Transparency log checks
+	ct := &checker.Result{Type: checker.TypeCT, CTBacklog: 25000}
+	if !m.checkCondition("ct_backlog > 10000", ct) || m.checkCondition("ct_backlog >= 0", result) {
+		t.Error("Expected ct_backlog to be compared for Certificate Transparency checks only")
+	}
+}
+
+func TestManager_EvaluateDomainExpiry(t *testing.T) {
+	tmpConfigFile, err := os.CreateTemp("", "config-*.yml")
+	if err != nil {
+		t.Fatalf("Failed to create temp config: %v", err)
+	}
+	defer os.Remove(tmpConfigFile.Name())
+
+	if _, err := tmpConfigFile.WriteString(`
+alert_channels:
+  - name: "test-webhook"
+    type: "webhook"
+    url: "http://localhost"
+
+domain_expiry:
+  enabled: true
+  alert_days: [7, 30]
+  channels: ["test-webhook"]
+`); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	tmpConfigFile.Close()
+
+	cfgMgr, err := config.NewManager(tmpConfigFile.Name())
+	if err != nil {
+		t.Fatalf("Failed to create config manager: %v", err)
+	}
+	am := NewManager(cfgMgr)
+	mockProvider := &MockProvider{Done: make(chan bool, 1)}
+	am.RegisterProvider("webhook", mockProvider)
+
+	endpoint := config.EndpointConfig{ID: "domain:example.com", Name: "example.com", Type: checker.TypeDomain}
+	steps := []struct {
+		daysLeft int
+		wantRule string // Empty when no alert is expected
+	}{
+		{daysLeft: 60},
+		{daysLeft: 20, wantRule: "Domain expires within 30 days"},
+		{daysLeft: 19},
+		{daysLeft: 5, wantRule: "Domain expires within 7 days"},
+		{daysLeft: 4},
+		{daysLeft: 365}, // Renewed
+		{daysLeft: 3, wantRule: "Domain expires within 7 days"},
+	}
+
+	for _, step := range steps {
+		result := &checker.Result{DomainExpiry: time.Now().Add(time.Duration(step.daysLeft)*24*time.Hour + time.Hour)}
+		am.Evaluate(context.Background(), endpoint, result)
+		am.EvaluateDomainExpiry(context.Background(), endpoint, result)
+
+		select {
+		case <-mockProvider.Done:
+			if step.wantRule == "" {
+				t.Errorf("%d days left: unexpected alert %q", step.daysLeft, mockProvider.LastRule.Name)
+			} else if mockProvider.LastRule.Name != step.wantRule {
+				t.Errorf("%d days left: expected alert %q, got %q", step.daysLeft, step.wantRule, mockProvider.LastRule.Name)
+			}
+		case <-time.After(200 * time.Millisecond):
+			if step.wantRule != "" {
+				t.Errorf("%d days left: expected alert %q, got none", step.daysLeft, step.wantRule)
+			}
+		}
+	}
+}
@@ -26,6 +26,14 @@ func (m *MockStorage) QueryHistory(ctx context.Context, endpointID string, from,
 	return []storage.Metric{}, nil
 }
 
+func (m *MockStorage) QueryContent(ctx context.Context, endpointID, satelliteID string, limit int) ([]storage.ContentSnapshot, error) {
+	return nil, nil
+}
+
+func (m *MockStorage) QueryState(ctx context.Context, endpointID, ipVersion string) (storage.CheckState, error) {
+	return storage.CheckState{}, nil
+}
+
 func (m *MockStorage) Close() {}
 
 func TestAuthWorkflow(t *testing.T) {
@@ -67,7 +75,7 @@ alert_rules: []
 
 	// 3. Initialize Server with Mock Storage
 	mockStorage := &MockStorage{}
-	server := NewServer(cfgMgr, mockStorage, nil, nil) // frontendFS is nil for API tests
+	server := NewServer(cfgMgr, mockStorage, nil, nil, nil, nil) // frontendFS is nil for API tests
 
 	// 4. Test Login (Success)
 	loginPayload := map[string]string{
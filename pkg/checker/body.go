@@ -0,0 +1,237 @@
+package checker
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"hash"
+	"io"
+	"regexp"
+
+	"github.com/manu/octo/pkg/config"
+)
+
+// defaultMaxBodyBytes is the part of a response body kept in memory when
+// max_body_bytes is not set
+const defaultMaxBodyBytes = 10 << 20
+
+// bodyStream reads a response body, keeping at most limit bytes in memory.
+// The streaming validators (checksum, and substring or regex content
+// matches) see the whole body; the others need it to fit under the limit.
+type bodyStream struct {
+	limit     int64
+	buf       bytes.Buffer
+	n         int64
+	truncated bool
+
+	checksum        hash.Hash
+	match, notMatch streamMatcher
+	sized           bool // a body_size assertion needs the full length
+	writers         []io.Writer
+}
+
+// newBodyStream prepares the streaming validators of an endpoint
+func newBodyStream(endpoint config.EndpointConfig) (*bodyStream, error) {
+	s := &bodyStream{limit: endpoint.MaxBodyBytes}
+	if s.limit <= 0 {
+		s.limit = defaultMaxBodyBytes
+	}
+
+	v := endpoint.Validation
+	if v.Checksum.Value != "" {
+		h, err := newChecksumHash(v.Checksum.Algorithm)
+		if err != nil {
+			return nil, err
+		}
+		s.checksum = h
+		s.writers = append(s.writers, h)
+	}
+
+	var err error
+	if v.ContentMatch.Pattern != "" {
+		if s.match, err = newStreamMatcher(v.ContentMatch); err != nil {
+			return nil, err
+		}
+	}
+	if v.ContentNotMatch.Pattern != "" {
+		if s.notMatch, err = newStreamMatcher(v.ContentNotMatch); err != nil {
+			s.close()
+			return nil, err
+		}
+	}
+	for _, m := range []streamMatcher{s.match, s.notMatch} {
+		if m != nil {
+			s.writers = append(s.writers, m)
+		}
+	}
+	s.sized = v.BodySize.Min > 0 || v.BodySize.Max > 0
+	return s, nil
+}
+
+// readFrom consumes r. Once over the limit, reading stops unless a
+// streaming validator still needs the rest of the body.
+func (s *bodyStream) readFrom(r io.Reader) error {
+	chunk := make([]byte, 32<<10)
+	for {
+		k, err := r.Read(chunk)
+		if k > 0 {
+			s.write(chunk[:k])
+			if s.truncated && len(s.writers) == 0 && !s.sized {
+				return nil
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (s *bodyStream) write(p []byte) {
+	s.n += int64(len(p))
+	if room := s.limit - int64(s.buf.Len()); room > 0 {
+		s.buf.Write(p[:min(room, int64(len(p)))])
+	}
+	if s.n > s.limit {
+		s.truncated = true
+	}
+	for _, w := range s.writers {
+		w.Write(p)
+	}
+}
+
+// close ends the streaming matchers; their outcome is known afterwards
+func (s *bodyStream) close() {
+	for _, m := range []streamMatcher{s.match, s.notMatch} {
+		if m != nil {
+			m.Close()
+		}
+	}
+}
+
+// bytes returns the part of the body kept in memory
+func (s *bodyStream) bytes() []byte {
+	return s.buf.Bytes()
+}
+
+// whole fails when a validator needing the full body only has part of it
+func (s *bodyStream) whole(validator string) error {
+	if s.truncated {
+		return fmt.Errorf("%s needs the whole body, truncated at max_body_bytes %d", validator, s.limit)
+	}
+	return nil
+}
+
+// matchContent evaluates the content match on the stream, or on the body
+// for the types that parse a document
+func (s *bodyStream) matchContent(cm config.ContentMatch) error {
+	if s.match == nil {
+		if err := s.whole(cm.Type + " content match"); err != nil {
+			return err
+		}
+		return matchContent(cm, s.bytes())
+	}
+	if s.match.Matched() {
+		return nil
+	}
+	if cm.Type == "regex" {
+		return fmt.Errorf("content regex match failed")
+	}
+	return fmt.Errorf("content string match failed")
+}
+
+// matchContentAbsent is the streaming counterpart of matchContentAbsent
+func (s *bodyStream) matchContentAbsent(cm config.ContentMatch) error {
+	if s.notMatch == nil {
+		if err := s.whole(cm.Type + " negative content match"); err != nil {
+			return err
+		}
+		return matchContentAbsent(cm, s.bytes())
+	}
+	if s.notMatch.Matched() {
+		return fmt.Errorf("negative content match failed: body matches %q", cm.Pattern)
+	}
+	return nil
+}
+
+// streamMatcher looks for a pattern in the body as it is written
+type streamMatcher interface {
+	io.Writer
+	Close()
+	Matched() bool
+}
+
+// newStreamMatcher returns the streaming matcher of a substring or regex
+// content match, or nil for the types that need the whole document
+func newStreamMatcher(cm config.ContentMatch) (streamMatcher, error) {
+	switch cm.Type {
+	case "", "exact":
+		return &substringMatcher{pattern: []byte(cm.Pattern)}, nil
+	case "regex":
+		re, err := regexp.Compile(cm.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regex: %w", err)
+		}
+		return newRegexMatcher(re), nil
+	}
+	return nil, nil
+}
+
+// substringMatcher keeps the end of the previous write so a pattern split
+// across writes is found
+type substringMatcher struct {
+	pattern []byte
+	tail    []byte
+	found   bool
+}
+
+func (m *substringMatcher) Write(p []byte) (int, error) {
+	if m.found {
+		return len(p), nil
+	}
+	window := append(m.tail, p...)
+	if bytes.Contains(window, m.pattern) {
+		m.found = true
+		m.tail = nil
+		return len(p), nil
+	}
+	keep := min(len(m.pattern)-1, len(window))
+	m.tail = append(m.tail[:0], window[len(window)-keep:]...)
+	return len(p), nil
+}
+
+func (m *substringMatcher) Close()        {}
+func (m *substringMatcher) Matched() bool { return m.found }
+
+// regexMatcher runs the regex over the stream in a goroutine fed through a
+// pipe. Once decided it stops reading and later writes are dropped.
+type regexMatcher struct {
+	pw      *io.PipeWriter
+	done    chan struct{}
+	matched bool
+}
+
+func newRegexMatcher(re *regexp.Regexp) *regexMatcher {
+	pr, pw := io.Pipe()
+	m := &regexMatcher{pw: pw, done: make(chan struct{})}
+	go func() {
+		defer close(m.done)
+		m.matched = re.MatchReader(bufio.NewReader(pr))
+		pr.Close()
+	}()
+	return m
+}
+
+func (m *regexMatcher) Write(p []byte) (int, error) {
+	m.pw.Write(p)
+	return len(p), nil
+}
+
+func (m *regexMatcher) Close() {
+	m.pw.Close()
+	<-m.done
+}
+
+func (m *regexMatcher) Matched() bool { return m.matched }
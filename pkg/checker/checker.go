@@ -0,0 +1,380 @@
+package checker
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/manu/octo/pkg/config"
+)
+
+// Built-in check types, selected by EndpointConfig.Type
+const (
+	TypeHTTP      = "http"
+	TypeTCP       = "tcp"
+	TypeDNS       = "dns"
+	TypeTLS       = "tls"
+	TypeGRPC      = "grpc"
+	TypeWebSocket = "websocket"
+	TypeSSE       = "sse"
+	TypePostgres  = "postgres"
+	TypeMySQL     = "mysql"
+	TypeRedis     = "redis"
+	TypeSMTP      = "smtp"
+	TypeIMAP      = "imap"
+	TypeFTP       = "ftp"
+	TypeSFTP      = "sftp"
+	TypePage      = "page"
+	TypeDomain    = "domain"
+	TypeCT        = "ct"
+
+	// TypePush endpoints are pinged by the monitored job. Their prober is
+	// registered by the scheduler, as it needs the received pings.
+	TypePush = "push"
+)
+
+// Prober runs one type of check against an endpoint
+type Prober interface {
+	Probe(ctx context.Context, endpoint config.EndpointConfig) Result
+}
+
+// ProberFunc adapts a function to the Prober interface
+type ProberFunc func(ctx context.Context, endpoint config.EndpointConfig) Result
+
+func (f ProberFunc) Probe(ctx context.Context, endpoint config.EndpointConfig) Result {
+	return f(ctx, endpoint)
+}
+
+// Result holds the metrics of a check. All check types share this shape;
+// fields that do not apply to a type are left at their zero value.
+type Result struct {
+	Timestamp     time.Time     `json:"timestamp"`
+	EndpointID    string        `json:"endpoint_id"`
+	SatelliteID   string        `json:"satellite_id"`
+	Type          string        `json:"type,omitempty"`
+	URL           string        `json:"url"`
+	Method        string        `json:"method"`
+	StatusCode    int           `json:"status_code"`
+	Duration      time.Duration `json:"duration"`
+	DNSDuration   time.Duration `json:"dns_duration"`
+	ConnDuration  time.Duration `json:"conn_duration"`
+	TLSDuration   time.Duration `json:"tls_duration"`
+	TTFB          time.Duration `json:"ttfb"`
+	BytesReceived int64         `json:"bytes_received"`
+	BodyTruncated bool          `json:"body_truncated,omitempty"` // Only the first max_body_bytes were kept
+	Success       bool          `json:"success"`
+	Error         string        `json:"error"`
+
+	// HTTP phases around TTFB: writing the request once a connection is
+	// available, and reading the body after the first response byte.
+	// ConnReused is set when a kept-alive connection skipped DNS, connect
+	// and TLS.
+	WriteDuration    time.Duration `json:"write_duration"`
+	TransferDuration time.Duration `json:"transfer_duration"`
+	ConnReused       bool          `json:"conn_reused,omitempty"`
+
+	// Negotiated HTTP protocol, e.g. "HTTP/2.0"
+	Protocol string `json:"protocol,omitempty"`
+
+	// Address family the check was restricted to, "4" or "6"
+	IPVersion string `json:"ip_version,omitempty"`
+
+	// Proxy that carried the check, without credentials
+	Proxy string `json:"proxy,omitempty"`
+
+	// SSL/TLS Info
+	CertExpiry    time.Time `json:"cert_expiry"`
+	CertIssuer    string    `json:"cert_issuer"`
+	CertSubject   string    `json:"cert_subject"`
+	CertNotBefore time.Time `json:"cert_not_before"`
+	CertNotAfter  time.Time `json:"cert_not_after"`
+
+	// Negotiated connection and full presented chain
+	TLSVersion      string     `json:"tls_version,omitempty"`
+	CipherSuite     string     `json:"cipher_suite,omitempty"`
+	ALPN            string     `json:"alpn,omitempty"`
+	CertSANs        []string   `json:"cert_sans,omitempty"`
+	CertKeyType     string     `json:"cert_key_type,omitempty"`
+	CertKeySize     int        `json:"cert_key_size,omitempty"`
+	CertFingerprint string     `json:"cert_fingerprint,omitempty"`
+	CertChain       []CertInfo `json:"cert_chain,omitempty"`
+
+	// Revocation status of the leaf certificate ("good", "revoked" or "unknown")
+	OCSPStatus  string `json:"ocsp_status,omitempty"`
+	OCSPStapled bool   `json:"ocsp_stapled,omitempty"`
+	OCSPError   string `json:"ocsp_error,omitempty"`
+
+	// The certificate does not match the allowed issuers or SPKI pins, or a
+	// Certificate Transparency log holds one issued by another CA
+	CertMismatch bool `json:"cert_mismatch,omitempty"`
+
+	// DNS check answers
+	DNSAnswers []string `json:"dns_answers,omitempty"`
+
+	// Status reported by the gRPC health service (e.g. "SERVING")
+	HealthStatus string `json:"health_status,omitempty"`
+
+	// First value returned by the probe query of a datastore check, or the
+	// size of the listing of a file transfer check
+	QueryResult string `json:"query_result,omitempty"`
+
+	// Greeting sent by SMTP, IMAP and SSH servers
+	Banner string `json:"banner,omitempty"`
+
+	// Per-step outcome of a multi-step check, and the name of the step that failed
+	Steps      []StepResult `json:"steps,omitempty"`
+	FailedStep string       `json:"failed_step,omitempty"`
+
+	// Subresources of a page check, the total bytes of the page and its
+	// assets, and the time to load them all
+	Assets           []AssetResult `json:"assets,omitempty"`
+	PageWeight       int64         `json:"page_weight,omitempty"`
+	PageLoadDuration time.Duration `json:"page_load_duration,omitempty"`
+
+	// Registration of the domain of a domain check
+	DomainExpiry time.Time `json:"domain_expiry,omitzero"`
+	Registrar    string    `json:"registrar,omitempty"`
+
+	// Certificates for the watched domains among the entries read by a
+	// Certificate Transparency log check
+	CTEntries []CTEntry `json:"ct_entries,omitempty"`
+	// Index of the next log entry to read, and entries left to read once
+	// the check ran out of time
+	CTPosition uint64 `json:"ct_position,omitempty"`
+	CTBacklog  uint64 `json:"ct_backlog,omitempty"`
+
+	// Custom metrics returned by a validation script
+	Metrics map[string]float64 `json:"metrics,omitempty"`
+
+	// Drift detection: SimHash of the normalized body, its similarity to the
+	// reference body, whether it changed beyond the threshold, and the
+	// normalized body when it changed since the last check
+	ContentHash       string  `json:"content_hash,omitempty"`
+	ContentSimilarity float64 `json:"content_similarity,omitempty"`
+	ContentDrift      bool    `json:"content_drift,omitempty"`
+	Content           string  `json:"content,omitempty"`
+
+	// Response of a failed HTTP check, kept to see what the server returned
+	Snapshot *Snapshot `json:"snapshot,omitempty"`
+}
+
+// Checker runs checks, dispatching each endpoint to the prober registered
+// for its type
+type Checker struct {
+	client  *http.Client
+	schemas schemaCache
+	probers map[string]Prober
+	mu      sync.RWMutex
+
+	// Shared transports restricted to one HTTP version, keyed by http_version
+	transports map[string]*http.Transport
+
+	// Cookie jars of endpoints with cookie_jar "persistent", keyed by ID
+	jars map[string]http.CookieJar
+
+	// RDAP servers of each TLD, loaded on the first domain check
+	rdap *rdapBootstrap
+
+	// Reference and last bodies of endpoints with drift detection
+	drift map[driftKey]driftState
+
+	// Index of the next entry to read from each Certificate Transparency
+	// log, keyed by endpoint ID
+	ctPositions map[string]uint64
+}
+
+func NewChecker() *Checker {
+	c := &Checker{
+		client: &http.Client{
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				if len(via) >= 10 {
+					return http.ErrUseLastResponse
+				}
+				return nil
+			},
+			Transport: &http.Transport{
+				MaxIdleConns:        100,
+				MaxIdleConnsPerHost: 10,
+				IdleConnTimeout:     90 * time.Second,
+				TLSClientConfig:     &tls.Config{InsecureSkipVerify: false}, // TODO: make configurable
+				ForceAttemptHTTP2:   true,
+				Proxy:               proxyRequest,
+				DialContext: func(ctx context.Context, network, address string) (net.Conn, error) {
+					return dialerFrom(ctx).DialContext(ctx, network, address)
+				},
+			},
+		},
+		probers:     make(map[string]Prober),
+		transports:  make(map[string]*http.Transport),
+		jars:        make(map[string]http.CookieJar),
+		drift:       make(map[driftKey]driftState),
+		ctPositions: make(map[string]uint64),
+	}
+
+	c.RegisterProber(TypeHTTP, ProberFunc(c.checkHTTP))
+	c.RegisterProber(TypeTCP, ProberFunc(c.checkTCP))
+	c.RegisterProber(TypeDNS, ProberFunc(c.checkDNS))
+	c.RegisterProber(TypeTLS, ProberFunc(c.checkTLS))
+	c.RegisterProber(TypeGRPC, ProberFunc(c.checkGRPC))
+	c.RegisterProber(TypeWebSocket, ProberFunc(c.checkWebSocket))
+	c.RegisterProber(TypeSSE, ProberFunc(c.checkSSE))
+	c.RegisterProber(TypePostgres, ProberFunc(c.checkPostgres))
+	c.RegisterProber(TypeMySQL, ProberFunc(c.checkMySQL))
+	c.RegisterProber(TypeRedis, ProberFunc(c.checkRedis))
+	c.RegisterProber(TypeSMTP, ProberFunc(c.checkSMTP))
+	c.RegisterProber(TypeIMAP, ProberFunc(c.checkIMAP))
+	c.RegisterProber(TypeFTP, ProberFunc(c.checkFTP))
+	c.RegisterProber(TypeSFTP, ProberFunc(c.checkSFTP))
+	c.RegisterProber(TypePage, ProberFunc(c.checkPage))
+	c.RegisterProber(TypeDomain, ProberFunc(c.checkDomain))
+	c.RegisterProber(TypeCT, ProberFunc(c.checkCT))
+
+	return c
+}
+
+// RegisterProber registers the implementation for a check type,
+// replacing any prober previously registered for it
+func (c *Checker) RegisterProber(checkType string, p Prober) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.probers[checkType] = p
+}
+
+// Check runs the endpoint with the prober registered for its type
+func (c *Checker) Check(ctx context.Context, endpoint config.EndpointConfig) Result {
+	checkType := endpointType(endpoint)
+
+	c.mu.RLock()
+	p, ok := c.probers[checkType]
+	c.mu.RUnlock()
+
+	if !ok {
+		result := newResult(endpoint)
+		result.Error = fmt.Sprintf("unknown check type %q", checkType)
+		return result
+	}
+
+	dialer, err := newNetDialer(endpoint)
+	if err != nil {
+		result := newResult(endpoint)
+		result.Error = err.Error()
+		return result
+	}
+
+	result := p.Probe(withDialer(ctx, dialer), endpoint)
+	result.Type = checkType
+	return result
+}
+
+func endpointType(endpoint config.EndpointConfig) string {
+	if endpoint.Type == "" {
+		return TypeHTTP
+	}
+	return endpoint.Type
+}
+
+// newResult initializes the fields every check type reports
+func newResult(endpoint config.EndpointConfig) Result {
+	return Result{
+		Timestamp:  time.Now(),
+		EndpointID: endpoint.ID,
+		Type:       endpointType(endpoint),
+		URL:        endpoint.URL,
+		Method:     endpoint.Method,
+		IPVersion:  endpoint.IPVersion,
+	}
+}
+
+// httpClient returns the client for an HTTP check. Unless the endpoint opts
+// out of fresh connections, it uses a clone of the shared transport so every
+// check pays for DNS, connect and TLS; release closes those connections.
+// Endpoints with custom resolution always get fresh connections, as pooled
+// ones may lead to another address.
+func (c *Checker) httpClient(ctx context.Context, endpoint config.EndpointConfig) (*http.Client, func(), error) {
+	protocols, err := httpProtocols(endpoint.HTTPVersion)
+	if err != nil {
+		return nil, nil, err
+	}
+	transport, ok := c.client.Transport.(*http.Transport)
+	if !ok {
+		return c.client, func() {}, nil
+	}
+
+	if !endpoint.UsesFreshConnection() && !dialerFrom(ctx).custom() {
+		if protocols == nil {
+			return c.client, func() {}, nil
+		}
+		return c.withTransport(c.versionTransport(endpoint.HTTPVersion, transport, protocols)), func() {}, nil
+	}
+
+	fresh := cloneTransport(transport, protocols)
+	return c.withTransport(fresh), fresh.CloseIdleConnections, nil
+}
+
+// versionTransport returns the shared transport restricted to one HTTP version
+func (c *Checker) versionTransport(version string, base *http.Transport, protocols *http.Protocols) *http.Transport {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if t, ok := c.transports[version]; ok {
+		return t
+	}
+	t := cloneTransport(base, protocols)
+	c.transports[version] = t
+	return t
+}
+
+// cloneTransport copies base, optionally restricting its protocols. The ALPN
+// list is reset as base may have added "h2" to it; the clone advertises only
+// what it can speak.
+func cloneTransport(base *http.Transport, protocols *http.Protocols) *http.Transport {
+	t := base.Clone()
+	if protocols != nil {
+		t.Protocols = protocols
+	}
+	if t.TLSClientConfig != nil {
+		t.TLSClientConfig.NextProtos = nil
+	}
+	return t
+}
+
+func (c *Checker) withTransport(transport http.RoundTripper) *http.Client {
+	client := *c.client
+	client.Transport = transport
+	return &client
+}
+
+// httpProtocols maps an http_version setting to the protocols a transport
+// may negotiate. Nil means the transport default (HTTP/2 with HTTP/1.1
+// fallback).
+func httpProtocols(version string) (*http.Protocols, error) {
+	var p http.Protocols
+	switch version {
+	case "", "auto":
+		return nil, nil
+	case "1.1":
+		p.SetHTTP1(true)
+	case "2":
+		p.SetHTTP2(true)
+	case "h2c":
+		p.SetUnencryptedHTTP2(true)
+	default:
+		return nil, fmt.Errorf("unsupported http_version %q", version)
+	}
+	return &p, nil
+}
+
+// tlsConfig returns a copy of the client TLS settings for non-HTTP probers
+func (c *Checker) tlsConfig(serverName string) *tls.Config {
+	cfg := &tls.Config{}
+	if transport, ok := c.client.Transport.(*http.Transport); ok && transport.TLSClientConfig != nil {
+		cfg = transport.TLSClientConfig.Clone()
+	}
+	cfg.ServerName = serverName
+	// The HTTP transport adds "h2" to its config; other protocols negotiate their own
+	cfg.NextProtos = nil
+	return cfg
+}
@@ -0,0 +1,110 @@
+package checker
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+
+	"github.com/andybalholm/cascadia"
+	"github.com/antchfx/xmlquery"
+	"github.com/antchfx/xpath"
+	"golang.org/x/net/html"
+
+	"github.com/manu/octo/pkg/config"
+)
+
+// matchContent evaluates a content match against the response body.
+// Supported types: "regex", "xpath" (XML documents), "css" (HTML documents)
+// and substring matching for "exact" or an empty type.
+func matchContent(cm config.ContentMatch, body []byte) error {
+	matched, err := contentMatches(cm, body)
+	if err != nil {
+		return err
+	}
+	if matched {
+		return nil
+	}
+
+	switch cm.Type {
+	case "regex":
+		return fmt.Errorf("content regex match failed")
+	case "xpath":
+		return fmt.Errorf("content xpath match failed: %s selected nothing", cm.Pattern)
+	case "css":
+		return fmt.Errorf("content css match failed: %s matched no elements", cm.Pattern)
+	default:
+		return fmt.Errorf("content string match failed")
+	}
+}
+
+// matchContentAbsent is the negation of matchContent: the body must not match
+func matchContentAbsent(cm config.ContentMatch, body []byte) error {
+	matched, err := contentMatches(cm, body)
+	if err != nil {
+		return err
+	}
+	if matched {
+		return fmt.Errorf("negative content match failed: body matches %q", cm.Pattern)
+	}
+	return nil
+}
+
+// contentMatches reports whether the body matches. Errors are reserved for
+// invalid patterns and malformed documents.
+func contentMatches(cm config.ContentMatch, body []byte) (bool, error) {
+	switch cm.Type {
+	case "regex":
+		matched, err := regexp.Match(cm.Pattern, body)
+		if err != nil {
+			return false, fmt.Errorf("invalid regex: %w", err)
+		}
+		return matched, nil
+	case "xpath":
+		return matchXPath(cm.Pattern, body)
+	case "css":
+		return matchCSS(cm.Pattern, body)
+	default:
+		return bytes.Contains(body, []byte(cm.Pattern)), nil
+	}
+}
+
+// matchXPath matches when the expression selects at least one node, or when it
+// evaluates to true, a non-zero number or a non-empty string
+func matchXPath(expr string, body []byte) (bool, error) {
+	compiled, err := xpath.Compile(expr)
+	if err != nil {
+		return false, fmt.Errorf("invalid xpath %q: %w", expr, err)
+	}
+
+	doc, err := xmlquery.Parse(bytes.NewReader(body))
+	if err != nil {
+		return false, fmt.Errorf("malformed XML document: %w", err)
+	}
+
+	switch v := compiled.Evaluate(xmlquery.CreateXPathNavigator(doc)).(type) {
+	case bool:
+		return v, nil
+	case float64:
+		return v != 0, nil
+	case string:
+		return v != "", nil
+	case *xpath.NodeIterator:
+		return v.MoveNext(), nil
+	}
+	return false, nil
+}
+
+// matchCSS matches when the selector selects at least one element
+func matchCSS(selector string, body []byte) (bool, error) {
+	sel, err := cascadia.Compile(selector)
+	if err != nil {
+		return false, fmt.Errorf("invalid css selector %q: %w", selector, err)
+	}
+
+	doc, err := html.Parse(bytes.NewReader(body))
+	if err != nil {
+		return false, fmt.Errorf("malformed HTML document: %w", err)
+	}
+
+	return sel.MatchFirst(doc) != nil, nil
+}
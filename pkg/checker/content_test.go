@@ -0,0 +1,64 @@
+package checker
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/manu/octo/pkg/config"
+)
+
+const testSOAPBody = `<?xml version="1.0"?>
+<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/">
+  <soap:Body>
+    <GetStatusResponse><Status>OK</Status><Count>3</Count></GetStatusResponse>
+  </soap:Body>
+</soap:Envelope>`
+
+const testHTMLBody = `<!DOCTYPE html>
+<html><body>
+  <div class="cart"><button id="checkout" class="btn primary">Checkout</button></div>
+</body></html>`
+
+func TestMatchContent(t *testing.T) {
+	tests := []struct {
+		name    string
+		cm      config.ContentMatch
+		body    string
+		wantErr string
+	}{
+		{name: "substring", cm: config.ContentMatch{Pattern: "Checkout"}, body: testHTMLBody},
+		{name: "substring miss", cm: config.ContentMatch{Type: "exact", Pattern: "Sold out"}, body: testHTMLBody, wantErr: "content string match failed"},
+		{name: "regex", cm: config.ContentMatch{Type: "regex", Pattern: `id="check\w+"`}, body: testHTMLBody},
+		{name: "invalid regex", cm: config.ContentMatch{Type: "regex", Pattern: `(`}, body: testHTMLBody, wantErr: "invalid regex"},
+
+		{name: "xpath node", cm: config.ContentMatch{Type: "xpath", Pattern: "//GetStatusResponse/Status"}, body: testSOAPBody},
+		{name: "xpath boolean", cm: config.ContentMatch{Type: "xpath", Pattern: "//Status = 'OK'"}, body: testSOAPBody},
+		{name: "xpath number", cm: config.ContentMatch{Type: "xpath", Pattern: "number(//Count) > 2"}, body: testSOAPBody},
+		{name: "xpath miss", cm: config.ContentMatch{Type: "xpath", Pattern: "//Fault"}, body: testSOAPBody, wantErr: "//Fault selected nothing"},
+		{name: "xpath false", cm: config.ContentMatch{Type: "xpath", Pattern: "//Status = 'DOWN'"}, body: testSOAPBody, wantErr: "content xpath match failed"},
+		{name: "xpath invalid expression", cm: config.ContentMatch{Type: "xpath", Pattern: "//["}, body: testSOAPBody, wantErr: "invalid xpath"},
+		{name: "xpath malformed document", cm: config.ContentMatch{Type: "xpath", Pattern: "//Status"}, body: "<a><b></a>", wantErr: "malformed XML document"},
+
+		{name: "css", cm: config.ContentMatch{Type: "css", Pattern: "div.cart button#checkout"}, body: testHTMLBody},
+		{name: "css miss", cm: config.ContentMatch{Type: "css", Pattern: "button.disabled"}, body: testHTMLBody, wantErr: "button.disabled matched no elements"},
+		{name: "css invalid selector", cm: config.ContentMatch{Type: "css", Pattern: "div[["}, body: testHTMLBody, wantErr: "invalid css selector"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := matchContent(tt.cm, []byte(tt.body))
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Expected match, got: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("Expected error containing %q, got nil", tt.wantErr)
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got %q", tt.wantErr, err.Error())
+			}
+		})
+	}
+}
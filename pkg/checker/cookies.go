@@ -0,0 +1,125 @@
+package checker
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/cookiejar"
+	"slices"
+	"strings"
+
+	"golang.org/x/net/publicsuffix"
+
+	"github.com/manu/octo/pkg/config"
+)
+
+// Cookie jar scopes
+const (
+	CookieJarCheck      = "check"
+	CookieJarPersistent = "persistent"
+)
+
+// cookieJar returns the jar of an endpoint: a new one for every check, or
+// the one kept for its ID across checks. Nil means no jar.
+func (c *Checker) cookieJar(endpoint config.EndpointConfig) (http.CookieJar, error) {
+	switch endpoint.CookieJar {
+	case "":
+		return nil, nil
+	case CookieJarCheck:
+		return cookiejar.New(&cookiejar.Options{PublicSuffixList: publicsuffix.List})
+	case CookieJarPersistent:
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if jar, ok := c.jars[endpoint.ID]; ok {
+			return jar, nil
+		}
+		jar, err := cookiejar.New(&cookiejar.Options{PublicSuffixList: publicsuffix.List})
+		if err != nil {
+			return nil, err
+		}
+		c.jars[endpoint.ID] = jar
+		return jar, nil
+	}
+	return nil, fmt.Errorf("unsupported cookie_jar %q", endpoint.CookieJar)
+}
+
+// RetainCookieJars drops the persistent jars of endpoints other than ids,
+// so sessions of removed endpoints are not kept for the process lifetime
+func (c *Checker) RetainCookieJars(ids []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for id := range c.jars {
+		if !slices.Contains(ids, id) {
+			delete(c.jars, id)
+		}
+	}
+}
+
+// recordCookies returns a copy of client that collects the cookies set by
+// redirect responses into cookies, the final response being left to the caller
+func recordCookies(client *http.Client, cookies *[]*http.Cookie) *http.Client {
+	recording := *client
+	recording.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if req.Response != nil {
+			*cookies = append(*cookies, req.Response.Cookies()...)
+		}
+		return checkRedirect(client, req, via)
+	}
+	return &recording
+}
+
+// validateCookies evaluates the cookie assertions against the cookies set
+// along the way. A cookie set more than once is judged by its last value.
+func validateCookies(assertions []config.CookieAssertion, cookies []*http.Cookie) error {
+	if len(assertions) == 0 {
+		return nil
+	}
+
+	last := make(map[string]*http.Cookie)
+	var names []string
+	for _, cookie := range cookies {
+		if _, ok := last[cookie.Name]; !ok {
+			names = append(names, cookie.Name)
+		}
+		last[cookie.Name] = cookie
+	}
+
+	for _, a := range assertions {
+		checked := names
+		if a.Name != "" {
+			if _, ok := last[a.Name]; !ok {
+				return fmt.Errorf("cookie assertion failed: %s was not set", a.Name)
+			}
+			checked = []string{a.Name}
+		}
+		for _, name := range checked {
+			cookie := last[name]
+			if a.Secure && !cookie.Secure {
+				return fmt.Errorf("cookie assertion failed: %s is not Secure", name)
+			}
+			if a.HTTPOnly && !cookie.HttpOnly {
+				return fmt.Errorf("cookie assertion failed: %s is not HttpOnly", name)
+			}
+			if a.SameSite != "" && !strings.EqualFold(sameSiteName(cookie.SameSite), a.SameSite) {
+				got := sameSiteName(cookie.SameSite)
+				if got == "" {
+					got = "unset"
+				}
+				return fmt.Errorf("cookie assertion failed: %s has SameSite %s, expected %s", name, got, a.SameSite)
+			}
+		}
+	}
+	return nil
+}
+
+// sameSiteName returns the attribute value as written in Set-Cookie
+func sameSiteName(mode http.SameSite) string {
+	switch mode {
+	case http.SameSiteLaxMode:
+		return "Lax"
+	case http.SameSiteStrictMode:
+		return "Strict"
+	case http.SameSiteNoneMode:
+		return "None"
+	}
+	return ""
+}
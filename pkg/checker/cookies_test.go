@@ -0,0 +1,118 @@
+package checker
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/manu/octo/pkg/config"
+)
+
+// newLoginServer serves /app to clients holding the session cookie and
+// otherwise redirects them through /login, which sets it
+func newLoginServer(logins *atomic.Int32) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/app", func(w http.ResponseWriter, r *http.Request) {
+		if _, err := r.Cookie("session"); err != nil {
+			http.Redirect(w, r, "/login", http.StatusFound)
+			return
+		}
+		w.Write([]byte("welcome"))
+	})
+	mux.HandleFunc("/login", func(w http.ResponseWriter, r *http.Request) {
+		logins.Add(1)
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "s1", Path: "/", Secure: true, HttpOnly: true, SameSite: http.SameSiteLaxMode})
+		http.SetCookie(w, &http.Cookie{Name: "theme", Value: "dark", Path: "/"})
+		http.Redirect(w, r, "/app", http.StatusFound)
+	})
+	return httptest.NewTLSServer(mux)
+}
+
+func TestChecker_Check_CookieJar(t *testing.T) {
+	var logins atomic.Int32
+	ts := newLoginServer(&logins)
+	defer ts.Close()
+
+	c := newInsecureChecker()
+	endpoint := config.EndpointConfig{ID: "app", URL: ts.URL + "/app", Method: "GET"}
+
+	if result := c.Check(context.Background(), endpoint); result.Success {
+		t.Error("Expected the login loop to fail without a cookie jar")
+	}
+
+	endpoint.CookieJar = CookieJarCheck
+	for range 2 {
+		if result := c.Check(context.Background(), endpoint); !result.Success {
+			t.Fatalf("Expected success with a cookie jar, got failure: %s", result.Error)
+		}
+	}
+
+	logins.Store(0)
+	endpoint.CookieJar = CookieJarPersistent
+	for range 2 {
+		if result := c.Check(context.Background(), endpoint); !result.Success {
+			t.Fatalf("Expected success with a persistent cookie jar, got failure: %s", result.Error)
+		}
+	}
+	if n := logins.Load(); n != 1 {
+		t.Errorf("Expected the session to be kept across checks, logged in %d times", n)
+	}
+
+	c.RetainCookieJars([]string{"app"})
+	c.Check(context.Background(), endpoint)
+	if n := logins.Load(); n != 1 {
+		t.Errorf("Expected the jar of a configured endpoint to be kept, logged in %d times", n)
+	}
+	c.RetainCookieJars([]string{"other"})
+	c.Check(context.Background(), endpoint)
+	if n := logins.Load(); n != 2 {
+		t.Errorf("Expected the jar of a removed endpoint to be dropped, logged in %d times", n)
+	}
+
+	endpoint.CookieJar = "forever"
+	if result := c.Check(context.Background(), endpoint); result.Success || !strings.Contains(result.Error, "unsupported cookie_jar") {
+		t.Errorf("Expected an invalid cookie_jar to be rejected, got %q", result.Error)
+	}
+}
+
+func TestChecker_Check_CookieAssertions(t *testing.T) {
+	var logins atomic.Int32
+	ts := newLoginServer(&logins)
+	defer ts.Close()
+
+	tests := []struct {
+		name    string
+		cookies []config.CookieAssertion
+		wantErr string
+	}{
+		{name: "session attributes", cookies: []config.CookieAssertion{{Name: "session", Secure: true, HTTPOnly: true, SameSite: "lax"}}},
+		{name: "same site mismatch", cookies: []config.CookieAssertion{{Name: "session", SameSite: "Strict"}},
+			wantErr: "session has SameSite Lax, expected Strict"},
+		{name: "every cookie secure", cookies: []config.CookieAssertion{{Secure: true}}, wantErr: "theme is not Secure"},
+		{name: "every cookie same site", cookies: []config.CookieAssertion{{SameSite: "Lax"}}, wantErr: "theme has SameSite unset"},
+		{name: "missing cookie", cookies: []config.CookieAssertion{{Name: "csrf"}}, wantErr: "csrf was not set"},
+	}
+
+	c := newInsecureChecker()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The cookies are set by the /login redirect, not the final response
+			result := c.Check(context.Background(), config.EndpointConfig{
+				ID: "app", URL: ts.URL + "/app", Method: "GET", CookieJar: CookieJarCheck,
+				Validation: config.ValidationConfig{Cookies: tt.cookies},
+			})
+			if tt.wantErr == "" {
+				if !result.Success {
+					t.Errorf("Expected success, got failure: %s", result.Error)
+				}
+				return
+			}
+			if result.Success || !strings.Contains(result.Error, tt.wantErr) {
+				t.Errorf("Expected error containing %q, got success=%v error=%q", tt.wantErr, result.Success, result.Error)
+			}
+		})
+	}
+}
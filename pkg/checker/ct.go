@@ -0,0 +1,258 @@
+package checker
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/cryptobyte"
+
+	"github.com/manu/octo/pkg/config"
+)
+
+// Limits of Certificate Transparency log checks
+const (
+	defaultCTPageSize = 1000
+	maxCTResponseSize = 32 << 20
+)
+
+// Entry types of an RFC 6962 TimestampedEntry
+const (
+	ctX509Entry    = 0
+	ctPrecertEntry = 1
+)
+
+// CTEntry is a certificate for a watched domain found in a Certificate
+// Transparency log
+type CTEntry struct {
+	Index        uint64    `json:"index"`
+	Timestamp    time.Time `json:"timestamp"` // When the log accepted it
+	Precert      bool      `json:"precert,omitempty"`
+	Subject      string    `json:"subject"`
+	Issuer       string    `json:"issuer"`
+	SerialNumber string    `json:"serial_number"`
+	DNSNames     []string  `json:"dns_names"`
+	NotBefore    time.Time `json:"not_before"`
+	NotAfter     time.Time `json:"not_after"`
+	Allowed      bool      `json:"allowed"` // Issuer on the allow-list
+}
+
+// ctSignedTreeHead is the part of a get-sth response we use
+type ctSignedTreeHead struct {
+	TreeSize uint64 `json:"tree_size"`
+}
+
+// ctEntries is a get-entries response, leaf_input and extra_data in base64
+type ctEntries struct {
+	Entries []struct {
+		LeafInput []byte `json:"leaf_input"`
+		ExtraData []byte `json:"extra_data"`
+	} `json:"entries"`
+}
+
+// checkCT reads the entries added to a Certificate Transparency log since
+// the previous check and keeps the certificates for the watched domains.
+// The first check only records the tree size. Entries are read page by page
+// until the tree size or most of the timeout is reached, and those left for
+// the next check are counted in result.CTBacklog. The check fails when one
+// of the certificates has an issuer outside the allow-list; the next check
+// starts after it either way, so each certificate is reported once.
+func (c *Checker) checkCT(ctx context.Context, endpoint config.EndpointConfig) Result {
+	result := newResult(endpoint)
+	base := strings.TrimSuffix(endpoint.URL, "/")
+	pageSize := uint64(defaultCTPageSize)
+	if endpoint.CT.MaxEntries > 0 {
+		pageSize = uint64(endpoint.CT.MaxEntries)
+	}
+
+	c.mu.Lock()
+	next, seen := c.ctPositions[endpoint.ID]
+	c.mu.Unlock()
+	result.CTPosition = next
+
+	start := time.Now()
+	var sth ctSignedTreeHead
+	if err := c.getCT(ctx, base+"/ct/v1/get-sth", &sth); err != nil {
+		result.Duration = time.Since(start)
+		result.Error = "get-sth failed: " + err.Error()
+		return result
+	}
+	if !seen || next > sth.TreeSize {
+		next = sth.TreeSize
+	}
+
+	// A tenth of the timeout is left to report what was read
+	readCtx := ctx
+	if deadline, ok := ctx.Deadline(); ok {
+		var cancel context.CancelFunc
+		readCtx, cancel = context.WithDeadline(ctx, deadline.Add(-time.Until(deadline)/10))
+		defer cancel()
+	}
+
+	for next < sth.TreeSize {
+		// Logs may return fewer entries than asked for
+		end := min(sth.TreeSize, next+pageSize)
+		var page ctEntries
+		u := fmt.Sprintf("%s/ct/v1/get-entries?start=%d&end=%d", base, next, end-1)
+		if err := c.getCT(readCtx, u, &page); err != nil {
+			// Out of time: the next check carries on
+			if readCtx.Err() != nil && ctx.Err() == nil {
+				break
+			}
+			result.Error = "get-entries failed: " + err.Error()
+			break
+		}
+		if len(page.Entries) == 0 {
+			result.Error = fmt.Sprintf("get-entries failed: no entries from %d", next)
+			break
+		}
+		for _, e := range page.Entries {
+			// Entries that cannot be parsed are skipped, other
+			// certificates are still worth reading
+			entry, cert, err := parseCTEntry(e.LeafInput, e.ExtraData)
+			entry.Index = next
+			next++
+			if err == nil && watchesName(endpoint.CT.Domains, entry.DNSNames) {
+				entry.Allowed = len(endpoint.CT.AllowedIssuers) == 0 || issuerAllowed(endpoint.CT.AllowedIssuers, cert.Issuer)
+				result.CTEntries = append(result.CTEntries, entry)
+			}
+			if next == end {
+				break
+			}
+		}
+	}
+	result.Duration = time.Since(start)
+	result.CTPosition = next
+	result.CTBacklog = sth.TreeSize - next
+
+	c.mu.Lock()
+	c.ctPositions[endpoint.ID] = next
+	c.mu.Unlock()
+	if result.Error != "" {
+		return result
+	}
+
+	var unexpected []string
+	for _, entry := range result.CTEntries {
+		if !entry.Allowed {
+			unexpected = append(unexpected, fmt.Sprintf("%s by %q (entry %d)", entry.DNSNames[0], entry.Issuer, entry.Index))
+		}
+	}
+	if len(unexpected) > 0 {
+		result.CertMismatch = true
+		result.Error = "certificates issued by unexpected CAs: " + strings.Join(unexpected, ", ")
+		return result
+	}
+
+	result.Success = true
+	return result
+}
+
+// SeedCTPosition sets the index of the next entry to read from the log of
+// a Certificate Transparency check, as recorded before a restart, unless
+// the log was read since
+func (c *Checker) SeedCTPosition(id string, position uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.ctPositions[id]; !ok {
+		c.ctPositions[id] = position
+	}
+}
+
+// getCT decodes the JSON document at u
+func (c *Checker) getCT(ctx context.Context, u string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("status code %d", resp.StatusCode)
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxCTResponseSize)).Decode(v); err != nil {
+		return fmt.Errorf("invalid response: %w", err)
+	}
+	return nil
+}
+
+// parseCTEntry decodes the certificate of a log entry. The leaf input is a
+// MerkleTreeLeaf (RFC 6962 section 3.4); a precertificate is read from the
+// PrecertChainEntry of the extra data, as its TBSCertificate alone cannot
+// be parsed.
+func parseCTEntry(leafInput, extraData []byte) (CTEntry, *x509.Certificate, error) {
+	var (
+		version, leafType uint8
+		timestamp         uint64
+		entryType         uint16
+		der               cryptobyte.String
+	)
+	s := cryptobyte.String(leafInput)
+	if !s.ReadUint8(&version) || !s.ReadUint8(&leafType) || !s.ReadUint64(&timestamp) || !s.ReadUint16(&entryType) {
+		return CTEntry{}, nil, errors.New("truncated leaf input")
+	}
+	if version != 0 || leafType != 0 {
+		return CTEntry{}, nil, fmt.Errorf("unsupported leaf version %d type %d", version, leafType)
+	}
+
+	switch entryType {
+	case ctX509Entry:
+		if !s.ReadUint24LengthPrefixed(&der) {
+			return CTEntry{}, nil, errors.New("truncated certificate")
+		}
+	case ctPrecertEntry:
+		extra := cryptobyte.String(extraData)
+		if !extra.ReadUint24LengthPrefixed(&der) {
+			return CTEntry{}, nil, errors.New("truncated precertificate")
+		}
+	default:
+		return CTEntry{}, nil, fmt.Errorf("unsupported entry type %d", entryType)
+	}
+
+	// The poison extension of precertificates is critical but unhandled,
+	// which the parser tolerates
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return CTEntry{}, nil, err
+	}
+	names := cert.DNSNames
+	if len(names) == 0 && cert.Subject.CommonName != "" {
+		names = []string{cert.Subject.CommonName}
+	}
+	return CTEntry{
+		Timestamp:    time.UnixMilli(int64(timestamp)).UTC(),
+		Precert:      entryType == ctPrecertEntry,
+		Subject:      cert.Subject.String(),
+		Issuer:       cert.Issuer.String(),
+		SerialNumber: cert.SerialNumber.String(),
+		DNSNames:     names,
+		NotBefore:    cert.NotBefore,
+		NotAfter:     cert.NotAfter,
+	}, cert, nil
+}
+
+// watchesName reports whether one of the names, wildcards included, is one
+// of the domains or a subdomain
+func watchesName(domains, names []string) bool {
+	for _, name := range names {
+		name = strings.TrimSuffix(strings.TrimPrefix(strings.ToLower(name), "*."), ".")
+		for _, domain := range domains {
+			domain = strings.TrimSuffix(strings.ToLower(domain), ".")
+			if name == domain || strings.HasSuffix(name, "."+domain) {
+				return true
+			}
+		}
+	}
+	return false
+}
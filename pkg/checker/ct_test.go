@@ -0,0 +1,215 @@
+package checker
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/cryptobyte"
+
+	"github.com/manu/octo/pkg/config"
+)
+
+// ctLog stands in for an RFC 6962 log, serving at most two entries per
+// request, each after delay
+type ctLog struct {
+	mu      sync.Mutex
+	entries []map[string][]byte
+	delay   time.Duration
+}
+
+func (l *ctLog) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	switch r.URL.Path {
+	case "/ct/v1/get-sth":
+		json.NewEncoder(w).Encode(map[string]any{"tree_size": len(l.entries), "timestamp": time.Now().UnixMilli()})
+	case "/ct/v1/get-entries":
+		time.Sleep(l.delay)
+		start, _ := strconv.Atoi(r.URL.Query().Get("start"))
+		end, _ := strconv.Atoi(r.URL.Query().Get("end"))
+		end = min(end, start+1, len(l.entries)-1)
+		json.NewEncoder(w).Encode(map[string]any{"entries": l.entries[start : end+1]})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// add logs a certificate, or a precertificate with the CT poison extension
+func (l *ctLog) add(t *testing.T, issuer *x509.Certificate, issuerKey crypto.Signer, precert bool, names ...string) {
+	t.Helper()
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: names[0]},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(90 * 24 * time.Hour),
+		DNSNames:     names,
+	}
+	if precert {
+		template.ExtraExtensions = []pkix.Extension{{
+			Id:       asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 11129, 2, 4, 3},
+			Critical: true,
+			Value:    asn1.NullBytes,
+		}}
+	}
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	der, err := x509.CreateCertificate(rand.Reader, template, issuer, &key.PublicKey, issuerKey)
+	if err != nil {
+		t.Fatalf("Failed to create certificate: %v", err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+
+	var leaf, extra cryptobyte.Builder
+	leaf.AddUint8(0) // v1
+	leaf.AddUint8(0) // timestamped_entry
+	leaf.AddUint64(uint64(time.Now().UnixMilli()))
+	if precert {
+		leaf.AddUint16(ctPrecertEntry)
+		leaf.AddBytes(make([]byte, 32)) // issuer_key_hash
+		leaf.AddUint24LengthPrefixed(func(b *cryptobyte.Builder) { b.AddBytes(cert.RawTBSCertificate) })
+		extra.AddUint24LengthPrefixed(func(b *cryptobyte.Builder) { b.AddBytes(der) })
+		extra.AddUint24LengthPrefixed(func(b *cryptobyte.Builder) {})
+	} else {
+		leaf.AddUint16(ctX509Entry)
+		leaf.AddUint24LengthPrefixed(func(b *cryptobyte.Builder) { b.AddBytes(der) })
+	}
+	leaf.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {}) // extensions
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.entries = append(l.entries, map[string][]byte{"leaf_input": leaf.BytesOrPanic(), "extra_data": extra.BytesOrPanic()})
+}
+
+func TestChecker_Check_CT(t *testing.T) {
+	ours := newTestPKI(t, "")
+	rogueKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	rogueTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Rogue CA", Organization: []string{"Rogue"}},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	rogueDER, _ := x509.CreateCertificate(rand.Reader, rogueTemplate, rogueTemplate, &rogueKey.PublicKey, rogueKey)
+	rogueCA, _ := x509.ParseCertificate(rogueDER)
+
+	log := &ctLog{}
+	log.add(t, rogueCA, rogueKey, false, "example.com") // Before monitoring started
+	ts := httptest.NewServer(log)
+	defer ts.Close()
+
+	c := NewChecker()
+	endpoint := config.EndpointConfig{
+		ID: "ct:test", Type: TypeCT, URL: ts.URL + "/",
+		CT: config.CTLogConfig{Domains: []string{"example.com"}, AllowedIssuers: []string{"Octo"}},
+	}
+
+	result := c.Check(context.Background(), endpoint)
+	if !result.Success || len(result.CTEntries) != 0 {
+		t.Fatalf("Expected the first check to start at the tree head, got %+v", result)
+	}
+
+	log.add(t, ours.caCert, ours.caKey, false, "www.example.com")
+	log.add(t, rogueCA, rogueKey, false, "example.org")
+	log.add(t, rogueCA, rogueKey, true, "*.example.com", "example.com")
+
+	result = c.Check(context.Background(), endpoint)
+	if result.Success || !result.CertMismatch {
+		t.Fatalf("Expected the rogue certificate to fail the check, got %+v", result)
+	}
+	if !strings.Contains(result.Error, `*.example.com by "CN=Rogue CA,O=Rogue" (entry 3)`) {
+		t.Errorf("Unexpected error: %s", result.Error)
+	}
+	if len(result.CTEntries) != 2 {
+		t.Fatalf("Expected the 2 certificates for example.com, got %+v", result.CTEntries)
+	}
+	if e := result.CTEntries[0]; e.Index != 1 || !e.Allowed || e.Precert || e.DNSNames[0] != "www.example.com" {
+		t.Errorf("Unexpected allowed entry: %+v", e)
+	}
+	if e := result.CTEntries[1]; e.Index != 3 || e.Allowed || !e.Precert {
+		t.Errorf("Unexpected rogue entry: %+v", e)
+	}
+
+	// Each certificate is reported once
+	result = c.Check(context.Background(), endpoint)
+	if !result.Success || len(result.CTEntries) != 0 {
+		t.Errorf("Expected no new entries, got %+v", result)
+	}
+}
+
+func TestChecker_Check_CTBacklog(t *testing.T) {
+	pki := newTestPKI(t, "")
+	log := &ctLog{delay: 20 * time.Millisecond}
+	log.add(t, pki.caCert, pki.caKey, false, "example.com")
+	ts := httptest.NewServer(log)
+	defer ts.Close()
+
+	endpoint := config.EndpointConfig{ID: "ct:test", Type: TypeCT, URL: ts.URL + "/", CT: config.CTLogConfig{Domains: []string{"example.com"}}}
+	c := NewChecker()
+	if result := c.Check(context.Background(), endpoint); !result.Success || result.CTPosition != 1 {
+		t.Fatalf("Expected the first check to start at the tree head, got %+v", result)
+	}
+
+	for i := range 20 {
+		log.add(t, pki.caCert, pki.caKey, false, fmt.Sprintf("host%d.example.com", i))
+	}
+
+	// The log serves 2 entries every 20ms, too slow to catch up in 100ms
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	result := c.Check(ctx, endpoint)
+	if !result.Success || result.CTBacklog == 0 || result.CTPosition+result.CTBacklog != 21 {
+		t.Fatalf("Expected a backlog once out of time, got success=%v position=%d backlog=%d error=%q",
+			result.Success, result.CTPosition, result.CTBacklog, result.Error)
+	}
+	read := len(result.CTEntries)
+
+	// A restarted checker resumes from the stored position
+	c = NewChecker()
+	c.SeedCTPosition(endpoint.ID, result.CTPosition)
+	result = c.Check(context.Background(), endpoint)
+	if !result.Success || result.CTBacklog != 0 || result.CTPosition != 21 {
+		t.Fatalf("Expected the next check to catch up, got %+v", result)
+	}
+	if read += len(result.CTEntries); read != 20 {
+		t.Errorf("Expected each of the 20 certificates to be read once, got %d", read)
+	}
+}
+
+func TestWatchesName(t *testing.T) {
+	domains := []string{"example.com", "Example.ORG."}
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"example.com", true},
+		{"api.eu.example.com", true},
+		{"*.example.com", true},
+		{"www.example.org", true},
+		{"notexample.com", false},
+		{"example.com.evil.net", false},
+	}
+	for _, tt := range tests {
+		if got := watchesName(domains, []string{tt.name}); got != tt.want {
+			t.Errorf("watchesName(%q) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
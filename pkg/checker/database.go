@@ -0,0 +1,312 @@
+package checker
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/manu/octo/pkg/config"
+)
+
+// maxQueryResult bounds the probe result recorded with a check
+const maxQueryResult = 256
+
+// checkPostgres connects to a PostgreSQL server and runs the probe query.
+// ConnDuration covers connecting and authenticating, TTFB the query.
+func (c *Checker) checkPostgres(ctx context.Context, endpoint config.EndpointConfig) Result {
+	result := newResult(endpoint)
+	db := endpoint.Database
+
+	address, username, password, expect, err := c.databaseParams(endpoint, "5432")
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	host, port, _ := net.SplitHostPort(address)
+	portNum, _ := strconv.ParseUint(port, 10, 16)
+
+	cfg, err := pgx.ParseConfig("")
+	if err != nil {
+		result.Error = "invalid connection settings: " + err.Error()
+		return result
+	}
+	cfg.Host = host
+	cfg.Port = uint16(portNum)
+	cfg.User = username
+	cfg.Password = password
+	cfg.Database = db.Database
+	cfg.TLSConfig = nil
+	cfg.Fallbacks = nil
+	// Nothing comes from the PG* environment of the checker: no session
+	// parameters, connect timeout or session attribute validation
+	cfg.RuntimeParams = map[string]string{}
+	cfg.ConnectTimeout = 0
+	cfg.ValidateConnect = nil
+	cfg.SSLNegotiation = ""
+	if db.TLS {
+		cfg.TLSConfig = c.tlsConfig(host)
+	}
+	// Resolve through the endpoint dialer rather than pgx's own lookup
+	cfg.LookupFunc = func(_ context.Context, host string) ([]string, error) { return []string{host}, nil }
+	cfg.DialFunc = dialerFrom(ctx).DialContext
+	// Probe queries are one-off, so skip preparing statements
+	cfg.DefaultQueryExecMode = pgx.QueryExecModeSimpleProtocol
+
+	start := time.Now()
+	conn, err := pgx.ConnectConfig(ctx, cfg)
+	result.ConnDuration = time.Since(start)
+	if err != nil {
+		result.Duration = time.Since(start)
+		result.Error = "connection failed: " + err.Error()
+		return result
+	}
+	defer conn.Close(context.Background())
+
+	query := db.Query
+	if query == "" {
+		query = "SELECT 1"
+	}
+
+	queryStart := time.Now()
+	rows, err := conn.Query(ctx, query)
+	var value string
+	if err == nil {
+		if rows.Next() {
+			var values []any
+			if values, err = rows.Values(); err == nil && len(values) > 0 {
+				value = fmt.Sprint(values[0])
+			}
+		}
+		rows.Close()
+		if err == nil {
+			err = rows.Err()
+		}
+	}
+	result.TTFB = time.Since(queryStart)
+	result.Duration = time.Since(start)
+
+	return finishQuery(result, value, err, expect)
+}
+
+// checkMySQL connects to a MySQL server and runs the probe query.
+// ConnDuration covers connecting and authenticating, TTFB the query.
+func (c *Checker) checkMySQL(ctx context.Context, endpoint config.EndpointConfig) Result {
+	result := newResult(endpoint)
+	db := endpoint.Database
+
+	address, username, password, expect, err := c.databaseParams(endpoint, "3306")
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	host, _, _ := net.SplitHostPort(address)
+
+	cfg := mysql.NewConfig()
+	cfg.Net = mysqlNetwork
+	cfg.Addr = address
+	cfg.User = username
+	cfg.Passwd = password
+	cfg.DBName = db.Database
+	cfg.Logger = log.New(io.Discard, "", 0) // Errors are reported in the result
+	if db.TLS {
+		cfg.TLS = c.tlsConfig(host)
+	}
+
+	connector, err := mysql.NewConnector(cfg)
+	if err != nil {
+		result.Error = "invalid connection settings: " + err.Error()
+		return result
+	}
+	pool := sql.OpenDB(connector)
+	defer pool.Close()
+
+	start := time.Now()
+	conn, err := pool.Conn(ctx)
+	result.ConnDuration = time.Since(start)
+	if err != nil {
+		result.Duration = time.Since(start)
+		result.Error = "connection failed: " + err.Error()
+		return result
+	}
+	defer conn.Close()
+
+	query := db.Query
+	if query == "" {
+		query = "SELECT 1"
+	}
+
+	queryStart := time.Now()
+	value, err := firstValue(ctx, conn, query)
+	result.TTFB = time.Since(queryStart)
+	result.Duration = time.Since(start)
+
+	return finishQuery(result, value, err, expect)
+}
+
+// firstValue runs query and returns the first column of the first row
+func firstValue(ctx context.Context, conn *sql.Conn, query string) (string, error) {
+	rows, err := conn.QueryContext(ctx, query)
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+
+	var value string
+	if rows.Next() {
+		cols, err := rows.Columns()
+		if err != nil {
+			return "", err
+		}
+		dest := make([]any, len(cols))
+		for i := range dest {
+			dest[i] = new(sql.RawBytes)
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return "", err
+		}
+		if len(dest) > 0 {
+			value = string(*dest[0].(*sql.RawBytes))
+		}
+	}
+	return value, rows.Err()
+}
+
+// databaseParams resolves the address, credentials and expectation shared by
+// the datastore check types
+func (c *Checker) databaseParams(endpoint config.EndpointConfig, defaultPort string) (address, username, password string, expect *regexp.Regexp, err error) {
+	address, err = targetAddress(endpoint.URL, defaultPort)
+	if err != nil {
+		return
+	}
+	if username, err = resolveSecret(endpoint.Database.Username, true); err != nil {
+		err = fmt.Errorf("invalid username: %w", err)
+		return
+	}
+	if password, err = resolveSecret(endpoint.Database.Password, false); err != nil {
+		err = fmt.Errorf("invalid password: %w", err)
+		return
+	}
+	if endpoint.Database.Expect != "" {
+		if expect, err = regexp.Compile(endpoint.Database.Expect); err != nil {
+			err = fmt.Errorf("invalid expect regex: %w", err)
+		}
+	}
+	return
+}
+
+// Secret references may only name environment variables starting with
+// secretEnvPrefix and files under the secrets directory, so a config
+// author cannot send other variables or files of the host to a server
+const (
+	secretEnvPrefix   = "OCTO_"
+	secretsDirEnv     = "OCTO_SECRETS_DIR"
+	defaultSecretsDir = "/run/secrets"
+)
+
+// resolveSecret reads an "env:OCTO_NAME" or "file:path" reference. Literal
+// values are only accepted when allowLiteral is set.
+func resolveSecret(ref string, allowLiteral bool) (string, error) {
+	switch {
+	case ref == "":
+		return "", nil
+	case strings.HasPrefix(ref, "env:"):
+		name := strings.TrimPrefix(ref, "env:")
+		if !strings.HasPrefix(name, secretEnvPrefix) {
+			return "", fmt.Errorf("environment variable %s does not start with %s", name, secretEnvPrefix)
+		}
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			return "", fmt.Errorf("environment variable %s is not set", name)
+		}
+		return value, nil
+	case strings.HasPrefix(ref, "file:"):
+		path, err := secretPath(strings.TrimPrefix(ref, "file:"))
+		if err != nil {
+			return "", err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimRight(string(data), "\r\n"), nil
+	case allowLiteral:
+		return ref, nil
+	default:
+		return "", fmt.Errorf("must be an env: or file: reference")
+	}
+}
+
+// secretPath resolves a secret file, relative to the secrets directory
+// unless absolute, and checks that it lies within it once symbolic links
+// are followed
+func secretPath(name string) (string, error) {
+	return pathUnder(secretsDirEnv, defaultSecretsDir, "secrets", name)
+}
+
+// pathUnder resolves a path relative to the directory named by the dirEnv
+// variable and refuses one that is outside it, symlinks included. A path
+// that is outside lexically is refused before the file system is touched,
+// so errors do not tell whether it exists.
+func pathUnder(dirEnv, defaultDir, kind, name string) (string, error) {
+	dir := os.Getenv(dirEnv)
+	if dir == "" {
+		dir = defaultDir
+	}
+	given := filepath.Clean(dir)
+	dir, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return "", fmt.Errorf("%s directory: %w", kind, err)
+	}
+	outside := func(dir, path string) bool {
+		rel, err := filepath.Rel(dir, path)
+		return err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator))
+	}
+
+	path := name
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+	if path = filepath.Clean(path); outside(dir, path) && outside(given, path) {
+		return "", fmt.Errorf("%s is outside the %s directory %s", name, kind, dir)
+	}
+	if path, err = filepath.EvalSymlinks(path); err != nil {
+		return "", err
+	}
+	if outside(dir, path) {
+		return "", fmt.Errorf("%s is outside the %s directory %s", name, kind, dir)
+	}
+	return path, nil
+}
+
+// finishQuery records the probe value and evaluates the expectation
+func finishQuery(result Result, value string, err error, expect *regexp.Regexp) Result {
+	if err != nil {
+		result.Error = "query failed: " + err.Error()
+		return result
+	}
+
+	result.QueryResult = value
+	if len(result.QueryResult) > maxQueryResult {
+		result.QueryResult = result.QueryResult[:maxQueryResult]
+	}
+
+	if expect != nil && !expect.MatchString(value) {
+		result.Error = fmt.Sprintf("query result %q does not match %q", result.QueryResult, expect.String())
+		return result
+	}
+
+	result.Success = true
+	return result
+}
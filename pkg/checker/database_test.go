@@ -0,0 +1,351 @@
+package checker
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgproto3"
+
+	"github.com/manu/octo/pkg/config"
+)
+
+// listen starts serving each accepted connection with handle
+func listen(t *testing.T, handle func(net.Conn)) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				handle(conn)
+			}()
+		}
+	}()
+	return ln.Addr().String()
+}
+
+// servePostgres fakes a PostgreSQL server that accepts the password "s3cret"
+// and answers two queries over the simple protocol. It refuses startup
+// parameters other than the user and database.
+func servePostgres(conn net.Conn) {
+	backend := pgproto3.NewBackend(conn, conn)
+	msg, err := backend.ReceiveStartupMessage()
+	if err != nil {
+		return
+	}
+	if startup, ok := msg.(*pgproto3.StartupMessage); ok {
+		for name := range startup.Parameters {
+			if name != "user" && name != "database" {
+				backend.Send(&pgproto3.ErrorResponse{Severity: "FATAL", Code: "42704", Message: "unexpected parameter " + name})
+				backend.Flush()
+				return
+			}
+		}
+	}
+
+	backend.Send(&pgproto3.AuthenticationCleartextPassword{})
+	backend.Flush()
+	backend.SetAuthType(pgproto3.AuthTypeCleartextPassword)
+	msg, err = backend.Receive()
+	if err != nil {
+		return
+	}
+	if pw, ok := msg.(*pgproto3.PasswordMessage); !ok || pw.Password != "s3cret" {
+		backend.Send(&pgproto3.ErrorResponse{Severity: "FATAL", Code: "28P01", Message: "password authentication failed"})
+		backend.Flush()
+		return
+	}
+	backend.Send(&pgproto3.AuthenticationOk{})
+	backend.Send(&pgproto3.ParameterStatus{Name: "standard_conforming_strings", Value: "on"})
+	backend.Send(&pgproto3.ParameterStatus{Name: "client_encoding", Value: "UTF8"})
+	backend.Send(&pgproto3.ReadyForQuery{TxStatus: 'I'})
+	backend.Flush()
+
+	for {
+		msg, err := backend.Receive()
+		if err != nil {
+			return
+		}
+		query, ok := msg.(*pgproto3.Query)
+		if !ok {
+			return
+		}
+
+		var oid uint32
+		var value string
+		switch query.String {
+		case "SELECT 1":
+			oid, value = 23, "1"
+		case "SELECT version()":
+			oid, value = 25, "PostgreSQL 16.2"
+		default:
+			backend.Send(&pgproto3.ErrorResponse{Severity: "ERROR", Code: "42601", Message: "syntax error"})
+			backend.Send(&pgproto3.ReadyForQuery{TxStatus: 'I'})
+			backend.Flush()
+			continue
+		}
+
+		backend.Send(&pgproto3.RowDescription{Fields: []pgproto3.FieldDescription{
+			{Name: []byte("value"), DataTypeOID: oid, DataTypeSize: -1, TypeModifier: -1},
+		}})
+		backend.Send(&pgproto3.DataRow{Values: [][]byte{[]byte(value)}})
+		backend.Send(&pgproto3.CommandComplete{CommandTag: []byte("SELECT 1")})
+		backend.Send(&pgproto3.ReadyForQuery{TxStatus: 'I'})
+		backend.Flush()
+	}
+}
+
+// serveRedis fakes a Redis server that requires the password "s3cret"
+func serveRedis(conn net.Conn) {
+	r := bufio.NewReader(conn)
+	authed := false
+	for {
+		args, err := readRESPCommand(r)
+		if err != nil {
+			return
+		}
+		var reply string
+		switch cmd := strings.ToUpper(args[0]); {
+		case cmd == "AUTH" && args[len(args)-1] == "s3cret":
+			authed, reply = true, "+OK\r\n"
+		case cmd == "AUTH":
+			reply = "-WRONGPASS invalid username-password pair\r\n"
+		case !authed:
+			reply = "-NOAUTH Authentication required.\r\n"
+		case cmd == "SELECT", cmd == "PING" && len(args) == 1:
+			reply = map[string]string{"SELECT": "+OK\r\n", "PING": "+PONG\r\n"}[cmd]
+		case cmd == "INFO":
+			info := "# Server\r\nredis_version:7.2.4\r\n# Replication\r\nrole:master\r\n"
+			reply = fmt.Sprintf("$%d\r\n%s\r\n", len(info), info)
+		default:
+			reply = "-ERR unknown command\r\n"
+		}
+		io.WriteString(conn, reply)
+	}
+}
+
+// serveMySQL fakes a MySQL server that accepts the password "s3cret" with
+// mysql_native_password and answers "SELECT 1" over the text protocol
+func serveMySQL(conn net.Conn) {
+	var seq byte
+	writePacket := func(payload []byte) {
+		header := []byte{byte(len(payload)), byte(len(payload) >> 8), byte(len(payload) >> 16), seq}
+		conn.Write(append(header, payload...))
+		seq++
+	}
+	readPacket := func() ([]byte, error) {
+		header := make([]byte, 4)
+		if _, err := io.ReadFull(conn, header); err != nil {
+			return nil, err
+		}
+		payload := make([]byte, int(header[0])|int(header[1])<<8|int(header[2])<<16)
+		_, err := io.ReadFull(conn, payload)
+		seq = header[3] + 1
+		return payload, err
+	}
+	lenencString := func(s string) []byte { return append([]byte{byte(len(s))}, s...) }
+	eof := []byte{0xfe, 0, 0, 2, 0}
+
+	// Protocol 41, long password, secure connection and plugin auth
+	const capabilities = 0x0200 | 0x0001 | 0x8000 | 0x2000 | 0x80000
+	scramble := []byte("abcdefghijklmnopqrst")
+	handshake := []byte{10}
+	handshake = append(handshake, "8.0.36\x00"...)
+	handshake = append(handshake, 1, 0, 0, 0)
+	handshake = append(handshake, scramble[:8]...)
+	handshake = append(handshake, 0, capabilities&0xff, capabilities>>8&0xff, 33, 2, 0,
+		capabilities>>16&0xff, capabilities>>24&0xff, 21)
+	handshake = append(handshake, make([]byte, 10)...)
+	handshake = append(handshake, scramble[8:]...)
+	handshake = append(handshake, 0)
+	handshake = append(handshake, "mysql_native_password\x00"...)
+	writePacket(handshake)
+
+	// The auth response follows the capabilities, max packet size, charset,
+	// filler and NUL terminated user name
+	response, err := readPacket()
+	if err != nil || len(response) < 33 {
+		return
+	}
+	rest := response[32:]
+	rest = rest[bytes.IndexByte(rest, 0)+1:]
+	auth := rest[1 : 1+int(rest[0])]
+
+	// SHA1(password) XOR SHA1(scramble + SHA1(SHA1(password)))
+	h1 := sha1.Sum([]byte("s3cret"))
+	h2 := sha1.Sum(h1[:])
+	h3 := sha1.Sum(append(slices.Clone(scramble), h2[:]...))
+	want := make([]byte, len(h1))
+	for i := range want {
+		want[i] = h1[i] ^ h3[i]
+	}
+	if !bytes.Equal(auth, want) {
+		writePacket(append([]byte{0xff, 0x15, 0x04, '#'}, "28000Access denied for user"...))
+		return
+	}
+	writePacket([]byte{0, 0, 0, 2, 0, 0, 0})
+
+	for {
+		query, err := readPacket()
+		if err != nil || query[0] != 3 { // COM_QUERY
+			return
+		}
+		if string(query[1:]) != "SELECT 1" {
+			writePacket(append([]byte{0xff, 0x28, 0x04, '#'}, "42000You have an error in your SQL syntax"...))
+			continue
+		}
+
+		writePacket([]byte{1})
+		column := slices.Concat(lenencString("def"), lenencString(""), lenencString(""), lenencString(""),
+			lenencString("1"), lenencString(""), []byte{0x0c, 63, 0, 1, 0, 0, 0, 0x08, 0x81, 0, 0, 0, 0})
+		writePacket(column)
+		writePacket(eof)
+		writePacket(lenencString("1"))
+		writePacket(eof)
+	}
+}
+
+func readRESPCommand(r *bufio.Reader) ([]string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(line[1:]))
+	if err != nil || n < 1 {
+		return nil, fmt.Errorf("bad command")
+	}
+	args := make([]string, n)
+	for i := range args {
+		if _, err := r.ReadString('\n'); err != nil {
+			return nil, err
+		}
+		arg, err := r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		args[i] = strings.TrimSuffix(arg, "\r\n")
+	}
+	return args, nil
+}
+
+func TestChecker_Check_Datastores(t *testing.T) {
+	pgAddr := listen(t, servePostgres)
+	redisAddr := listen(t, serveRedis)
+
+	mysqlAddr := listen(t, serveMySQL)
+
+	secretsDir := t.TempDir()
+	t.Setenv("OCTO_SECRETS_DIR", secretsDir)
+	secretFile := filepath.Join(secretsDir, "password")
+	if err := os.WriteFile(secretFile, []byte("s3cret\n"), 0o600); err != nil {
+		t.Fatalf("Failed to write secret: %v", err)
+	}
+	outside := filepath.Join(t.TempDir(), "other")
+	if err := os.WriteFile(outside, []byte("s3cret\n"), 0o600); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	if err := os.Symlink(outside, filepath.Join(secretsDir, "link")); err != nil {
+		t.Fatalf("Failed to link file: %v", err)
+	}
+	t.Setenv("DB_PASSWORD", "s3cret")
+	t.Setenv("OCTO_TEST_DB_PASSWORD", "s3cret")
+
+	// The libpq environment of the checker does not apply to endpoints
+	t.Setenv("PGOPTIONS", "-c statement_timeout=1")
+	t.Setenv("PGAPPNAME", "octo")
+	t.Setenv("PGTARGETSESSIONATTRS", "read-write")
+	t.Setenv("PGCONNECT_TIMEOUT", "1")
+	t.Setenv("OCTO_TEST_DB_WRONG", "nope")
+
+	tests := []struct {
+		name      string
+		checkType string
+		addr      string
+		db        config.DatabaseConfig
+		wantValue string
+		wantErr   string
+	}{
+		{name: "postgres select 1", checkType: TypePostgres, addr: pgAddr,
+			db: config.DatabaseConfig{Username: "monitor", Password: "env:OCTO_TEST_DB_PASSWORD"}, wantValue: "1"},
+		{name: "postgres expect", checkType: TypePostgres, addr: pgAddr,
+			db: config.DatabaseConfig{Username: "monitor", Password: "file:" + secretFile, Query: "SELECT version()", Expect: `^PostgreSQL 1[5-9]`}, wantValue: "PostgreSQL 16.2"},
+		{name: "postgres expect mismatch", checkType: TypePostgres, addr: pgAddr,
+			db: config.DatabaseConfig{Username: "monitor", Password: "env:OCTO_TEST_DB_PASSWORD", Expect: `^2$`}, wantErr: `query result "1" does not match`},
+		{name: "postgres bad query", checkType: TypePostgres, addr: pgAddr,
+			db: config.DatabaseConfig{Username: "monitor", Password: "env:OCTO_TEST_DB_PASSWORD", Query: "SELEC 1"}, wantErr: "query failed"},
+		{name: "postgres wrong password", checkType: TypePostgres, addr: pgAddr,
+			db: config.DatabaseConfig{Username: "monitor", Password: "env:OCTO_TEST_DB_WRONG"}, wantErr: "password authentication failed"},
+		{name: "redis ping", checkType: TypeRedis, addr: redisAddr,
+			db: config.DatabaseConfig{Password: "env:OCTO_TEST_DB_PASSWORD", Database: "2"}, wantValue: "PONG"},
+		{name: "redis info", checkType: TypeRedis, addr: "redis://" + redisAddr,
+			db: config.DatabaseConfig{Username: "default", Password: "file:" + secretFile, Query: "INFO replication", Expect: `(?m)^role:master`}, wantValue: "# Server"},
+		{name: "redis no auth", checkType: TypeRedis, addr: redisAddr, wantErr: "NOAUTH"},
+		{name: "redis wrong password", checkType: TypeRedis, addr: redisAddr,
+			db: config.DatabaseConfig{Password: "env:OCTO_TEST_DB_WRONG"}, wantErr: "authentication failed: WRONGPASS"},
+		{name: "literal password", checkType: TypeRedis, addr: redisAddr,
+			db: config.DatabaseConfig{Password: "s3cret"}, wantErr: "invalid password: must be an env: or file: reference"},
+		{name: "missing env", checkType: TypePostgres, addr: pgAddr,
+			db: config.DatabaseConfig{Password: "env:OCTO_TEST_DB_UNSET"}, wantErr: "OCTO_TEST_DB_UNSET is not set"},
+		{name: "secret outside the directory", checkType: TypeRedis, addr: redisAddr,
+			db: config.DatabaseConfig{Password: "file:" + outside}, wantErr: "outside the secrets directory"},
+		{name: "secret linked outside the directory", checkType: TypeRedis, addr: redisAddr,
+			db: config.DatabaseConfig{Password: "file:link"}, wantErr: "outside the secrets directory"},
+		{name: "secret traversing out of the directory", checkType: TypeRedis, addr: redisAddr,
+			db: config.DatabaseConfig{Password: "file:../" + filepath.Base(filepath.Dir(outside)) + "/other"}, wantErr: "outside the secrets directory"},
+		{name: "env without prefix", checkType: TypeRedis, addr: redisAddr,
+			db: config.DatabaseConfig{Password: "env:DB_PASSWORD"}, wantErr: "does not start with OCTO_"},
+		{name: "mysql select 1", checkType: TypeMySQL, addr: mysqlAddr,
+			db: config.DatabaseConfig{Username: "monitor", Password: "file:password"}, wantValue: "1"},
+		{name: "mysql wrong password", checkType: TypeMySQL, addr: mysqlAddr,
+			db: config.DatabaseConfig{Username: "monitor", Password: "env:OCTO_TEST_DB_WRONG"}, wantErr: "Access denied"},
+		{name: "mysql bad query", checkType: TypeMySQL, addr: mysqlAddr,
+			db: config.DatabaseConfig{Username: "monitor", Password: "env:OCTO_TEST_DB_PASSWORD", Query: "SELEC 1"}, wantErr: "query failed"},
+		{name: "mysql refused", checkType: TypeMySQL, addr: "127.0.0.1:1", wantErr: "connection failed"},
+	}
+
+	c := NewChecker()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+			defer cancel()
+
+			result := c.Check(ctx, config.EndpointConfig{ID: "db", Type: tt.checkType, URL: tt.addr, Database: tt.db})
+			if tt.wantErr == "" {
+				if !result.Success {
+					t.Fatalf("Expected success, got failure: %s", result.Error)
+				}
+				if !strings.HasPrefix(result.QueryResult, tt.wantValue) {
+					t.Errorf("Expected query result %q, got %q", tt.wantValue, result.QueryResult)
+				}
+				if result.ConnDuration == 0 || result.TTFB == 0 {
+					t.Error("Expected connect and query latency to be recorded")
+				}
+				return
+			}
+			if result.Success || !strings.Contains(result.Error, tt.wantErr) {
+				t.Errorf("Expected error containing %q, got success=%v error=%q", tt.wantErr, result.Success, result.Error)
+			}
+		})
+	}
+}
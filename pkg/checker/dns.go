@@ -0,0 +1,145 @@
+package checker
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/manu/octo/pkg/config"
+)
+
+// checkDNS resolves the endpoint name and verifies the expected answers
+func (c *Checker) checkDNS(ctx context.Context, endpoint config.EndpointConfig) Result {
+	result := newResult(endpoint)
+
+	name := strings.TrimPrefix(endpoint.URL, "dns://")
+	name = strings.TrimSuffix(name, "/")
+	if name == "" {
+		result.Error = "dns check requires a name to resolve"
+		return result
+	}
+
+	resolver, err := newResolver(endpoint.DNS.Resolver)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	recordType := strings.ToUpper(endpoint.DNS.RecordType)
+	if recordType == "" {
+		recordType = "A"
+	}
+
+	start := time.Now()
+	answers, err := lookupRecords(ctx, resolver, recordType, name)
+	result.DNSDuration = time.Since(start)
+	result.Duration = result.DNSDuration
+	result.DNSAnswers = answers
+
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	if len(answers) == 0 {
+		result.Error = fmt.Sprintf("no %s records found for %s", recordType, name)
+		return result
+	}
+
+	got := make(map[string]bool, len(answers))
+	for _, a := range answers {
+		got[normalizeAnswer(recordType, a)] = true
+	}
+	for _, want := range endpoint.DNS.Expected {
+		if !got[normalizeAnswer(recordType, want)] {
+			result.Error = fmt.Sprintf("expected %s answer %s not found in %v", recordType, want, answers)
+			return result
+		}
+	}
+
+	result.Success = true
+	return result
+}
+
+// newResolver returns the system resolver, or one that sends all queries to
+// the given server ("host" or "host:port")
+func newResolver(server string) (*net.Resolver, error) {
+	if server == "" {
+		return net.DefaultResolver, nil
+	}
+	address, err := targetAddress(server, "53")
+	if err != nil {
+		return nil, fmt.Errorf("invalid resolver: %w", err)
+	}
+
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, network, address)
+		},
+	}, nil
+}
+
+func lookupRecords(ctx context.Context, resolver *net.Resolver, recordType, name string) ([]string, error) {
+	var answers []string
+
+	switch recordType {
+	case "A", "AAAA":
+		network := "ip4"
+		if recordType == "AAAA" {
+			network = "ip6"
+		}
+		ips, err := resolver.LookupIP(ctx, network, name)
+		if err != nil {
+			return nil, err
+		}
+		for _, ip := range ips {
+			answers = append(answers, ip.String())
+		}
+	case "CNAME":
+		cname, err := resolver.LookupCNAME(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		answers = append(answers, cname)
+	case "MX":
+		records, err := resolver.LookupMX(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		for _, mx := range records {
+			answers = append(answers, mx.Host)
+		}
+	case "NS":
+		records, err := resolver.LookupNS(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		for _, ns := range records {
+			answers = append(answers, ns.Host)
+		}
+	case "TXT":
+		records, err := resolver.LookupTXT(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		answers = append(answers, records...)
+	default:
+		return nil, fmt.Errorf("unsupported record type %q", recordType)
+	}
+
+	return answers, nil
+}
+
+// normalizeAnswer makes host names comparable; TXT data is compared verbatim
+func normalizeAnswer(recordType, s string) string {
+	if recordType == "TXT" {
+		return s
+	}
+	if ip := net.ParseIP(s); ip != nil {
+		return ip.String()
+	}
+	return strings.ToLower(strings.TrimSuffix(s, "."))
+}
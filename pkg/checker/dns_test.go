@@ -0,0 +1,113 @@
+package checker
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+
+	"github.com/manu/octo/pkg/config"
+)
+
+// startDNSServer serves fixed A and MX records for api.example.test, and an A
+// record pointing loopback.example.test to 127.0.0.1, over UDP
+func startDNSServer(t *testing.T) string {
+	t.Helper()
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	t.Cleanup(func() { pc.Close() })
+
+	zoneName := dnsmessage.MustNewName("api.example.test.")
+	loopbackName := dnsmessage.MustNewName("loopback.example.test.")
+
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, addr, err := pc.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			var req dnsmessage.Message
+			if err := req.Unpack(buf[:n]); err != nil || len(req.Questions) == 0 {
+				continue
+			}
+			q := req.Questions[0]
+
+			resp := dnsmessage.Message{
+				Header:    dnsmessage.Header{ID: req.ID, Response: true, Authoritative: true},
+				Questions: req.Questions,
+			}
+			hdr := dnsmessage.ResourceHeader{Name: q.Name, Class: dnsmessage.ClassINET, TTL: 60}
+
+			switch {
+			case q.Name == loopbackName && q.Type == dnsmessage.TypeA:
+				resp.Answers = append(resp.Answers, dnsmessage.Resource{Header: hdr, Body: &dnsmessage.AResource{A: [4]byte{127, 0, 0, 1}}})
+			case q.Name == loopbackName:
+			case q.Name != zoneName:
+				resp.RCode = dnsmessage.RCodeNameError
+			case q.Type == dnsmessage.TypeA:
+				for _, ip := range [][4]byte{{10, 0, 0, 1}, {10, 0, 0, 2}} {
+					resp.Answers = append(resp.Answers, dnsmessage.Resource{Header: hdr, Body: &dnsmessage.AResource{A: ip}})
+				}
+			case q.Type == dnsmessage.TypeMX:
+				resp.Answers = append(resp.Answers, dnsmessage.Resource{
+					Header: hdr,
+					Body:   &dnsmessage.MXResource{Pref: 10, MX: dnsmessage.MustNewName("mx.example.test.")},
+				})
+			}
+
+			packed, err := resp.Pack()
+			if err != nil {
+				continue
+			}
+			pc.WriteTo(packed, addr)
+		}
+	}()
+
+	return pc.LocalAddr().String()
+}
+
+func TestChecker_Check_DNS(t *testing.T) {
+	resolver := startDNSServer(t)
+
+	tests := []struct {
+		name    string
+		url     string
+		dns     config.DNSConfig
+		wantErr string
+	}{
+		{name: "A records", url: "api.example.test", dns: config.DNSConfig{Expected: []string{"10.0.0.1", "10.0.0.2"}}},
+		{name: "MX record", url: "dns://api.example.test", dns: config.DNSConfig{RecordType: "mx", Expected: []string{"MX.example.test"}}},
+		{name: "unexpected answers", url: "api.example.test", dns: config.DNSConfig{Expected: []string{"10.0.0.9"}}, wantErr: "expected A answer 10.0.0.9 not found"},
+		{name: "nxdomain", url: "missing.example.test", wantErr: "no such host"},
+		{name: "unsupported type", url: "api.example.test", dns: config.DNSConfig{RecordType: "SRV"}, wantErr: "unsupported record type"},
+	}
+
+	c := NewChecker()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+			defer cancel()
+
+			tt.dns.Resolver = resolver
+			result := c.Check(ctx, config.EndpointConfig{ID: "dns", Type: TypeDNS, URL: tt.url, DNS: tt.dns})
+			if tt.wantErr == "" {
+				if !result.Success {
+					t.Errorf("Expected success, got failure: %s", result.Error)
+				}
+				if len(result.DNSAnswers) == 0 {
+					t.Error("Expected answers to be recorded")
+				}
+				return
+			}
+			if result.Success || !strings.Contains(result.Error, tt.wantErr) {
+				t.Errorf("Expected error containing %q, got success=%v error=%q", tt.wantErr, result.Success, result.Error)
+			}
+		})
+	}
+}
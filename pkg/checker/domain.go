@@ -0,0 +1,356 @@
+package checker
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/net/publicsuffix"
+
+	"github.com/manu/octo/pkg/config"
+)
+
+// rdapBootstrapURL is the IANA registry of the RDAP servers of each TLD
+var rdapBootstrapURL = "https://data.iana.org/rdap/dns.json"
+
+// Where WHOIS servers are looked up, and how long the RDAP registry is kept
+const (
+	whoisReferralServer = "whois.iana.org:43"
+	rdapBootstrapTTL    = 24 * time.Hour
+	maxRDAPResponseSize = 1 << 20
+	maxWHOISResponse    = 256 * 1024
+)
+
+// domainRegistration is what a registry reports about a domain
+type domainRegistration struct {
+	expiry    time.Time
+	registrar string
+}
+
+// rdapBootstrap caches the RDAP base URL of each TLD
+type rdapBootstrap struct {
+	servers map[string]string
+	fetched time.Time
+}
+
+// RegistrableDomain returns the domain registered for the host of a URL or
+// host[:port] target, e.g. "example.co.uk" for "https://api.example.co.uk".
+// IP addresses and names outside ICANN suffixes, like "db.internal", have none.
+func RegistrableDomain(target string) (string, bool) {
+	host := target
+	if strings.Contains(target, "://") {
+		u, err := url.Parse(target)
+		if err != nil {
+			return "", false
+		}
+		host = u.Hostname()
+	} else if h, _, err := net.SplitHostPort(target); err == nil {
+		host = h
+	}
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	if host == "" || net.ParseIP(strings.Trim(host, "[]")) != nil {
+		return "", false
+	}
+
+	if _, icann := publicsuffix.PublicSuffix(host); !icann {
+		return "", false
+	}
+	domain, err := publicsuffix.EffectiveTLDPlusOne(host)
+	if err != nil {
+		return "", false
+	}
+	return domain, true
+}
+
+// checkDomain looks up the registration of the registrable domain of the
+// endpoint URL over RDAP, falling back to WHOIS. It fails when neither
+// reports an expiration date or the domain has expired; alerting ahead of
+// the expiration is left to the alert thresholds.
+func (c *Checker) checkDomain(ctx context.Context, endpoint config.EndpointConfig) Result {
+	result := newResult(endpoint)
+
+	domain, ok := RegistrableDomain(endpoint.URL)
+	if !ok {
+		result.Error = fmt.Sprintf("%q has no registrable domain", endpoint.URL)
+		return result
+	}
+
+	start := time.Now()
+	reg, err := c.lookupRDAP(ctx, endpoint.Domain.RDAPServer, domain)
+	if err != nil {
+		var whoisErr error
+		reg, whoisErr = lookupWHOIS(ctx, endpoint.Domain.WHOISServer, domain)
+		if whoisErr != nil {
+			result.Duration = time.Since(start)
+			result.Error = fmt.Sprintf("RDAP lookup failed: %v; WHOIS lookup failed: %v", err, whoisErr)
+			return result
+		}
+	}
+	result.Duration = time.Since(start)
+	result.DomainExpiry = reg.expiry
+	result.Registrar = reg.registrar
+
+	if time.Now().After(reg.expiry) {
+		result.Error = fmt.Sprintf("domain %s expired on %s", domain, reg.expiry.Format(time.DateOnly))
+		return result
+	}
+
+	result.Success = true
+	return result
+}
+
+// lookupRDAP queries the RDAP server of the domain's TLD, or server when set
+func (c *Checker) lookupRDAP(ctx context.Context, server, domain string) (domainRegistration, error) {
+	if server == "" {
+		var err error
+		if server, err = c.rdapServer(ctx, domain); err != nil {
+			return domainRegistration{}, err
+		}
+	}
+
+	var doc rdapDomain
+	if err := c.getRDAP(ctx, strings.TrimSuffix(server, "/")+"/domain/"+domain, &doc); err != nil {
+		return domainRegistration{}, err
+	}
+
+	var reg domainRegistration
+	for _, event := range doc.Events {
+		if event.Action == "expiration" {
+			t, err := time.Parse(time.RFC3339, event.Date)
+			if err != nil {
+				return domainRegistration{}, fmt.Errorf("invalid expiration date %q", event.Date)
+			}
+			reg.expiry = t
+		}
+	}
+	if reg.expiry.IsZero() {
+		return domainRegistration{}, fmt.Errorf("no expiration event for %s", domain)
+	}
+	reg.registrar = rdapRegistrar(doc.Entities)
+	return reg, nil
+}
+
+// rdapServer returns the RDAP base URL of the domain's TLD from the IANA
+// bootstrap registry, downloaded once a day
+func (c *Checker) rdapServer(ctx context.Context, domain string) (string, error) {
+	c.mu.RLock()
+	bootstrap := c.rdap
+	c.mu.RUnlock()
+
+	if bootstrap == nil || time.Since(bootstrap.fetched) > rdapBootstrapTTL {
+		var doc struct {
+			Services [][][]string `json:"services"`
+		}
+		if err := c.getRDAP(ctx, rdapBootstrapURL, &doc); err != nil {
+			return "", fmt.Errorf("failed to load the RDAP bootstrap registry: %w", err)
+		}
+		bootstrap = &rdapBootstrap{servers: make(map[string]string), fetched: time.Now()}
+		for _, service := range doc.Services {
+			if len(service) != 2 || len(service[1]) == 0 {
+				continue
+			}
+			for _, tld := range service[0] {
+				bootstrap.servers[strings.ToLower(tld)] = service[1][0]
+			}
+		}
+		c.mu.Lock()
+		c.rdap = bootstrap
+		c.mu.Unlock()
+	}
+
+	// Registries may serve a multi-label suffix, e.g. "co.uk", or only the TLD
+	for suffix := domain; suffix != ""; {
+		if server, ok := bootstrap.servers[suffix]; ok {
+			return server, nil
+		}
+		_, suffix, _ = strings.Cut(suffix, ".")
+	}
+	return "", fmt.Errorf("no RDAP server for %s", domain)
+}
+
+// getRDAP decodes the JSON document at u
+func (c *Checker) getRDAP(ctx context.Context, u string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/rdap+json, application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("%s: not found", u)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: status code %d", u, resp.StatusCode)
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxRDAPResponseSize)).Decode(v); err != nil {
+		return fmt.Errorf("%s: invalid response: %w", u, err)
+	}
+	return nil
+}
+
+// rdapDomain holds the parts of an RDAP domain object (RFC 9083) we use
+type rdapDomain struct {
+	Events []struct {
+		Action string `json:"eventAction"`
+		Date   string `json:"eventDate"`
+	} `json:"events"`
+	Entities []rdapEntity `json:"entities"`
+}
+
+type rdapEntity struct {
+	Roles    []string          `json:"roles"`
+	Handle   string            `json:"handle"`
+	VCard    []json.RawMessage `json:"vcardArray"`
+	Entities []rdapEntity      `json:"entities"`
+}
+
+// rdapRegistrar returns the formatted name of the registrar entity, or its
+// handle when it has no vCard
+func rdapRegistrar(entities []rdapEntity) string {
+	for _, entity := range entities {
+		isRegistrar := false
+		for _, role := range entity.Roles {
+			isRegistrar = isRegistrar || role == "registrar"
+		}
+		if !isRegistrar {
+			continue
+		}
+
+		// ["vcard", [["fn", {}, "text", "Example Registrar, Inc."], ...]]
+		var properties [][]any
+		if len(entity.VCard) == 2 && json.Unmarshal(entity.VCard[1], &properties) == nil {
+			for _, p := range properties {
+				if len(p) == 4 && p[0] == "fn" {
+					if name, ok := p[3].(string); ok && name != "" {
+						return name
+					}
+				}
+			}
+		}
+		return entity.Handle
+	}
+	return ""
+}
+
+// lookupWHOIS queries the WHOIS server of the domain's TLD, as referred by
+// IANA, or server when set
+func lookupWHOIS(ctx context.Context, server, domain string) (domainRegistration, error) {
+	if server == "" {
+		tld := domain[strings.LastIndex(domain, ".")+1:]
+		referral, err := queryWHOIS(ctx, whoisReferralServer, tld)
+		if err != nil {
+			return domainRegistration{}, err
+		}
+		fields := whoisFields(referral)
+		if server = fields["refer"]; server == "" {
+			server = fields["whois"]
+		}
+		if server == "" {
+			return domainRegistration{}, fmt.Errorf("no WHOIS server for .%s", tld)
+		}
+	}
+	if _, _, err := net.SplitHostPort(server); err != nil {
+		server = net.JoinHostPort(server, "43")
+	}
+
+	response, err := queryWHOIS(ctx, server, domain)
+	if err != nil {
+		return domainRegistration{}, err
+	}
+	fields := whoisFields(response)
+
+	var reg domainRegistration
+	for _, key := range []string{"registry expiry date", "registrar registration expiration date",
+		"expiration date", "expiry date", "expires on", "expires", "paid-till"} {
+		if value := fields[key]; value != "" {
+			if reg.expiry, err = parseWHOISDate(value); err != nil {
+				return domainRegistration{}, err
+			}
+			break
+		}
+	}
+	if reg.expiry.IsZero() {
+		return domainRegistration{}, fmt.Errorf("%s reported no expiration date for %s", server, domain)
+	}
+	for _, key := range []string{"registrar", "sponsoring registrar", "registrar name"} {
+		if value := fields[key]; value != "" {
+			reg.registrar = value
+			break
+		}
+	}
+	return reg, nil
+}
+
+// queryWHOIS sends a query to a WHOIS server (RFC 3912) and returns its reply
+func queryWHOIS(ctx context.Context, server, query string) (string, error) {
+	conn, err := dialerFrom(ctx).DialContext(ctx, "tcp", server)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	if _, err := io.WriteString(conn, query+"\r\n"); err != nil {
+		return "", fmt.Errorf("failed to send WHOIS query: %w", err)
+	}
+	response, err := io.ReadAll(io.LimitReader(conn, maxWHOISResponse))
+	if err != nil {
+		return "", fmt.Errorf("failed to read WHOIS response: %w", err)
+	}
+	return string(response), nil
+}
+
+// whoisFields returns the first value of each "Key: value" line, keyed by
+// the lower case key
+func whoisFields(response string) map[string]string {
+	fields := make(map[string]string)
+	scanner := bufio.NewScanner(strings.NewReader(response))
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), ":")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+		if _, seen := fields[key]; !seen && value != "" {
+			fields[key] = value
+		}
+	}
+	return fields
+}
+
+// whoisDateLayouts are the date formats used by common registries
+var whoisDateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04:05 MST",
+	time.DateOnly,
+	"2006.01.02",
+	"02-Jan-2006",
+	"02.01.2006",
+}
+
+func parseWHOISDate(value string) (time.Time, error) {
+	for _, layout := range whoisDateLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unrecognized WHOIS date %q", value)
+}
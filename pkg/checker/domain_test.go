@@ -0,0 +1,159 @@
+package checker
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/manu/octo/pkg/config"
+)
+
+func TestRegistrableDomain(t *testing.T) {
+	tests := []struct {
+		target string
+		want   string
+	}{
+		{"https://api.example.com/health", "example.com"},
+		{"https://www.shop.example.co.uk:8443", "example.co.uk"},
+		{"mail.example.org:25", "example.org"},
+		{"example.net", "example.net"},
+		{"wss://Stream.Example.COM./feed", "example.com"},
+		{"db.internal:5432", ""},
+		{"http://10.0.0.12:8080", ""},
+		{"[2001:db8::1]:443", ""},
+		{"localhost:6379", ""},
+		{"co.uk", ""},
+	}
+
+	for _, tt := range tests {
+		got, ok := RegistrableDomain(tt.target)
+		if got != tt.want || ok != (tt.want != "") {
+			t.Errorf("RegistrableDomain(%q) = %q, %v; want %q", tt.target, got, ok, tt.want)
+		}
+	}
+}
+
+// newRDAPServer stands in for a registry RDAP server knowing example.com
+func newRDAPServer(expiry time.Time) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/domain/example.com" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/rdap+json")
+		fmt.Fprintf(w, `{
+			"objectClassName": "domain",
+			"ldhName": "EXAMPLE.COM",
+			"events": [
+				{"eventAction": "registration", "eventDate": "1995-08-14T04:00:00Z"},
+				{"eventAction": "expiration", "eventDate": %q}
+			],
+			"entities": [{
+				"objectClassName": "entity",
+				"handle": "376",
+				"roles": ["registrar"],
+				"vcardArray": ["vcard", [["version", {}, "text", "4.0"], ["fn", {}, "text", "Example Registrar, Inc."]]]
+			}]
+		}`, expiry.Format(time.RFC3339))
+	}))
+}
+
+// newWHOISServer answers WHOIS queries for example.com until the test ends
+func newWHOISServer(t *testing.T, response string) string {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			query, _ := bufio.NewReader(conn).ReadString('\n')
+			if strings.TrimSpace(query) == "example.com" {
+				conn.Write([]byte(response))
+			} else {
+				conn.Write([]byte("No match for \"" + strings.TrimSpace(query) + "\".\r\n"))
+			}
+			conn.Close()
+		}
+	}()
+	return ln.Addr().String()
+}
+
+func TestChecker_Check_DomainRDAP(t *testing.T) {
+	expiry := time.Now().Add(90 * 24 * time.Hour).UTC().Truncate(time.Second)
+	ts := newRDAPServer(expiry)
+	defer ts.Close()
+
+	c := NewChecker()
+	result := c.Check(context.Background(), config.EndpointConfig{
+		ID: "domain", Type: TypeDomain, URL: "https://api.example.com/health",
+		Domain: config.DomainConfig{RDAPServer: ts.URL},
+	})
+	if !result.Success {
+		t.Fatalf("Expected success, got failure: %s", result.Error)
+	}
+	if !result.DomainExpiry.Equal(expiry) {
+		t.Errorf("Expected expiry %v, got %v", expiry, result.DomainExpiry)
+	}
+	if result.Registrar != "Example Registrar, Inc." {
+		t.Errorf("Expected registrar from the vCard, got %q", result.Registrar)
+	}
+
+	// An expired domain fails the check
+	expired := newRDAPServer(time.Now().Add(-24 * time.Hour))
+	defer expired.Close()
+	result = c.Check(context.Background(), config.EndpointConfig{
+		ID: "domain", Type: TypeDomain, URL: "example.com",
+		Domain: config.DomainConfig{RDAPServer: expired.URL},
+	})
+	if result.Success || !strings.Contains(result.Error, "domain example.com expired on") {
+		t.Errorf("Expected an expired domain to fail, got success=%v error=%q", result.Success, result.Error)
+	}
+}
+
+func TestChecker_Check_DomainWHOISFallback(t *testing.T) {
+	broken := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "rate limited", http.StatusTooManyRequests)
+	}))
+	defer broken.Close()
+
+	whois := newWHOISServer(t, "   Domain Name: EXAMPLE.COM\r\n"+
+		"   Registrar WHOIS Server: whois.example-registrar.com\r\n"+
+		"   Registry Expiry Date: 2031-08-13T04:00:00Z\r\n"+
+		"   Registrar: Example Registrar, Inc.\r\n"+
+		">>> Last update of whois database: 2026-10-18T08:00:00Z <<<\r\n")
+
+	c := NewChecker()
+	result := c.Check(context.Background(), config.EndpointConfig{
+		ID: "domain", Type: TypeDomain, URL: "https://www.example.com",
+		Domain: config.DomainConfig{RDAPServer: broken.URL, WHOISServer: whois},
+	})
+	if !result.Success {
+		t.Fatalf("Expected success through WHOIS, got failure: %s", result.Error)
+	}
+	if want := time.Date(2031, 8, 13, 4, 0, 0, 0, time.UTC); !result.DomainExpiry.Equal(want) {
+		t.Errorf("Expected expiry %v, got %v", want, result.DomainExpiry)
+	}
+	if result.Registrar != "Example Registrar, Inc." {
+		t.Errorf("Expected registrar from WHOIS, got %q", result.Registrar)
+	}
+
+	result = c.Check(context.Background(), config.EndpointConfig{
+		ID: "domain", Type: TypeDomain, URL: "example.org",
+		Domain: config.DomainConfig{RDAPServer: broken.URL, WHOISServer: whois},
+	})
+	if result.Success || !strings.Contains(result.Error, "status code 429") || !strings.Contains(result.Error, "no expiration date") {
+		t.Errorf("Expected both lookups to fail for an unknown domain, got %q", result.Error)
+	}
+}
@@ -0,0 +1,213 @@
+package checker
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"math/bits"
+	"mime"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+
+	"github.com/manu/octo/pkg/config"
+)
+
+// Defaults of drift detection
+const (
+	defaultDriftThreshold = 0.9
+	maxDriftContent       = 64 * 1024
+)
+
+// driftKey identifies the baseline of an endpoint checked over an IP
+// version, as each address family may be served a different body
+type driftKey struct {
+	id, ipVersion string
+}
+
+// driftBaseline is the fingerprint of a body seen for an endpoint. A
+// baseline seeded from storage only has the SimHash.
+type driftBaseline struct {
+	sum     [sha256.Size]byte
+	simhash uint64
+	seeded  bool
+}
+
+// unchanged reports whether the body of current is the one of b
+func (b driftBaseline) unchanged(current driftBaseline) bool {
+	if b.seeded {
+		return b.simhash == current.simhash
+	}
+	return b.sum == current.sum
+}
+
+// driftState holds the body drift is measured against, which only moves
+// when a change is reported, and the body of the last check, which tells
+// whether the content must be kept for the diff of the last two versions
+type driftState struct {
+	reference driftBaseline
+	last      driftBaseline
+}
+
+// SeedDriftBaseline restores the drift state of an endpoint from the content
+// hashes recorded before a restart, the reference one and the last one,
+// unless the endpoint was checked since
+func (c *Checker) SeedDriftBaseline(endpoint config.EndpointConfig, reference, last string) error {
+	var state driftState
+	for _, seed := range []struct {
+		hash string
+		dst  *driftBaseline
+	}{{reference, &state.reference}, {last, &state.last}} {
+		simhash, err := strconv.ParseUint(seed.hash, 16, 64)
+		if err != nil {
+			return fmt.Errorf("invalid content hash %q: %w", seed.hash, err)
+		}
+		*seed.dst = driftBaseline{simhash: simhash, seeded: true}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	key := driftKey{endpoint.ID, endpoint.IPVersion}
+	if _, ok := c.drift[key]; !ok {
+		c.drift[key] = state
+	}
+	return nil
+}
+
+// detectDrift fingerprints the normalized body of a successful check and
+// compares it with the reference body of the endpoint over the same IP
+// version. The check fails when their similarity falls below the threshold.
+// The normalized body is kept in result.Content when it changed since the
+// last check, so the last two versions can be diffed.
+//
+// The reference is the first body seen and only moves to a body that was
+// reported as a change: a change is reported once, and gradual changes add
+// up until they cross the threshold.
+func (c *Checker) detectDrift(endpoint config.EndpointConfig, result *Result, header http.Header, body []byte) {
+	drift := endpoint.Drift
+	content, err := normalizeContent(drift.Ignore, result.StatusCode, header, body)
+	if err != nil {
+		result.Success = false
+		result.Error = err.Error()
+		return
+	}
+
+	current := driftBaseline{sum: sha256.Sum256([]byte(content)), simhash: simhash(content)}
+	result.ContentHash = fmt.Sprintf("%016x", current.simhash)
+	threshold := drift.Threshold
+	if threshold <= 0 {
+		threshold = defaultDriftThreshold
+	}
+
+	key := driftKey{endpoint.ID, endpoint.IPVersion}
+	c.mu.Lock()
+	state, seen := c.drift[key]
+	reference := state.reference
+	if !seen {
+		reference = current
+	}
+	result.ContentSimilarity = 1 - float64(bits.OnesCount64(reference.simhash^current.simhash))/64
+	drifted := result.ContentSimilarity < threshold
+	if !seen || drifted {
+		state.reference = current
+	}
+	previous := state.last
+	state.last = current
+	c.drift[key] = state
+	c.mu.Unlock()
+
+	// The first body, or one that changed since the last check, is kept
+	if !seen || !previous.unchanged(current) {
+		if len(content) > maxDriftContent {
+			content = strings.ToValidUTF8(content[:maxDriftContent], "")
+		}
+		result.Content = content
+	}
+
+	if drifted {
+		result.ContentDrift = true
+		result.Success = false
+		result.Error = fmt.Sprintf("content changed: similarity %.2f below %.2f (content hash %s, reference %016x)",
+			result.ContentSimilarity, threshold, result.ContentHash, reference.simhash)
+	}
+}
+
+// normalizeContent removes the ignored regions of a body and splits it into
+// lines: one per tag and text node for HTML, one per non-blank line
+// otherwise, with whitespace collapsed. The status line comes first so a
+// status change is part of the content. It is stored as text, so invalid
+// UTF-8 and NUL bytes are replaced.
+func normalizeContent(ignore []string, statusCode int, header http.Header, body []byte) (string, error) {
+	for _, pattern := range ignore {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return "", fmt.Errorf("invalid drift ignore regex %q: %w", pattern, err)
+		}
+		body = re.ReplaceAll(body, nil)
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "status %d\n", statusCode)
+	addLine := func(line string) {
+		if line = strings.Join(strings.Fields(line), " "); line != "" {
+			sb.WriteString(line)
+			sb.WriteByte('\n')
+		}
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(header.Get("Content-Type"))
+	if mediaType == "text/html" || mediaType == "application/xhtml+xml" {
+		z := html.NewTokenizer(bytes.NewReader(body))
+		for {
+			if z.Next() == html.ErrorToken {
+				if err := z.Err(); !errors.Is(err, io.EOF) {
+					return "", fmt.Errorf("malformed HTML document: %w", err)
+				}
+				break
+			}
+			addLine(string(z.Raw()))
+		}
+	} else {
+		for _, line := range strings.Split(string(body), "\n") {
+			addLine(line)
+		}
+	}
+	return strings.ReplaceAll(strings.ToValidUTF8(sb.String(), "\uFFFD"), "\x00", "\uFFFD"), nil
+}
+
+// simhash computes a 64 bit SimHash of the word pairs of content. Similar
+// contents have hashes differing by few bits.
+func simhash(content string) uint64 {
+	words := strings.Fields(content)
+	var weights [64]int
+	h := fnv.New64a()
+	for i := range words {
+		h.Reset()
+		io.WriteString(h, words[i])
+		if i+1 < len(words) {
+			io.WriteString(h, " "+words[i+1])
+		}
+		feature := h.Sum64()
+		for b := range 64 {
+			if feature&(1<<b) != 0 {
+				weights[b]++
+			} else {
+				weights[b]--
+			}
+		}
+	}
+
+	var hash uint64
+	for b, w := range weights {
+		if w > 0 {
+			hash |= 1 << b
+		}
+	}
+	return hash
+}
@@ -0,0 +1,200 @@
+package checker
+
+import (
+	"context"
+	"fmt"
+	"math/bits"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/manu/octo/pkg/config"
+)
+
+const storePage = `<!DOCTYPE html>
+<html>
+<head><title>Example Store</title><link rel="stylesheet" href="/app.css"></head>
+<body>
+<nav><a href="/">Home</a> <a href="/products">Products</a> <a href="/support">Support</a></nav>
+<h1>Welcome to the Example Store</h1>
+<p>Browse our catalogue of hardware, software and accessories, with free shipping on orders over fifty dollars.</p>
+<ul>
+<li>Laptops and desktops for work and play</li>
+<li>Monitors, keyboards and mice</li>
+<li>Networking equipment and cables</li>
+<li>Extended warranties and support plans</li>
+</ul>
+<form method="post" action="/newsletter"><input type="hidden" name="csrf" value="%s">
+<input name="email" placeholder="Your email"><button>Subscribe</button></form>
+<footer>Rendered at %s. Copyright Example Store, all rights reserved.</footer>
+</body>
+</html>`
+
+func TestChecker_Check_Drift(t *testing.T) {
+	var page atomic.Value
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		body := page.Load().(string)
+		// The token and render time change on every request
+		fmt.Fprintf(w, body, fmt.Sprint(time.Now().UnixNano()), time.Now().Format(time.RFC3339Nano))
+	}))
+	defer ts.Close()
+
+	c := NewChecker()
+	endpoint := config.EndpointConfig{
+		ID: "store", URL: ts.URL, Method: "GET",
+		Drift: config.DriftConfig{Enabled: true, Ignore: []string{`name="csrf" value="[^"]*"`, `Rendered at \S+`}},
+	}
+
+	defaced := `<html><body><h1>HACKED BY NOBODY</h1><input name="csrf" value="%s"><p>Rendered at %s</p></body></html>`
+	steps := []struct {
+		name      string
+		page      string
+		wantDrift bool
+	}{
+		{name: "baseline", page: storePage},
+		{name: "unchanged apart from ignored regions", page: storePage},
+		{name: "small edit", page: strings.Replace(storePage, "fifty dollars", "forty dollars", 1)},
+		{name: "defacement", page: defaced, wantDrift: true},
+		{name: "defaced page stays", page: defaced},
+	}
+
+	for _, step := range steps {
+		page.Store(step.page)
+		result := c.Check(context.Background(), endpoint)
+		if result.ContentDrift != step.wantDrift || result.Success == step.wantDrift {
+			t.Errorf("%s: expected drift=%v, got drift=%v success=%v similarity=%.2f error=%q",
+				step.name, step.wantDrift, result.ContentDrift, result.Success, result.ContentSimilarity, result.Error)
+		}
+		if result.ContentHash == "" {
+			t.Errorf("%s: expected a content hash", step.name)
+		}
+
+		// The normalized content is kept when it changed, for diffs
+		changed := step.name != "unchanged apart from ignored regions" && step.name != "defaced page stays"
+		if (result.Content != "") != changed {
+			t.Errorf("%s: expected content kept=%v, got %q", step.name, changed, result.Content)
+		}
+	}
+}
+
+func TestChecker_Check_GradualDrift(t *testing.T) {
+	endpoint := config.EndpointConfig{ID: "catalogue", Drift: config.DriftConfig{Enabled: true}}
+	header := http.Header{"Content-Type": {"text/plain"}}
+	page := func(replaced int) string {
+		var sb strings.Builder
+		for i := range 40 {
+			if i < replaced {
+				fmt.Fprintf(&sb, "discontinued product %d withdrawn from sale\n", i)
+			} else {
+				fmt.Fprintf(&sb, "product %d in stock ships today\n", i)
+			}
+		}
+		return sb.String()
+	}
+
+	// Every edit is small, but they add up to a changed page
+	c := NewChecker()
+	drifted := -1
+	for replaced := 0; replaced <= 40 && drifted < 0; replaced++ {
+		result := Result{Success: true, StatusCode: 200}
+		c.detectDrift(endpoint, &result, header, []byte(page(replaced)))
+		if result.ContentDrift {
+			drifted = replaced
+		}
+	}
+	if drifted < 0 {
+		t.Fatal("Expected gradual changes to be reported once they cross the threshold")
+	}
+	previous, _ := normalizeContent(nil, 200, header, []byte(page(drifted-1)))
+	current, _ := normalizeContent(nil, 200, header, []byte(page(drifted)))
+	if similarity := 1 - float64(bits.OnesCount64(simhash(previous)^simhash(current)))/64; similarity < defaultDriftThreshold {
+		t.Fatalf("Expected the last edit alone to stay above the threshold, got similarity %.2f", similarity)
+	}
+
+	// The reported body becomes the reference
+	result := Result{Success: true, StatusCode: 200}
+	c.detectDrift(endpoint, &result, header, []byte(page(drifted)))
+	if result.ContentDrift || result.ContentSimilarity != 1 || result.Content != "" {
+		t.Errorf("Expected the reported body to become the reference, got %+v", result)
+	}
+}
+
+func TestChecker_SeedDriftBaseline(t *testing.T) {
+	endpoint := config.EndpointConfig{ID: "store", Drift: config.DriftConfig{Enabled: true}}
+	header := http.Header{"Content-Type": {"text/html"}}
+	check := func(c *Checker, ep config.EndpointConfig, body string) Result {
+		result := Result{Success: true, StatusCode: 200}
+		c.detectDrift(ep, &result, header, []byte(body))
+		return result
+	}
+	stored := check(NewChecker(), endpoint, storePage).ContentHash
+
+	// After a restart, the body stored last is not reported again
+	c := NewChecker()
+	if err := c.SeedDriftBaseline(endpoint, stored, stored); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if result := check(c, endpoint, storePage); !result.Success || result.Content != "" || result.ContentSimilarity != 1 {
+		t.Errorf("Expected the seeded body to be unchanged, got %+v", result)
+	}
+
+	// The reference and the last body are restored apart
+	edited := strings.Replace(storePage, "fifty dollars", "forty dollars", 1)
+	c = NewChecker()
+	c.SeedDriftBaseline(endpoint, stored, check(NewChecker(), endpoint, edited).ContentHash)
+	if result := check(c, endpoint, edited); result.Content != "" || result.ContentSimilarity == 1 {
+		t.Errorf("Expected the last body to be unchanged and measured against the reference, got %+v", result)
+	}
+
+	// and a defacement during the restart is caught by the first check
+	c = NewChecker()
+	c.SeedDriftBaseline(endpoint, stored, stored)
+	if result := check(c, endpoint, "<html><body><h1>HACKED BY NOBODY</h1></body></html>"); !result.ContentDrift {
+		t.Errorf("Expected drift from the seeded baseline, got %+v", result)
+	}
+
+	if err := c.SeedDriftBaseline(endpoint, "not hex", stored); err == nil {
+		t.Error("Expected an invalid content hash to be rejected")
+	}
+
+	// Each address family has its own baseline
+	v4, v6 := endpoint, endpoint
+	v4.IPVersion, v6.IPVersion = "4", "6"
+	c = NewChecker()
+	for range 2 {
+		for _, tt := range []struct {
+			endpoint config.EndpointConfig
+			body     string
+		}{{v4, storePage}, {v6, "<html><body><p>IPv6 is served by another site</p></body></html>"}} {
+			if result := check(c, tt.endpoint, tt.body); result.ContentDrift {
+				t.Errorf("IPv%s: expected no drift, got %+v", tt.endpoint.IPVersion, result)
+			}
+		}
+	}
+}
+
+func TestNormalizeContent(t *testing.T) {
+	header := http.Header{"Content-Type": {"text/html"}}
+	got, err := normalizeContent([]string{`\d{2}:\d{2}`}, 200, header,
+		[]byte("<html><body>\n  <p>Updated   at 12:30</p><script src=\"/app.js\"></script></body></html>"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	want := "status 200\n<html>\n<body>\n<p>\nUpdated at\n</p>\n<script src=\"/app.js\">\n</script>\n</body>\n</html>\n"
+	if got != want {
+		t.Errorf("Unexpected normalized HTML:\n%s\nwant:\n%s", got, want)
+	}
+
+	got, _ = normalizeContent(nil, 200, http.Header{}, []byte("id\x00\xff 1\n\n"))
+	if want := "status 200\nid\uFFFD\uFFFD 1\n"; got != want {
+		t.Errorf("Expected NUL bytes and invalid UTF-8 to be replaced, got %q", got)
+	}
+
+	if _, err := normalizeContent([]string{"("}, 200, header, nil); err == nil || !strings.Contains(err.Error(), "invalid drift ignore regex") {
+		t.Errorf("Expected an invalid regex to be rejected, got %v", err)
+	}
+}
@@ -0,0 +1,202 @@
+package checker
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/jlaffaye/ftp"
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+
+	"github.com/manu/octo/pkg/config"
+)
+
+// checkFTP logs in and lists the configured directory. FTPS is used with
+// implicit TLS (tls or ftps://) or AUTH TLS (starttls). Without credentials
+// the login is anonymous. TTFB covers the listing.
+func (c *Checker) checkFTP(ctx context.Context, endpoint config.EndpointConfig) Result {
+	result := newResult(endpoint)
+
+	t, err := resolveServiceTarget(endpoint, "21", "990")
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	// The first handshake is the control connection's, data connections
+	// follow. It happens on first use, which is the greeting with implicit
+	// TLS and the login with AUTH TLS, so the certificate is inspected while
+	// handshaking for the credentials not to be sent to a rejected server.
+	var tlsErr error
+	handshaken := false
+	tlsCfg := c.tlsConfig(t.host)
+	tlsCfg.VerifyConnection = func(cs tls.ConnectionState) error {
+		if handshaken {
+			return nil
+		}
+		handshaken = true
+		tlsErr = c.inspectTLS(ctx, &result, endpoint.SSL, t.host, &cs)
+		return tlsErr
+	}
+
+	// Given a dial function the library leaves TLS to it: wrap the control
+	// connection for implicit TLS and every data connection once TLS is on.
+	// The control connection of AUTH TLS is upgraded by the library.
+	// Connections inherit the context deadline, so a server that never
+	// greets or never completes the handshake cannot hold the check.
+	dialer := dialerFrom(ctx)
+	control := true
+	opts := []ftp.DialOption{
+		ftp.DialWithContext(ctx),
+		ftp.DialWithDialFunc(func(network, address string) (net.Conn, error) {
+			conn, err := dialer.DialContext(ctx, network, address)
+			if err != nil {
+				return nil, err
+			}
+			if deadline, ok := ctx.Deadline(); ok {
+				conn.SetDeadline(deadline)
+			}
+			wrap := t.useTLS || (endpoint.Service.StartTLS && !control)
+			control = false
+			if wrap {
+				return tls.Client(conn, tlsCfg), nil
+			}
+			return conn, nil
+		}),
+	}
+	switch {
+	case t.useTLS:
+		opts = append(opts, ftp.DialWithTLS(tlsCfg))
+	case endpoint.Service.StartTLS:
+		opts = append(opts, ftp.DialWithExplicitTLS(tlsCfg))
+	}
+
+	start := time.Now()
+	conn, err := ftp.Dial(t.address, opts...)
+	result.ConnDuration = time.Since(start)
+	if tlsErr != nil {
+		result.Duration = time.Since(start)
+		result.Error = tlsErr.Error()
+		return result
+	}
+	if err != nil {
+		result.Duration = time.Since(start)
+		result.Error = "connection failed: " + err.Error()
+		return result
+	}
+	defer conn.Quit()
+
+	// Unblock the control connection once the check times out
+	stop := context.AfterFunc(ctx, func() { conn.Quit() })
+	defer stop()
+
+	fail := func(format string, args ...any) Result {
+		result.Duration = time.Since(start)
+		result.Error = fmt.Sprintf(format, args...)
+		return result
+	}
+
+	username, password := t.username, t.password
+	if username == "" {
+		username, password = "anonymous", "anonymous"
+	}
+	if err := conn.Login(username, password); err != nil {
+		if tlsErr != nil {
+			return fail("%v", tlsErr)
+		}
+		return fail("login failed: %v", err)
+	}
+
+	listStart := time.Now()
+	entries, err := conn.List(endpoint.Service.Path)
+	result.TTFB = time.Since(listStart)
+	if err != nil {
+		return fail("list failed: %v", err)
+	}
+	result.QueryResult = fmt.Sprintf("%d entries", len(entries))
+
+	result.Duration = time.Since(start)
+	result.Success = true
+	return result
+}
+
+// checkSFTP logs in over SSH with a password and lists the configured
+// directory. The host key must match host_key, so the password is never
+// sent to a server that is not the one configured.
+func (c *Checker) checkSFTP(ctx context.Context, endpoint config.EndpointConfig) Result {
+	result := newResult(endpoint)
+
+	t, err := resolveServiceTarget(endpoint, "22", "")
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	sshConfig := &ssh.ClientConfig{
+		User: t.username,
+		Auth: []ssh.AuthMethod{ssh.Password(t.password)},
+		HostKeyCallback: func(_ string, _ net.Addr, key ssh.PublicKey) error {
+			fingerprint := ssh.FingerprintSHA256(key)
+			if endpoint.Service.HostKey == "" {
+				return fmt.Errorf("host key %s is not trusted, set host_key", fingerprint)
+			}
+			if fingerprint != endpoint.Service.HostKey {
+				return fmt.Errorf("host key %s does not match %s", fingerprint, endpoint.Service.HostKey)
+			}
+			return nil
+		},
+	}
+
+	start := time.Now()
+	conn, err := c.dialService(ctx, &result, t)
+	if err != nil {
+		result.Duration = time.Since(start)
+		result.Error = "connection failed: " + err.Error()
+		return result
+	}
+	defer conn.Close()
+
+	fail := func(format string, args ...any) Result {
+		result.Duration = time.Since(start)
+		result.Error = fmt.Sprintf(format, args...)
+		return result
+	}
+
+	sshConn, chans, reqs, err := ssh.NewClientConn(conn, t.address, sshConfig)
+	if err != nil {
+		return fail("ssh login failed: %v", err)
+	}
+	client := ssh.NewClient(sshConn, chans, reqs)
+	defer client.Close()
+	setBanner(&result, string(sshConn.ServerVersion()))
+
+	sftpClient, err := sftp.NewClient(client)
+	if err != nil {
+		return fail("sftp session failed: %v", err)
+	}
+	defer sftpClient.Close()
+
+	path := endpoint.Service.Path
+	if path == "" {
+		path = "."
+	}
+	listStart := time.Now()
+	entries, err := sftpClient.ReadDir(path)
+	result.TTFB = time.Since(listStart)
+	if err != nil {
+		return fail("list failed: %v", err)
+	}
+	result.QueryResult = fmt.Sprintf("%d entries", len(entries))
+	result.Duration = time.Since(start)
+
+	if t.expect != nil && !t.expect.MatchString(result.Banner) {
+		result.Error = fmt.Sprintf("banner does not match %q", t.expect.String())
+		return result
+	}
+
+	result.Success = true
+	return result
+}
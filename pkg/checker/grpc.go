@@ -0,0 +1,122 @@
+package checker
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"github.com/manu/octo/pkg/config"
+)
+
+// checkGRPC calls grpc.health.v1.Health/Check on the target. ConnDuration
+// covers establishing the connection and TTFB the health RPC itself.
+func (c *Checker) checkGRPC(ctx context.Context, endpoint config.EndpointConfig) Result {
+	result := newResult(endpoint)
+
+	useTLS := endpoint.GRPC.TLS || strings.HasPrefix(endpoint.URL, "grpcs://")
+	defaultPort := ""
+	if useTLS {
+		defaultPort = "443"
+	}
+	address, err := targetAddress(endpoint.URL, defaultPort)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	host, _, _ := net.SplitHostPort(address)
+
+	creds := insecure.NewCredentials()
+	if useTLS {
+		creds = credentials.NewTLS(c.tlsConfig(host))
+	}
+
+	start := time.Now()
+	dialer := dialerFrom(ctx)
+	conn, err := grpc.NewClient(address,
+		grpc.WithTransportCredentials(creds),
+		grpc.WithContextDialer(func(ctx context.Context, addr string) (net.Conn, error) {
+			return dialer.DialContext(ctx, "tcp", addr)
+		}),
+	)
+	if err != nil {
+		result.Error = "invalid grpc target: " + err.Error()
+		return result
+	}
+	defer conn.Close()
+
+	err = waitForReady(ctx, conn)
+	result.ConnDuration = time.Since(start)
+	if err != nil {
+		result.Duration = time.Since(start)
+		result.Error = "grpc connection failed: " + err.Error()
+		return result
+	}
+
+	rpcCtx := ctx
+	if len(endpoint.GRPC.Metadata) > 0 {
+		rpcCtx = metadata.NewOutgoingContext(ctx, metadata.New(endpoint.GRPC.Metadata))
+	}
+
+	var p peer.Peer
+	rpcStart := time.Now()
+	resp, err := healthpb.NewHealthClient(conn).Check(rpcCtx, &healthpb.HealthCheckRequest{
+		Service: endpoint.GRPC.Service,
+	}, grpc.Peer(&p))
+	result.TTFB = time.Since(rpcStart)
+	result.Duration = time.Since(start)
+
+	if tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo); ok {
+		if err := c.inspectTLS(ctx, &result, endpoint.SSL, host, &tlsInfo.State); err != nil {
+			result.Error = err.Error()
+			return result
+		}
+	}
+
+	if err != nil {
+		st := status.Convert(err)
+		result.Error = fmt.Sprintf("grpc health check failed: %s: %s", st.Code(), st.Message())
+		return result
+	}
+
+	result.HealthStatus = resp.GetStatus().String()
+	expected := strings.ToUpper(endpoint.GRPC.ExpectedStatus)
+	if expected == "" {
+		expected = healthpb.HealthCheckResponse_SERVING.String()
+	}
+	if result.HealthStatus != expected {
+		result.Error = fmt.Sprintf("health status is %s, expected %s", result.HealthStatus, expected)
+		return result
+	}
+
+	result.Success = true
+	return result
+}
+
+// waitForReady connects eagerly so connection setup can be timed separately
+// from the RPC
+func waitForReady(ctx context.Context, conn *grpc.ClientConn) error {
+	conn.Connect()
+	for {
+		state := conn.GetState()
+		switch state {
+		case connectivity.Ready:
+			return nil
+		case connectivity.TransientFailure, connectivity.Shutdown:
+			return fmt.Errorf("connection is in state %s", state)
+		}
+		if !conn.WaitForStateChange(ctx, state) {
+			return ctx.Err()
+		}
+	}
+}
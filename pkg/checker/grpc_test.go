@@ -0,0 +1,105 @@
+package checker
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/manu/octo/pkg/config"
+)
+
+// startHealthServer serves the standard health service. Requests carrying an
+// "authorization" header other than "Bearer secret" are rejected.
+func startHealthServer(t *testing.T) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+
+	auth := func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		if values := md.Get("authorization"); len(values) > 0 && values[0] != "Bearer secret" {
+			return nil, status.Error(codes.Unauthenticated, "bad token")
+		}
+		return handler(ctx, req)
+	}
+
+	srv := grpc.NewServer(grpc.UnaryInterceptor(auth))
+	hs := health.NewServer()
+	hs.SetServingStatus("orders.v1.Orders", healthpb.HealthCheckResponse_SERVING)
+	hs.SetServingStatus("billing.v1.Billing", healthpb.HealthCheckResponse_NOT_SERVING)
+	healthpb.RegisterHealthServer(srv, hs)
+
+	go srv.Serve(ln)
+	t.Cleanup(srv.Stop)
+
+	return ln.Addr().String()
+}
+
+func TestChecker_Check_GRPC(t *testing.T) {
+	addr := startHealthServer(t)
+
+	tests := []struct {
+		name    string
+		url     string
+		grpc    config.GRPCConfig
+		wantErr string
+	}{
+		{name: "server health", url: "grpc://" + addr},
+		{name: "service serving", url: addr, grpc: config.GRPCConfig{Service: "orders.v1.Orders"}},
+		{name: "service not serving", url: addr, grpc: config.GRPCConfig{Service: "billing.v1.Billing"}, wantErr: "health status is NOT_SERVING"},
+		{name: "expected not serving", url: addr, grpc: config.GRPCConfig{Service: "billing.v1.Billing", ExpectedStatus: "not_serving"}},
+		{name: "unknown service", url: addr, grpc: config.GRPCConfig{Service: "missing.v1.Missing"}, wantErr: "NotFound"},
+		{name: "metadata", url: addr, grpc: config.GRPCConfig{Metadata: map[string]string{"authorization": "Bearer secret"}}},
+		{name: "bad metadata", url: addr, grpc: config.GRPCConfig{Metadata: map[string]string{"authorization": "Bearer wrong"}}, wantErr: "Unauthenticated"},
+	}
+
+	c := NewChecker()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+			defer cancel()
+
+			result := c.Check(ctx, config.EndpointConfig{ID: "grpc", Type: TypeGRPC, URL: tt.url, GRPC: tt.grpc})
+			if tt.wantErr == "" {
+				if !result.Success {
+					t.Errorf("Expected success, got failure: %s", result.Error)
+				}
+				if result.HealthStatus == "" {
+					t.Error("Expected health status to be recorded")
+				}
+				return
+			}
+			if result.Success || !strings.Contains(result.Error, tt.wantErr) {
+				t.Errorf("Expected error containing %q, got success=%v error=%q", tt.wantErr, result.Success, result.Error)
+			}
+		})
+	}
+}
+
+func TestChecker_Check_GRPCConnectionRefused(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	result := NewChecker().Check(ctx, config.EndpointConfig{ID: "grpc", Type: TypeGRPC, URL: addr})
+	if result.Success || !strings.Contains(result.Error, "grpc connection failed") {
+		t.Errorf("Expected connection failure, got success=%v error=%q", result.Success, result.Error)
+	}
+}
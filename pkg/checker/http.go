@@ -3,128 +3,191 @@ package checker
 import (
 	"context"
 	"crypto/tls"
+	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptrace"
-	"regexp"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/manu/octo/pkg/config"
 )
 
-// Result holds the metrics of a check
-type Result struct {
-	Timestamp     time.Time     `json:"timestamp"`
-	EndpointID    string        `json:"endpoint_id"`
-	SatelliteID   string        `json:"satellite_id"`
-	URL           string        `json:"url"`
-	Method        string        `json:"method"`
-	StatusCode    int           `json:"status_code"`
-	Duration      time.Duration `json:"duration"`
-	DNSDuration   time.Duration `json:"dns_duration"`
-	ConnDuration  time.Duration `json:"conn_duration"`
-	TLSDuration   time.Duration `json:"tls_duration"`
-	TTFB          time.Duration `json:"ttfb"`
-	BytesReceived int64         `json:"bytes_received"`
-	Success       bool          `json:"success"`
-	Error         string        `json:"error"`
-
-	// SSL/TLS Info
-	CertExpiry    time.Time `json:"cert_expiry"`
-	CertIssuer    string    `json:"cert_issuer"`
-	CertSubject   string    `json:"cert_subject"`
-	CertNotBefore time.Time `json:"cert_not_before"`
-	CertNotAfter  time.Time `json:"cert_not_after"`
-}
-
-// Checker handles the HTTP checks
-type Checker struct {
-	client *http.Client
-}
+// checkHTTP performs an HTTP(S) request and validates the response, or runs
+// the steps of a multi-step transaction
+func (c *Checker) checkHTTP(ctx context.Context, endpoint config.EndpointConfig) Result {
+	client, release, err := c.sessionClient(ctx, endpoint)
+	if err != nil {
+		result := newResult(endpoint)
+		result.Error = err.Error()
+		return result
+	}
+	defer release()
 
-func NewChecker() *Checker {
-	return &Checker{
-		client: &http.Client{
-			CheckRedirect: func(req *http.Request, via []*http.Request) error {
-				if len(via) >= 10 {
-					return http.ErrUseLastResponse
-				}
-				return nil
-			},
-			Transport: &http.Transport{
-				MaxIdleConns:        100,
-				MaxIdleConnsPerHost: 10,
-				IdleConnTimeout:     90 * time.Second,
-				TLSClientConfig:     &tls.Config{InsecureSkipVerify: false}, // TODO: make configurable
-			},
-		},
+	if len(endpoint.Steps) > 0 {
+		return c.checkSteps(ctx, client, endpoint)
 	}
+	result, header, body := c.doHTTP(ctx, client, endpoint)
+	if result.Success && endpoint.Drift.Enabled {
+		c.detectDrift(endpoint, &result, header, body)
+	}
+	return result
 }
 
-func (c *Checker) Check(ctx context.Context, endpoint config.EndpointConfig) Result {
-	result := Result{
-		Timestamp:  time.Now(),
-		EndpointID: endpoint.ID,
-		URL:        endpoint.URL,
-		Method:     endpoint.Method,
+// sessionClient returns the HTTP client of a check, holding the cookie jar
+// of the endpoint if it has one
+func (c *Checker) sessionClient(ctx context.Context, endpoint config.EndpointConfig) (*http.Client, func(), error) {
+	client, release, err := c.httpClient(ctx, endpoint)
+	if err != nil {
+		return nil, nil, err
+	}
+	jar, err := c.cookieJar(endpoint)
+	if err != nil {
+		release()
+		return nil, nil, err
 	}
+	if jar != nil {
+		withJar := *client
+		withJar.Jar = jar
+		client = &withJar
+	}
+	return client, release, nil
+}
 
-	var dnsStart, connStart, tlsStart, ttfbStart time.Time
+// doHTTP performs a single request and validates the response. The response
+// headers and body are returned once the body has been read, even if a later
+// validation failed. A failed check carries a snapshot of the response.
+//
+// TTFB runs from the request being fully written to the first response byte,
+// i.e. the server processing time. Duration includes reading the body.
+func (c *Checker) doHTTP(ctx context.Context, client *http.Client, endpoint config.EndpointConfig) (result Result, header http.Header, body []byte) {
+	result = newResult(endpoint)
 
+	// Over HTTP/2 the request is written and the response read on different
+	// goroutines, and a dial may outlive the request, so the hooks only record
+	// timestamps under a lock and the durations are computed once it is done
+	var (
+		mu                                                sync.Mutex
+		addr                                              string
+		reused                                            bool
+		dnsStart, dnsDone, connStart, connDone            time.Time
+		tlsStart, tlsDone, connReady, wroteRequest, first time.Time
+	)
+	record := func(t *time.Time) {
+		mu.Lock()
+		defer mu.Unlock()
+		*t = time.Now()
+	}
 	trace := &httptrace.ClientTrace{
-		DNSStart:     func(_ httptrace.DNSStartInfo) { dnsStart = time.Now() },
-		DNSDone:      func(_ httptrace.DNSDoneInfo) { result.DNSDuration = time.Since(dnsStart) },
-		ConnectStart: func(_, _ string) { connStart = time.Now() },
+		DNSStart: func(_ httptrace.DNSStartInfo) { record(&dnsStart) },
+		DNSDone:  func(_ httptrace.DNSDoneInfo) { record(&dnsDone) },
+		ConnectStart: func(_, _ string) {
+			mu.Lock()
+			defer mu.Unlock()
+			// Dialing several addresses reports the first attempt
+			if connStart.IsZero() {
+				connStart = time.Now()
+			}
+		},
 		ConnectDone: func(_, _ string, err error) {
 			if err == nil {
-				result.ConnDuration = time.Since(connStart)
+				record(&connDone)
 			}
 		},
-		TLSHandshakeStart: func() { tlsStart = time.Now() },
+		TLSHandshakeStart: func() { record(&tlsStart) },
 		TLSHandshakeDone: func(_ tls.ConnectionState, err error) {
 			if err == nil {
-				result.TLSDuration = time.Since(tlsStart)
+				record(&tlsDone)
 			}
 		},
-		GotFirstResponseByte: func() {
-			result.TTFB = time.Since(ttfbStart)
+		GotConn: func(info httptrace.GotConnInfo) {
+			mu.Lock()
+			defer mu.Unlock()
+			connReady = time.Now()
+			reused = info.Reused
+			addr = info.Conn.RemoteAddr().String()
 		},
+		WroteRequest:         func(_ httptrace.WroteRequestInfo) { record(&wroteRequest) },
+		GotFirstResponseByte: func() { record(&first) },
+	}
+	// since is the time from a to b, zero unless both happened in order
+	since := func(a, b time.Time) time.Duration {
+		if a.IsZero() || b.IsZero() || b.Before(a) {
+			return 0
+		}
+		return b.Sub(a)
+	}
+	timings := func() (string, time.Time) {
+		mu.Lock()
+		defer mu.Unlock()
+		result.DNSDuration = since(dnsStart, dnsDone)
+		result.ConnDuration = since(connStart, connDone)
+		result.TLSDuration = since(tlsStart, tlsDone)
+		result.WriteDuration = since(connReady, wroteRequest)
+		result.TTFB = since(wroteRequest, first)
+		result.ConnReused = reused
+		return addr, first
+	}
+
+	var reqBody io.Reader
+	if endpoint.Body != "" {
+		reqBody = strings.NewReader(endpoint.Body)
 	}
 
-	req, err := http.NewRequestWithContext(httptrace.WithClientTrace(ctx, trace), endpoint.Method, endpoint.URL, nil)
+	req, err := http.NewRequestWithContext(httptrace.WithClientTrace(ctx, trace), endpoint.Method, endpoint.URL, reqBody)
 	if err != nil {
 		result.Error = err.Error()
-		return result
+		return result, nil, nil
 	}
 
 	for k, v := range endpoint.Headers {
 		req.Header.Add(k, v)
 	}
 
-	// Start total timer
-	start := time.Now()
-	ttfbStart = start // approximate start for TTFB
+	// Cookies set by redirects are only visible while following them
+	var cookies []*http.Cookie
+	if len(endpoint.Validation.Cookies) > 0 {
+		client = recordCookies(client, &cookies)
+	}
 
-	resp, err := c.client.Do(req)
+	recordProxy(&result, dialerFrom(ctx), req.URL)
+	start := time.Now()
+	resp, err := client.Do(req)
 	result.Duration = time.Since(start)
+	remoteAddr, firstByte := timings()
 
 	if err != nil {
 		result.Error = err.Error()
-		return result
+		result.Snapshot = newSnapshot(endpoint.Snapshot, remoteAddr, nil, nil, nil)
+		return result, nil, nil
 	}
 	defer resp.Body.Close()
 
-	if resp.TLS != nil && len(resp.TLS.PeerCertificates) > 0 {
-		cert := resp.TLS.PeerCertificates[0]
-		result.CertExpiry = cert.NotAfter
-		result.CertNotAfter = cert.NotAfter
-		result.CertNotBefore = cert.NotBefore
-		result.CertIssuer = cert.Issuer.String()
-		result.CertSubject = cert.Subject.String()
-	}
+	// Runs before the body is closed, so a body not read yet can be sampled
+	defer func() {
+		if !result.Success {
+			result.Snapshot = newSnapshot(endpoint.Snapshot, remoteAddr, resp.Header, body, resp.Body)
+		}
+	}()
 
 	result.StatusCode = resp.StatusCode
+	result.Protocol = resp.Proto
+	if resp.Request.URL != req.URL {
+		recordProxy(&result, dialerFrom(ctx), resp.Request.URL)
+	}
+
+	if resp.TLS != nil {
+		if err := c.inspectTLS(ctx, &result, endpoint.SSL, resp.Request.URL.Hostname(), resp.TLS); err != nil {
+			result.Error = err.Error()
+			return result, nil, nil
+		}
+	}
+
+	if err := validateHTTPVersion(endpoint.Validation.HTTPVersion, resp); err != nil {
+		result.Error = err.Error()
+		return result, nil, nil
+	}
 
 	// Verify status code
 	statusOk := false
@@ -142,37 +205,116 @@ func (c *Checker) Check(ctx context.Context, endpoint config.EndpointConfig) Res
 
 	if !statusOk {
 		result.Error = "status code validation failed"
-		return result
+		return result, nil, nil
 	}
 
-	// Verify content if needed
-	bodyBytes, err := io.ReadAll(resp.Body)
+	if err := validateHeaders(endpoint.Validation.Headers, resp.Header); err != nil {
+		result.Error = err.Error()
+		return result, nil, nil
+	}
+
+	if err := validateCookies(endpoint.Validation.Cookies, append(cookies, resp.Cookies()...)); err != nil {
+		result.Error = err.Error()
+		return result, nil, nil
+	}
+
+	// Verify content if needed. Substring, regex and checksum validators
+	// run on the stream; the body kept in memory is bounded.
+	stream, err := newBodyStream(endpoint)
+	if err != nil {
+		result.Error = err.Error()
+		return result, nil, nil
+	}
+	defer stream.close()
+
+	err = stream.readFrom(resp.Body)
+	stream.close()
+	result.TransferDuration = time.Since(firstByte)
+	result.Duration = time.Since(start)
 	if err != nil {
 		result.Error = "failed to read body: " + err.Error()
-		return result
+		return result, nil, nil
+	}
+	bodyBytes := stream.bytes()
+	result.BytesReceived = stream.n
+	result.BodyTruncated = stream.truncated
+
+	if err := validateBodySize(endpoint.Validation.BodySize, result.BytesReceived); err != nil {
+		result.Error = err.Error()
+		return result, resp.Header, bodyBytes
+	}
+
+	if stream.checksum != nil {
+		if err := compareChecksum(endpoint.Validation.Checksum, stream.checksum.Sum(nil)); err != nil {
+			result.Error = err.Error()
+			return result, resp.Header, bodyBytes
+		}
 	}
-	result.BytesReceived = int64(len(bodyBytes))
 
 	if endpoint.Validation.ContentMatch.Pattern != "" {
-		bodyStr := string(bodyBytes)
-		if endpoint.Validation.ContentMatch.Type == "regex" {
-			matched, err := regexp.MatchString(endpoint.Validation.ContentMatch.Pattern, bodyStr)
-			if err != nil {
-				result.Error = "invalid regex: " + err.Error()
-				return result
-			}
-			if !matched {
-				result.Error = "content regex match failed"
-				return result
-			}
-		} else {
-			if !strings.Contains(bodyStr, endpoint.Validation.ContentMatch.Pattern) {
-				result.Error = "content string match failed"
-				return result
-			}
+		if err := stream.matchContent(endpoint.Validation.ContentMatch); err != nil {
+			result.Error = err.Error()
+			return result, resp.Header, bodyBytes
+		}
+	}
+
+	if endpoint.Validation.ContentNotMatch.Pattern != "" {
+		if err := stream.matchContentAbsent(endpoint.Validation.ContentNotMatch); err != nil {
+			result.Error = err.Error()
+			return result, resp.Header, bodyBytes
+		}
+	}
+
+	if len(endpoint.Validation.JSONAssertions) > 0 || endpoint.Validation.JSONSchema != "" {
+		if err := stream.whole("json validation"); err != nil {
+			result.Error = err.Error()
+			return result, resp.Header, bodyBytes
+		}
+	}
+	if err := c.validateJSON(endpoint.Validation.JSONAssertions, endpoint.Validation.JSONSchema, bodyBytes); err != nil {
+		result.Error = err.Error()
+		return result, resp.Header, bodyBytes
+	}
+
+	if endpoint.Validation.Script != "" {
+		if err := runScript(ctx, endpoint.Validation, &result, resp, bodyBytes, stream.truncated); err != nil {
+			result.Error = err.Error()
+			return result, resp.Header, bodyBytes
 		}
 	}
 
 	result.Success = true
-	return result
+	return result, resp.Header, bodyBytes
+}
+
+// checkRedirect applies the redirect policy of client, or the default one of
+// net/http, when a wrapping client follows a redirect
+func checkRedirect(client *http.Client, req *http.Request, via []*http.Request) error {
+	if client.CheckRedirect != nil {
+		return client.CheckRedirect(req, via)
+	}
+	if len(via) >= 10 {
+		return fmt.Errorf("stopped after 10 redirects")
+	}
+	return nil
+}
+
+// validateHTTPVersion checks the negotiated protocol against the expected
+// version: "1.0", "1.1" or "2" (h2c is accepted as an alias of 2)
+func validateHTTPVersion(expected string, resp *http.Response) error {
+	var want string
+	switch expected {
+	case "":
+		return nil
+	case "1.0", "1.1":
+		want = "HTTP/" + expected
+	case "2", "h2c":
+		want = "HTTP/2.0"
+	default:
+		return fmt.Errorf("unsupported expected http_version %q", expected)
+	}
+	if resp.Proto != want {
+		return fmt.Errorf("negotiated %s, expected %s", resp.Proto, want)
+	}
+	return nil
 }
@@ -2,8 +2,12 @@ package checker
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
@@ -58,3 +62,248 @@ func TestChecker_Check_SSL(t *testing.T) {
 		t.Error("Expected CertNotBefore to be reasonable")
 	}
 }
+
+func TestChecker_Check_TimingPhases(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(40 * time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("first chunk\n"))
+		w.(http.Flusher).Flush()
+		time.Sleep(30 * time.Millisecond)
+		w.Write([]byte("second chunk\n"))
+	}))
+	defer ts.Close()
+
+	c := newInsecureChecker()
+	endpoint := config.EndpointConfig{ID: "timing", URL: ts.URL, Method: "GET"}
+
+	for i := range 2 {
+		result := c.Check(context.Background(), endpoint)
+		if !result.Success {
+			t.Fatalf("Check %d failed: %s", i, result.Error)
+		}
+		if result.ConnReused || result.ConnDuration == 0 || result.TLSDuration == 0 {
+			t.Errorf("Check %d: expected a fresh connection, got reused=%v conn=%v tls=%v", i, result.ConnReused, result.ConnDuration, result.TLSDuration)
+		}
+		if result.TTFB < 40*time.Millisecond || result.TTFB > result.Duration {
+			t.Errorf("Check %d: expected TTFB to cover the server processing, got %v", i, result.TTFB)
+		}
+		if result.TransferDuration < 30*time.Millisecond {
+			t.Errorf("Check %d: expected transfer to cover the body, got %v", i, result.TransferDuration)
+		}
+		phases := result.ConnDuration + result.TLSDuration + result.WriteDuration + result.TTFB + result.TransferDuration
+		if phases > result.Duration {
+			t.Errorf("Check %d: phases add up to %v, more than the total %v", i, phases, result.Duration)
+		}
+	}
+
+	reuse := false
+	endpoint.FreshConnection = &reuse
+	c.Check(context.Background(), endpoint)
+	result := c.Check(context.Background(), endpoint)
+	if !result.Success {
+		t.Fatalf("Check failed: %s", result.Error)
+	}
+	if !result.ConnReused || result.ConnDuration != 0 || result.TLSDuration != 0 {
+		t.Errorf("Expected a reused connection, got reused=%v conn=%v tls=%v", result.ConnReused, result.ConnDuration, result.TLSDuration)
+	}
+}
+
+func TestChecker_Check_HTTPVersion(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	h2 := httptest.NewUnstartedServer(handler)
+	h2.EnableHTTP2 = true
+	h2.StartTLS()
+	defer h2.Close()
+
+	h1 := httptest.NewTLSServer(handler)
+	defer h1.Close()
+
+	h2c := httptest.NewUnstartedServer(handler)
+	h2c.Config.Protocols = new(http.Protocols)
+	h2c.Config.Protocols.SetHTTP1(true)
+	h2c.Config.Protocols.SetUnencryptedHTTP2(true)
+	h2c.Start()
+	defer h2c.Close()
+
+	tests := []struct {
+		name         string
+		url          string
+		version      string
+		expect       string
+		wantProtocol string
+		wantErr      string
+	}{
+		{name: "auto negotiates h2", url: h2.URL, wantProtocol: "HTTP/2.0"},
+		{name: "auto falls back to 1.1", url: h1.URL, wantProtocol: "HTTP/1.1"},
+		{name: "force 1.1", url: h2.URL, version: "1.1", wantProtocol: "HTTP/1.1"},
+		{name: "force 2", url: h2.URL, version: "2", expect: "2", wantProtocol: "HTTP/2.0"},
+		{name: "force 2 unsupported", url: h1.URL, version: "2", wantErr: "no application protocol"},
+		{name: "h2c", url: h2c.URL, version: "h2c", expect: "2", wantProtocol: "HTTP/2.0"},
+		{name: "plain http is 1.1", url: h2c.URL, wantProtocol: "HTTP/1.1"},
+		{name: "expect 2 but got 1.1", url: h1.URL, expect: "2", wantErr: "negotiated HTTP/1.1, expected HTTP/2.0"},
+		{name: "invalid version", url: h1.URL, version: "3", wantErr: `unsupported http_version "3"`},
+	}
+
+	c := newInsecureChecker()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := c.Check(context.Background(), config.EndpointConfig{
+				ID: "proto", URL: tt.url, Method: "GET", HTTPVersion: tt.version,
+				Validation: config.ValidationConfig{HTTPVersion: tt.expect},
+			})
+			if tt.wantErr != "" {
+				if result.Success || !strings.Contains(result.Error, tt.wantErr) {
+					t.Errorf("Expected error containing %q, got success=%v error=%q", tt.wantErr, result.Success, result.Error)
+				}
+				return
+			}
+			if !result.Success {
+				t.Fatalf("Expected success, got failure: %s", result.Error)
+			}
+			if result.Protocol != tt.wantProtocol {
+				t.Errorf("Expected protocol %s, got %s", tt.wantProtocol, result.Protocol)
+			}
+		})
+	}
+}
+
+func TestChecker_Check_FailureSnapshot(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Set-Cookie", "session=secret")
+		w.Header().Set("X-Internal-Token", "secret")
+		w.Header().Set("X-Request-Id", "abc123")
+		w.WriteHeader(http.StatusBadGateway)
+		w.Write([]byte(strings.Repeat("upstream error ", 100)))
+	}))
+	defer ts.Close()
+
+	c := NewChecker()
+	result := c.Check(context.Background(), config.EndpointConfig{
+		ID: "failing", URL: ts.URL, Method: "GET",
+		Snapshot: config.SnapshotConfig{MaxBody: 32, RedactHeaders: []string{"x-internal-token"}},
+	})
+	if result.Success {
+		t.Fatal("Expected the check to fail")
+	}
+	s := result.Snapshot
+	if s == nil {
+		t.Fatal("Expected a snapshot of the failed response")
+	}
+	if s.RemoteIP != "127.0.0.1" {
+		t.Errorf("Expected remote IP 127.0.0.1, got %q", s.RemoteIP)
+	}
+	if len(s.Body) != 32 || !s.Truncated || !strings.HasPrefix(s.Body, "upstream error") {
+		t.Errorf("Expected the first 32 bytes of the body, truncated, got %q truncated=%v", s.Body, s.Truncated)
+	}
+	for name, want := range map[string]string{"Set-Cookie": redactedValue, "X-Internal-Token": redactedValue, "X-Request-Id": "abc123"} {
+		if got := s.Headers[name]; len(got) != 1 || got[0] != want {
+			t.Errorf("Expected header %s to be %q, got %v", name, want, got)
+		}
+	}
+
+	// A body read for validation is snapshotted as well
+	result = c.Check(context.Background(), config.EndpointConfig{
+		ID: "failing", URL: ts.URL, Method: "GET",
+		Validation: config.ValidationConfig{StatusCodes: []int{http.StatusBadGateway}, ContentMatch: config.ContentMatch{Pattern: "healthy"}},
+	})
+	if result.Success || result.Snapshot == nil || len(result.Snapshot.Body) != 1500 || result.Snapshot.Truncated {
+		t.Errorf("Expected the whole body in the snapshot, got %+v", result.Snapshot)
+	}
+
+	ok := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ok.Close()
+	if result := c.Check(context.Background(), config.EndpointConfig{ID: "ok", URL: ok.URL, Method: "GET"}); !result.Success || result.Snapshot != nil {
+		t.Errorf("Expected no snapshot for a successful check, got %+v", result.Snapshot)
+	}
+}
+
+func TestChecker_Check_MaxBodyBytes(t *testing.T) {
+	// 4 MiB of filler with a marker at the very end
+	chunk := strings.Repeat("x", 64<<10)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for range 64 {
+			io.WriteString(w, chunk)
+		}
+		io.WriteString(w, "END-OF-FILE")
+	}))
+	defer ts.Close()
+
+	h := sha256.New()
+	for range 64 {
+		io.WriteString(h, chunk)
+	}
+	io.WriteString(h, "END-OF-FILE")
+	sum := hex.EncodeToString(h.Sum(nil))
+	total := int64(64*len(chunk) + len("END-OF-FILE"))
+
+	tests := []struct {
+		name       string
+		validation config.ValidationConfig
+		wantErr    string
+		wantBytes  int64
+	}{
+		{name: "checksum", validation: config.ValidationConfig{Checksum: config.ChecksumConfig{Value: sum}}, wantBytes: total},
+		{name: "substring", validation: config.ValidationConfig{ContentMatch: config.ContentMatch{Pattern: "xEND-OF-FILE"}}, wantBytes: total},
+		{name: "regex", validation: config.ValidationConfig{ContentMatch: config.ContentMatch{Type: "regex", Pattern: `x+END-OF-FILE$`}}, wantBytes: total},
+		{name: "regex not found", validation: config.ValidationConfig{ContentMatch: config.ContentMatch{Type: "regex", Pattern: `y{3}`}},
+			wantErr: "content regex match failed"},
+		{name: "negative substring", validation: config.ValidationConfig{ContentNotMatch: config.ContentMatch{Pattern: "END-OF"}},
+			wantErr: "negative content match failed"},
+		{name: "json needs the whole body", validation: config.ValidationConfig{JSONAssertions: []string{"$.status == \"ok\""}},
+			wantErr: "truncated at max_body_bytes 65536"},
+		{name: "xpath needs the whole body", validation: config.ValidationConfig{ContentMatch: config.ContentMatch{Type: "xpath", Pattern: "//x"}},
+			wantErr: "truncated at max_body_bytes"},
+	}
+
+	c := NewChecker()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := c.Check(context.Background(), config.EndpointConfig{
+				ID: "download", URL: ts.URL, Method: "GET", MaxBodyBytes: 64 << 10, Validation: tt.validation,
+			})
+			if !result.BodyTruncated {
+				t.Error("Expected the body to be reported as truncated")
+			}
+			if tt.wantErr != "" {
+				if result.Success || !strings.Contains(result.Error, tt.wantErr) {
+					t.Errorf("Expected error containing %q, got success=%v error=%q", tt.wantErr, result.Success, result.Error)
+				}
+				return
+			}
+			if !result.Success {
+				t.Fatalf("Expected success, got failure: %s", result.Error)
+			}
+			if result.BytesReceived != tt.wantBytes {
+				t.Errorf("Expected %d bytes streamed, got %d", tt.wantBytes, result.BytesReceived)
+			}
+		})
+	}
+
+	// Without a streaming validator reading stops past the limit
+	result := c.Check(context.Background(), config.EndpointConfig{ID: "download", URL: ts.URL, Method: "GET", MaxBodyBytes: 64 << 10})
+	if !result.Success || !result.BodyTruncated || result.BytesReceived >= total {
+		t.Errorf("Expected a truncated read, got success=%v truncated=%v bytes=%d", result.Success, result.BodyTruncated, result.BytesReceived)
+	}
+}
+
+func TestSubstringMatcher_SplitWrites(t *testing.T) {
+	m := &substringMatcher{pattern: []byte("needle")}
+	for _, p := range []string{"hay ne", "e", "dle hay"} {
+		m.Write([]byte(p))
+	}
+	if !m.Matched() {
+		t.Error("Expected a pattern split across writes to be found")
+	}
+
+	m = &substringMatcher{pattern: []byte("needle")}
+	for _, p := range []string{"need", "l", "xle"} {
+		m.Write([]byte(p))
+	}
+	if m.Matched() {
+		t.Error("Expected no match")
+	}
+}
@@ -0,0 +1,115 @@
+package checker
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net/textproto"
+	"strings"
+	"time"
+
+	"github.com/manu/octo/pkg/config"
+)
+
+// checkIMAP reads the greeting, optionally upgrades with STARTTLS and, when
+// credentials are configured, probes a LOGIN
+func (c *Checker) checkIMAP(ctx context.Context, endpoint config.EndpointConfig) Result {
+	result := newResult(endpoint)
+
+	t, err := resolveServiceTarget(endpoint, "143", "993")
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	start := time.Now()
+	conn, err := c.dialService(ctx, &result, t)
+	if err != nil {
+		result.Duration = time.Since(start)
+		result.Error = "connection failed: " + err.Error()
+		return result
+	}
+	defer conn.Close()
+
+	fail := func(format string, args ...any) Result {
+		result.Duration = time.Since(start)
+		result.Error = fmt.Sprintf(format, args...)
+		return result
+	}
+
+	tp := textproto.NewConn(conn)
+	greeting, err := tp.ReadLine()
+	result.TTFB = time.Since(start)
+	if err != nil {
+		return fail("failed to read greeting: %v", err)
+	}
+	if !strings.HasPrefix(greeting, "* OK") && !strings.HasPrefix(greeting, "* PREAUTH") {
+		return fail("unexpected greeting %q", greeting)
+	}
+	setBanner(&result, greeting)
+
+	if endpoint.Service.StartTLS && !t.useTLS {
+		if _, err := imapCmd(tp, "a1", "STARTTLS"); err != nil {
+			return fail("STARTTLS failed: %v", err)
+		}
+		tlsConn, err := c.handshake(ctx, &result, conn, t.host)
+		if err != nil {
+			return fail("%v", err)
+		}
+		conn = tlsConn
+		tp = textproto.NewConn(conn)
+	}
+
+	if tlsConn, ok := conn.(*tls.Conn); ok {
+		state := tlsConn.ConnectionState()
+		if err := c.inspectTLS(ctx, &result, endpoint.SSL, t.host, &state); err != nil {
+			return fail("%v", err)
+		}
+	}
+
+	if t.username != "" {
+		if _, err := imapCmd(tp, "a2", "LOGIN "+imapQuote(t.username)+" "+imapQuote(t.password)); err != nil {
+			return fail("login failed: %v", err)
+		}
+	}
+
+	imapCmd(tp, "a3", "LOGOUT")
+	result.Duration = time.Since(start)
+
+	if t.expect != nil && !t.expect.MatchString(result.Banner) {
+		result.Error = fmt.Sprintf("banner does not match %q", t.expect.String())
+		return result
+	}
+
+	result.Success = true
+	return result
+}
+
+// imapCmd sends a tagged command and reads untagged responses until the
+// tagged completion, which must be OK
+func imapCmd(tp *textproto.Conn, tag, command string) (string, error) {
+	if err := tp.PrintfLine("%s %s", tag, command); err != nil {
+		return "", err
+	}
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return "", err
+		}
+		status, ok := strings.CutPrefix(line, tag+" ")
+		if !ok {
+			continue
+		}
+		if !strings.HasPrefix(status, "OK") {
+			return "", fmt.Errorf("%s", status)
+		}
+		return status, nil
+	}
+}
+
+// imapQuote renders s as an IMAP quoted string
+func imapQuote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	return `"` + s + `"`
+}
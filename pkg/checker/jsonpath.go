@@ -0,0 +1,257 @@
+package checker
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// jsonAssertion is a parsed assertion such as `$.items.length > 0`
+type jsonAssertion struct {
+	path  string
+	op    string
+	value string // Raw right-hand side: a JSON literal, or a regex for "matches"
+}
+
+var jsonAssertionOps = []string{"==", "!=", ">=", "<=", ">", "<", "matches", "exists"}
+
+// parseJSONAssertion splits an assertion into path, operator and value.
+// The path ends at the first whitespace outside of brackets and quotes.
+func parseJSONAssertion(expr string) (jsonAssertion, error) {
+	expr = strings.TrimSpace(expr)
+	if !strings.HasPrefix(expr, "$") {
+		return jsonAssertion{}, fmt.Errorf("invalid json assertion %q: path must start with $", expr)
+	}
+
+	depth := 0
+	var quote rune
+	end := len(expr)
+	for i, r := range expr {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '\'' || r == '"':
+			quote = r
+		case r == '[':
+			depth++
+		case r == ']':
+			depth--
+		case unicode.IsSpace(r) && depth == 0:
+			end = i
+		}
+		if end != len(expr) {
+			break
+		}
+	}
+
+	a := jsonAssertion{path: expr[:end]}
+	rest := strings.TrimSpace(expr[end:])
+	if rest == "" {
+		// A bare path asserts existence
+		a.op = "exists"
+		return a, nil
+	}
+
+	for _, op := range jsonAssertionOps {
+		if strings.HasPrefix(rest, op) {
+			a.op = op
+			a.value = strings.TrimSpace(rest[len(op):])
+			break
+		}
+	}
+	if a.op == "" {
+		return jsonAssertion{}, fmt.Errorf("invalid json assertion %q: unknown operator", expr)
+	}
+	if a.op != "exists" && a.value == "" {
+		return jsonAssertion{}, fmt.Errorf("invalid json assertion %q: missing value", expr)
+	}
+	return a, nil
+}
+
+// evaluate runs the assertion against a decoded JSON document
+func (a jsonAssertion) evaluate(doc any) error {
+	got, err := lookupJSONPath(doc, a.path)
+	if err != nil {
+		return fmt.Errorf("json assertion failed at %s: %w", a.path, err)
+	}
+
+	switch a.op {
+	case "exists":
+		return nil
+	case "matches":
+		re, err := regexp.Compile(a.value)
+		if err != nil {
+			return fmt.Errorf("json assertion at %s has invalid regex: %w", a.path, err)
+		}
+		s := jsonString(got)
+		if !re.MatchString(s) {
+			return fmt.Errorf("json assertion failed at %s: %q does not match %s", a.path, s, a.value)
+		}
+		return nil
+	}
+
+	var want any
+	if err := json.Unmarshal([]byte(a.value), &want); err != nil {
+		// Allow unquoted strings for convenience, e.g. `$.status == ok`
+		want = a.value
+	}
+
+	ok, err := compareJSON(got, a.op, want)
+	if err != nil {
+		return fmt.Errorf("json assertion failed at %s: %w", a.path, err)
+	}
+	if !ok {
+		return fmt.Errorf("json assertion failed at %s: expected %s %s, got %s", a.path, a.op, a.value, jsonLiteral(got))
+	}
+	return nil
+}
+
+func compareJSON(got any, op string, want any) (bool, error) {
+	switch op {
+	case "==":
+		return jsonEqual(got, want), nil
+	case "!=":
+		return !jsonEqual(got, want), nil
+	}
+
+	g, gok := got.(float64)
+	w, wok := want.(float64)
+	if !gok || !wok {
+		return false, fmt.Errorf("operator %s requires numbers, got %s", op, jsonLiteral(got))
+	}
+	switch op {
+	case ">":
+		return g > w, nil
+	case ">=":
+		return g >= w, nil
+	case "<":
+		return g < w, nil
+	case "<=":
+		return g <= w, nil
+	}
+	return false, fmt.Errorf("unknown operator %s", op)
+}
+
+func jsonEqual(a, b any) bool {
+	return jsonLiteral(a) == jsonLiteral(b)
+}
+
+func jsonLiteral(v any) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
+}
+
+// jsonString renders scalars without JSON quoting, for regex matching
+func jsonString(v any) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	return jsonLiteral(v)
+}
+
+// lookupJSONPath resolves a simple JSONPath against a decoded document.
+// Supported syntax: $, .key, ['key'], [index] (negative counts from the end),
+// and the .length pseudo-property on arrays, objects and strings.
+func lookupJSONPath(doc any, path string) (any, error) {
+	if !strings.HasPrefix(path, "$") {
+		return nil, fmt.Errorf("path must start with $")
+	}
+
+	current := doc
+	rest := path[1:]
+	walked := "$"
+	for rest != "" {
+		var key string
+		var index int
+		isIndex := false
+
+		switch rest[0] {
+		case '.':
+			rest = rest[1:]
+			end := strings.IndexAny(rest, ".[")
+			if end == -1 {
+				end = len(rest)
+			}
+			key = rest[:end]
+			rest = rest[end:]
+			if key == "" {
+				return nil, fmt.Errorf("empty key after %s", walked)
+			}
+		case '[':
+			end := strings.IndexByte(rest, ']')
+			if end == -1 {
+				return nil, fmt.Errorf("unterminated bracket after %s", walked)
+			}
+			inner := strings.TrimSpace(rest[1:end])
+			rest = rest[end+1:]
+			if len(inner) >= 2 && (inner[0] == '\'' || inner[0] == '"') && inner[len(inner)-1] == inner[0] {
+				key = inner[1 : len(inner)-1]
+			} else {
+				n, err := strconv.Atoi(inner)
+				if err != nil {
+					return nil, fmt.Errorf("invalid index [%s] after %s", inner, walked)
+				}
+				index = n
+				isIndex = true
+			}
+		default:
+			return nil, fmt.Errorf("unexpected %q after %s", rest[0], walked)
+		}
+
+		if isIndex {
+			arr, ok := current.([]any)
+			if !ok {
+				return nil, fmt.Errorf("%s is not an array", walked)
+			}
+			i := index
+			if i < 0 {
+				i += len(arr)
+			}
+			if i < 0 || i >= len(arr) {
+				return nil, fmt.Errorf("index %d out of range at %s (length %d)", index, walked, len(arr))
+			}
+			current = arr[i]
+			walked += fmt.Sprintf("[%d]", index)
+			continue
+		}
+
+		if obj, ok := current.(map[string]any); ok {
+			if v, found := obj[key]; found {
+				current = v
+				walked += "." + key
+				continue
+			}
+			if key == "length" {
+				current = float64(len(obj))
+				walked += ".length"
+				continue
+			}
+			return nil, fmt.Errorf("path %s.%s not found", walked, key)
+		}
+
+		if key == "length" {
+			switch v := current.(type) {
+			case []any:
+				current = float64(len(v))
+			case string:
+				current = float64(len([]rune(v)))
+			default:
+				return nil, fmt.Errorf("%s has no length", walked)
+			}
+			walked += ".length"
+			continue
+		}
+
+		return nil, fmt.Errorf("path %s.%s not found: %s is not an object", walked, key, walked)
+	}
+
+	return current, nil
+}
@@ -0,0 +1,165 @@
+package checker
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/manu/octo/pkg/config"
+)
+
+const testJSONBody = `{"status":"ok","version":"2.4.1","items":[{"id":1},{"id":2}],"meta":{"total":2,"odd key":true}}`
+
+func TestJSONAssertions(t *testing.T) {
+	tests := []struct {
+		expr    string
+		wantErr string
+	}{
+		{expr: `$.status == "ok"`},
+		{expr: `$.status == ok`},
+		{expr: `$.items.length > 0`},
+		{expr: `$.items[1].id == 2`},
+		{expr: `$.items[-1].id >= 2`},
+		{expr: `$.version matches ^2\.`},
+		{expr: `$.meta['odd key'] == true`},
+		{expr: `$.meta.total exists`},
+		{expr: `$.meta.total`},
+		{expr: `$.status == "degraded"`, wantErr: `failed at $.status: expected == "degraded", got "ok"`},
+		{expr: `$.items.length > 5`, wantErr: `failed at $.items.length: expected > 5, got 2`},
+		{expr: `$.version matches ^3\.`, wantErr: `failed at $.version: "2.4.1" does not match`},
+		{expr: `$.items[5].id == 1`, wantErr: `failed at $.items[5].id: index 5 out of range`},
+		{expr: `$.missing.field exists`, wantErr: `failed at $.missing.field: path $.missing not found`},
+		{expr: `$.status > 1`, wantErr: `requires numbers`},
+		{expr: `status == "ok"`, wantErr: `path must start with $`},
+		{expr: `$.status ~= "ok"`, wantErr: `unknown operator`},
+	}
+
+	c := NewChecker()
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			err := c.validateJSON([]string{tt.expr}, "", []byte(testJSONBody))
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Expected assertion to pass, got: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("Expected error containing %q, got nil", tt.wantErr)
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got %q", tt.wantErr, err.Error())
+			}
+		})
+	}
+}
+
+func TestChecker_Check_JSONSchema(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(testJSONBody))
+	}))
+	defer ts.Close()
+
+	dir := t.TempDir()
+	t.Setenv("OCTO_SCHEMAS_DIR", dir)
+	writeSchema := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("Failed to write schema: %v", err)
+		}
+		return path
+	}
+
+	valid := writeSchema("valid.json", `{
+		"type": "object",
+		"required": ["status", "items"],
+		"properties": {"items": {"type": "array", "items": {"required": ["id"]}}}
+	}`)
+	invalid := writeSchema("invalid.json", `{
+		"type": "object",
+		"properties": {"items": {"type": "array", "items": {"properties": {"id": {"type": "string"}}}}}
+	}`)
+
+	c := NewChecker()
+
+	result := c.Check(context.Background(), config.EndpointConfig{
+		ID:         "schema-valid",
+		URL:        ts.URL,
+		Method:     "GET",
+		Validation: config.ValidationConfig{JSONSchema: valid},
+	})
+	if !result.Success {
+		t.Errorf("Expected success, got failure: %s", result.Error)
+	}
+
+	result = c.Check(context.Background(), config.EndpointConfig{
+		ID:         "schema-invalid",
+		URL:        ts.URL,
+		Method:     "GET",
+		Validation: config.ValidationConfig{JSONSchema: invalid},
+	})
+	if result.Success {
+		t.Fatal("Expected schema validation failure")
+	}
+	if !strings.Contains(result.Error, "failed at $.items[0].id") {
+		t.Errorf("Expected error to name the failing path, got %q", result.Error)
+	}
+
+	// An edited schema is compiled again
+	writeSchema("invalid.json", `{"type": "object"}`)
+	future := time.Now().Add(time.Minute)
+	os.Chtimes(invalid, future, future)
+	result = c.Check(context.Background(), config.EndpointConfig{
+		ID:         "schema-invalid",
+		URL:        ts.URL,
+		Method:     "GET",
+		Validation: config.ValidationConfig{JSONSchema: invalid},
+	})
+	if !result.Success {
+		t.Errorf("Expected the edited schema to be used, got failure: %s", result.Error)
+	}
+}
+
+func TestChecker_Check_JSONSchemaOutsideDir(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(testJSONBody))
+	}))
+	defer ts.Close()
+
+	dir, outside := t.TempDir(), t.TempDir()
+	t.Setenv("OCTO_SCHEMAS_DIR", dir)
+	secret := filepath.Join(outside, "secret.json")
+	os.WriteFile(secret, []byte(`{"type": "object"}`), 0o644)
+	os.Symlink(secret, filepath.Join(dir, "link.json"))
+	os.WriteFile(filepath.Join(dir, "ref.json"), []byte(`{"$ref": "file://`+filepath.ToSlash(secret)+`"}`), 0o644)
+
+	tests := []struct {
+		name   string
+		schema string
+	}{
+		{name: "absolute", schema: secret},
+		{name: "relative", schema: "../" + filepath.Base(outside) + "/secret.json"},
+		{name: "missing", schema: filepath.Join(outside, "missing.json")},
+		{name: "symlink", schema: "link.json"},
+		{name: "reference", schema: "ref.json"},
+	}
+
+	c := NewChecker()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := c.Check(context.Background(), config.EndpointConfig{
+				ID: "schema", URL: ts.URL, Method: "GET",
+				Validation: config.ValidationConfig{JSONSchema: tt.schema},
+			})
+			if result.Success || !strings.Contains(result.Error, "outside the schemas directory") {
+				t.Errorf("Expected the schema to be refused, got success=%v error=%q", result.Success, result.Error)
+			}
+		})
+	}
+}
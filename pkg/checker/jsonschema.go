@@ -0,0 +1,150 @@
+package checker
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+)
+
+// JSON Schema files are read from the schemas directory only, references
+// between schemas included, so a config author cannot read other files of
+// the host through compiler errors
+const (
+	schemasDirEnv     = "OCTO_SCHEMAS_DIR"
+	defaultSchemasDir = "/etc/octo/schemas"
+)
+
+// schemaCache compiles each JSON Schema file once and reuses it across
+// checks, until the file is modified. It holds at most one entry per file
+// of the schemas directory.
+type schemaCache struct {
+	mu      sync.Mutex
+	schemas map[string]cachedSchema
+}
+
+// cachedSchema is a compiled schema and the version of the file it came from
+type cachedSchema struct {
+	schema  *jsonschema.Schema
+	modTime time.Time
+	size    int64
+}
+
+// get returns the compiled schema of a file, relative to the schemas
+// directory unless absolute
+func (sc *schemaCache) get(name string) (*jsonschema.Schema, error) {
+	path, err := pathUnder(schemasDirEnv, defaultSchemasDir, "schemas", name)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	if cached, ok := sc.schemas[path]; ok && cached.modTime.Equal(info.ModTime()) && cached.size == info.Size() {
+		return cached.schema, nil
+	}
+
+	compiler := jsonschema.NewCompiler()
+	compiler.UseLoader(schemaLoader{})
+	sch, err := compiler.Compile(path)
+	if err != nil {
+		return nil, err
+	}
+	if sc.schemas == nil {
+		sc.schemas = make(map[string]cachedSchema)
+	}
+	sc.schemas[path] = cachedSchema{schema: sch, modTime: info.ModTime(), size: info.Size()}
+	return sch, nil
+}
+
+// schemaLoader loads the files referenced by a schema from the schemas
+// directory. Other URLs are refused.
+type schemaLoader struct{}
+
+func (schemaLoader) Load(url string) (any, error) {
+	name, err := jsonschema.FileLoader{}.ToFile(url)
+	if err != nil {
+		return nil, err
+	}
+	path, err := pathUnder(schemasDirEnv, defaultSchemasDir, "schemas", name)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return jsonschema.UnmarshalJSON(f)
+}
+
+// validateJSON evaluates the JSON assertions and schema of an endpoint
+// against the response body
+func (c *Checker) validateJSON(assertions []string, schemaPath string, body []byte) error {
+	if len(assertions) > 0 {
+		var doc any
+		if err := json.Unmarshal(body, &doc); err != nil {
+			return fmt.Errorf("response is not valid JSON: %w", err)
+		}
+		for _, expr := range assertions {
+			a, err := parseJSONAssertion(expr)
+			if err != nil {
+				return err
+			}
+			if err := a.evaluate(doc); err != nil {
+				return err
+			}
+		}
+	}
+
+	if schemaPath != "" {
+		sch, err := c.schemas.get(schemaPath)
+		if err != nil {
+			return fmt.Errorf("failed to load json schema: %w", err)
+		}
+		inst, err := jsonschema.UnmarshalJSON(bytes.NewReader(body))
+		if err != nil {
+			return fmt.Errorf("response is not valid JSON: %w", err)
+		}
+		if err := sch.Validate(inst); err != nil {
+			var verr *jsonschema.ValidationError
+			if errors.As(err, &verr) {
+				return schemaError(verr)
+			}
+			return fmt.Errorf("json schema validation failed: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// schemaError reports the first leaf cause, which names the exact failing
+// location, rendered with the same path syntax as JSON assertions
+func schemaError(verr *jsonschema.ValidationError) error {
+	for len(verr.Causes) > 0 {
+		verr = verr.Causes[0]
+	}
+	location := "$"
+	for _, token := range verr.InstanceLocation {
+		if _, err := strconv.Atoi(token); err == nil {
+			location += "[" + token + "]"
+		} else {
+			location += "." + token
+		}
+	}
+	msg := verr.ErrorKind.LocalizedString(message.NewPrinter(language.English))
+	return fmt.Errorf("json schema validation failed at %s: %s", location, msg)
+}
@@ -0,0 +1,129 @@
+package checker
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"net/http"
+
+	"golang.org/x/crypto/ocsp"
+)
+
+// Revocation statuses reported in Result.OCSPStatus
+const (
+	OCSPStatusGood    = "good"
+	OCSPStatusRevoked = "revoked"
+	OCSPStatusUnknown = "unknown"
+)
+
+// maxOCSPResponseSize bounds the responder reply we are willing to read
+const maxOCSPResponseSize = 64 * 1024
+
+// checkOCSP determines the revocation status of the leaf certificate. A stapled
+// response is always preferred; the responder from the certificate's AIA
+// extension is only queried when queryResponder is set and nothing was stapled.
+// An empty status means no revocation information was available.
+func (c *Checker) checkOCSP(ctx context.Context, state *tls.ConnectionState, queryResponder bool) (status string, stapled bool, err error) {
+	if len(state.PeerCertificates) == 0 {
+		return "", false, nil
+	}
+	leaf := state.PeerCertificates[0]
+	issuer := ocspIssuer(state)
+
+	if len(state.OCSPResponse) > 0 {
+		if issuer == nil {
+			return "", true, fmt.Errorf("cannot verify stapled OCSP response: issuer certificate not available")
+		}
+		resp, err := ocsp.ParseResponseForCert(state.OCSPResponse, leaf, issuer)
+		if err != nil {
+			return "", true, fmt.Errorf("invalid stapled OCSP response: %w", err)
+		}
+		return ocspStatusName(resp.Status), true, nil
+	}
+
+	if !queryResponder || len(leaf.OCSPServer) == 0 {
+		return "", false, nil
+	}
+	if issuer == nil {
+		return "", false, fmt.Errorf("cannot query OCSP responder: issuer certificate not available")
+	}
+
+	resp, err := c.queryOCSPResponder(ctx, leaf.OCSPServer[0], leaf, issuer)
+	if err != nil {
+		return "", false, err
+	}
+	return ocspStatusName(resp.Status), false, nil
+}
+
+// ocspIssuer returns the certificate that signs the responses about the
+// leaf: its issuer in the verified chain, or the certificate presented after
+// it when the chain was not verified. Without an issuer a response cannot be
+// verified, so it must never be parsed with a nil one.
+func ocspIssuer(state *tls.ConnectionState) *x509.Certificate {
+	if len(state.VerifiedChains) > 0 && len(state.VerifiedChains[0]) > 1 {
+		return state.VerifiedChains[0][1]
+	}
+	if len(state.VerifiedChains) == 0 && len(state.PeerCertificates) > 1 {
+		return state.PeerCertificates[1]
+	}
+	return nil
+}
+
+// queryOCSPResponder asks the responder of the leaf for its status. The
+// request goes through the dialer and proxy of the endpoint being checked,
+// on connections of its own when the dialer resolves addresses differently.
+func (c *Checker) queryOCSPResponder(ctx context.Context, server string, leaf, issuer *x509.Certificate) (*ocsp.Response, error) {
+	reqBytes, err := ocsp.CreateRequest(leaf, issuer, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OCSP request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, server, bytes.NewReader(reqBytes))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/ocsp-request")
+	req.Header.Set("Accept", "application/ocsp-response")
+
+	client := c.client
+	if transport, ok := c.client.Transport.(*http.Transport); ok && dialerFrom(ctx).custom() {
+		fresh := cloneTransport(transport, nil)
+		defer fresh.CloseIdleConnections()
+		client = c.withTransport(fresh)
+	}
+
+	httpResp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("OCSP responder request failed: %w", err)
+	}
+	defer httpResp.Body.Close()
+
+	if httpResp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("OCSP responder returned status %d", httpResp.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(httpResp.Body, maxOCSPResponseSize))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read OCSP response: %w", err)
+	}
+
+	resp, err := ocsp.ParseResponseForCert(body, leaf, issuer)
+	if err != nil {
+		return nil, fmt.Errorf("invalid OCSP response: %w", err)
+	}
+	return resp, nil
+}
+
+func ocspStatusName(status int) string {
+	switch status {
+	case ocsp.Good:
+		return OCSPStatusGood
+	case ocsp.Revoked:
+		return OCSPStatusRevoked
+	default:
+		return OCSPStatusUnknown
+	}
+}
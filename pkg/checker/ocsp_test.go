@@ -0,0 +1,239 @@
+package checker
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ocsp"
+
+	"github.com/manu/octo/pkg/config"
+)
+
+// testPKI is a throwaway CA with a single leaf certificate for 127.0.0.1
+type testPKI struct {
+	caCert  *x509.Certificate
+	caKey   crypto.Signer
+	leaf    *x509.Certificate
+	leafKey crypto.Signer
+}
+
+func newTestPKI(t *testing.T, ocspServer string) *testPKI {
+	t.Helper()
+
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate CA key: %v", err)
+	}
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Octo Test CA", Organization: []string{"Octo"}},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatalf("Failed to create CA certificate: %v", err)
+	}
+	caCert, _ := x509.ParseCertificate(caDER)
+
+	leafKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate leaf key: %v", err)
+	}
+	leafTemplate := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(12 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	if ocspServer != "" {
+		leafTemplate.OCSPServer = []string{ocspServer}
+	}
+	leafDER, err := x509.CreateCertificate(rand.Reader, leafTemplate, caCert, &leafKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatalf("Failed to create leaf certificate: %v", err)
+	}
+	leaf, _ := x509.ParseCertificate(leafDER)
+
+	return &testPKI{caCert: caCert, caKey: caKey, leaf: leaf, leafKey: leafKey}
+}
+
+func (p *testPKI) ocspResponse(t *testing.T, status int) []byte {
+	t.Helper()
+	template := ocsp.Response{
+		Status:       status,
+		SerialNumber: p.leaf.SerialNumber,
+		ThisUpdate:   time.Now().Add(-time.Minute),
+		NextUpdate:   time.Now().Add(time.Hour),
+	}
+	if status == ocsp.Revoked {
+		template.RevokedAt = time.Now().Add(-time.Minute)
+		template.RevocationReason = ocsp.KeyCompromise
+	}
+	resp, err := ocsp.CreateResponse(p.caCert, p.caCert, template, p.caKey)
+	if err != nil {
+		t.Fatalf("Failed to create OCSP response: %v", err)
+	}
+	return resp
+}
+
+// startServer starts a TLS server presenting the leaf and CA, optionally stapling an OCSP response
+func (p *testPKI) startServer(t *testing.T, staple []byte) *httptest.Server {
+	t.Helper()
+	return p.startServerWithChain(t, staple, p.leaf.Raw, p.caCert.Raw)
+}
+
+// startServerWithChain starts a TLS server presenting the given certificates
+func (p *testPKI) startServerWithChain(t *testing.T, staple []byte, chain ...[]byte) *httptest.Server {
+	t.Helper()
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	ts.TLS = &tls.Config{
+		Certificates: []tls.Certificate{{
+			Certificate: chain,
+			PrivateKey:  p.leafKey,
+			OCSPStaple:  staple,
+		}},
+	}
+	ts.StartTLS()
+	return ts
+}
+
+func TestChecker_Check_OCSPStapled(t *testing.T) {
+	pki := newTestPKI(t, "")
+	ts := pki.startServer(t, pki.ocspResponse(t, ocsp.Good))
+	defer ts.Close()
+
+	result := newInsecureChecker().Check(context.Background(), config.EndpointConfig{
+		ID:     "ocsp-stapled",
+		URL:    ts.URL,
+		Method: "GET",
+	})
+
+	if !result.Success {
+		t.Fatalf("Expected success, got failure: %s", result.Error)
+	}
+	if result.OCSPStatus != OCSPStatusGood || !result.OCSPStapled {
+		t.Errorf("Expected stapled good status, got %q (stapled=%v)", result.OCSPStatus, result.OCSPStapled)
+	}
+}
+
+func TestChecker_Check_OCSPStapledLeafOnly(t *testing.T) {
+	pki := newTestPKI(t, "")
+	other := newTestPKI(t, "")
+	ts := pki.startServerWithChain(t, other.ocspResponse(t, ocsp.Good), pki.leaf.Raw)
+	defer ts.Close()
+
+	// Without an issuer a forged staple cannot be told apart
+	result := newInsecureChecker().Check(context.Background(), config.EndpointConfig{ID: "ocsp-leaf", URL: ts.URL, Method: "GET"})
+	if result.OCSPStatus != OCSPStatusUnknown || !strings.Contains(result.OCSPError, "issuer certificate not available") {
+		t.Errorf("Expected unknown status without an issuer, got %q (error: %s)", result.OCSPStatus, result.OCSPError)
+	}
+
+	// The issuer comes from the verified chain, which rejects the forged staple
+	result = newTrustingChecker(pki.caCert).Check(context.Background(), config.EndpointConfig{ID: "ocsp-leaf", URL: ts.URL, Method: "GET"})
+	if result.OCSPStatus != OCSPStatusUnknown || !strings.Contains(result.OCSPError, "invalid stapled OCSP response") {
+		t.Errorf("Expected the forged staple to be rejected, got %q (error: %s)", result.OCSPStatus, result.OCSPError)
+	}
+}
+
+func TestChecker_Check_OCSPResponder(t *testing.T) {
+	var respStatus int
+	var pki *testPKI
+	responder := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if _, err := ocsp.ParseRequest(body); err != nil {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/ocsp-response")
+		w.Write(pki.ocspResponse(t, respStatus))
+	}))
+	defer responder.Close()
+
+	pki = newTestPKI(t, responder.URL)
+	ts := pki.startServer(t, nil)
+	defer ts.Close()
+
+	tests := []struct {
+		name        string
+		status      int
+		wantStatus  string
+		wantSuccess bool
+	}{
+		{name: "good", status: ocsp.Good, wantStatus: OCSPStatusGood, wantSuccess: true},
+		{name: "revoked", status: ocsp.Revoked, wantStatus: OCSPStatusRevoked, wantSuccess: false},
+		{name: "unknown", status: ocsp.Unknown, wantStatus: OCSPStatusUnknown, wantSuccess: true},
+	}
+
+	c := newInsecureChecker()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			respStatus = tt.status
+			result := c.Check(context.Background(), config.EndpointConfig{
+				ID:     "ocsp-responder",
+				URL:    ts.URL,
+				Method: "GET",
+				SSL:    config.SSLConfig{OCSPCheck: true},
+			})
+
+			if result.OCSPStatus != tt.wantStatus {
+				t.Errorf("Expected OCSP status %q, got %q (error: %s)", tt.wantStatus, result.OCSPStatus, result.OCSPError)
+			}
+			if result.OCSPStapled {
+				t.Error("Expected response to come from the responder, not a staple")
+			}
+			if result.Success != tt.wantSuccess {
+				t.Errorf("Expected success=%v, got %v (%s)", tt.wantSuccess, result.Success, result.Error)
+			}
+		})
+	}
+}
+
+func TestChecker_Check_OCSPResponderResolve(t *testing.T) {
+	var pki *testPKI
+	responder := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/ocsp-response")
+		w.Write(pki.ocspResponse(t, ocsp.Good))
+	}))
+	defer responder.Close()
+
+	// The responder name only resolves through the endpoint's overrides
+	_, port, _ := net.SplitHostPort(responder.Listener.Addr().String())
+	pki = newTestPKI(t, "http://ocsp.octo.test:"+port)
+	ts := pki.startServer(t, nil)
+	defer ts.Close()
+
+	result := newInsecureChecker().Check(context.Background(), config.EndpointConfig{
+		ID:      "ocsp-resolve",
+		URL:     ts.URL,
+		Method:  "GET",
+		Resolve: []string{"ocsp.octo.test:*:127.0.0.1"},
+		SSL:     config.SSLConfig{OCSPCheck: true},
+	})
+	if result.OCSPStatus != OCSPStatusGood {
+		t.Errorf("Expected the responder to be reached through the override, got %q (error: %s)", result.OCSPStatus, result.OCSPError)
+	}
+}
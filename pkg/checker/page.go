@@ -0,0 +1,250 @@
+package checker
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/html"
+
+	"github.com/manu/octo/pkg/config"
+)
+
+// Default limits of a page check
+const (
+	defaultPageConcurrency = 6
+	defaultPageMaxAssets   = 100
+)
+
+// AssetResult is the outcome of fetching one subresource of a page
+type AssetResult struct {
+	URL        string        `json:"url"`
+	Type       string        `json:"type"` // "script", "stylesheet" or "image"
+	StatusCode int           `json:"status_code"`
+	Bytes      int64         `json:"bytes"`
+	Duration   time.Duration `json:"duration"`
+	Error      string        `json:"error,omitempty"`
+}
+
+// pageAsset is a subresource referenced by a page
+type pageAsset struct {
+	url       *url.URL
+	assetType string
+}
+
+// checkPage fetches and validates an HTML page like an HTTP check, then
+// fetches its same-origin scripts, stylesheets and images concurrently. It
+// fails when any of them cannot be loaded, and reports the weight of the
+// page with its assets and the time to load them all.
+func (c *Checker) checkPage(ctx context.Context, endpoint config.EndpointConfig) Result {
+	client, release, err := c.sessionClient(ctx, endpoint)
+	if err != nil {
+		result := newResult(endpoint)
+		result.Error = err.Error()
+		return result
+	}
+	defer release()
+
+	// Relative references resolve against the URL the page was served from
+	pageURL, err := url.Parse(endpoint.URL)
+	if err != nil {
+		result := newResult(endpoint)
+		result.Error = err.Error()
+		return result
+	}
+
+	start := time.Now()
+	result, header, body := c.doHTTP(ctx, trackRedirects(client, pageURL), endpoint)
+	if !result.Success {
+		return result
+	}
+	result.Success = false
+
+	mediaType, _, _ := mime.ParseMediaType(header.Get("Content-Type"))
+	if mediaType != "text/html" && mediaType != "application/xhtml+xml" {
+		result.Error = fmt.Sprintf("page check expects an HTML document, got %q", header.Get("Content-Type"))
+		return result
+	}
+	doc, err := html.Parse(bytes.NewReader(body))
+	if err != nil {
+		result.Error = "malformed HTML document: " + err.Error()
+		return result
+	}
+
+	maxAssets := endpoint.Page.MaxAssets
+	if maxAssets <= 0 {
+		maxAssets = defaultPageMaxAssets
+	}
+	assets := pageAssets(doc, pageURL)
+	if len(assets) > maxAssets {
+		assets = assets[:maxAssets]
+	}
+
+	result.Assets = c.fetchAssets(ctx, client, endpoint, assets)
+	result.PageLoadDuration = time.Since(start)
+	result.PageWeight = result.BytesReceived
+
+	var failed []AssetResult
+	for _, a := range result.Assets {
+		result.PageWeight += a.Bytes
+		if a.Error != "" {
+			failed = append(failed, a)
+		}
+	}
+	if len(failed) > 0 {
+		result.Error = fmt.Sprintf("%d of %d assets failed, %s %s: %s", len(failed), len(result.Assets), failed[0].Type, failed[0].URL, failed[0].Error)
+		return result
+	}
+
+	result.Success = true
+	if endpoint.Drift.Enabled {
+		c.detectDrift(endpoint, &result, header, body)
+	}
+	return result
+}
+
+// fetchAssets loads the assets, at most page.concurrency at a time
+func (c *Checker) fetchAssets(ctx context.Context, client *http.Client, endpoint config.EndpointConfig, assets []pageAsset) []AssetResult {
+	concurrency := endpoint.Page.Concurrency
+	if concurrency <= 0 {
+		concurrency = defaultPageConcurrency
+	}
+
+	results := make([]AssetResult, len(assets))
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, asset := range assets {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			results[i] = fetchAsset(ctx, client, endpoint, asset)
+		}()
+	}
+	wg.Wait()
+	return results
+}
+
+// fetchAsset downloads one asset, counting its bytes without keeping them
+func fetchAsset(ctx context.Context, client *http.Client, endpoint config.EndpointConfig, asset pageAsset) AssetResult {
+	r := AssetResult{URL: asset.url.String(), Type: asset.assetType}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, r.URL, nil)
+	if err != nil {
+		r.Error = err.Error()
+		return r
+	}
+	for k, v := range endpoint.Headers {
+		req.Header.Add(k, v)
+	}
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		r.Duration = time.Since(start)
+		r.Error = err.Error()
+		return r
+	}
+	defer resp.Body.Close()
+
+	r.StatusCode = resp.StatusCode
+	r.Bytes, err = io.Copy(io.Discard, resp.Body)
+	r.Duration = time.Since(start)
+	switch {
+	case err != nil:
+		r.Error = "failed to read body: " + err.Error()
+	case resp.StatusCode >= 400:
+		r.Error = fmt.Sprintf("status code %d", resp.StatusCode)
+	}
+	return r
+}
+
+// pageAssets lists the distinct same-origin scripts, stylesheets and images
+// of a document, in document order. A <base> element changes how relative
+// references resolve, not which origin is allowed.
+func pageAssets(doc *html.Node, pageURL *url.URL) []pageAsset {
+	base := pageURL
+	baseSeen := false
+	seen := make(map[string]bool)
+	var assets []pageAsset
+
+	add := func(ref, assetType string) {
+		ref = strings.TrimSpace(ref)
+		if ref == "" {
+			return
+		}
+		u, err := base.Parse(ref)
+		if err != nil || u.Scheme != pageURL.Scheme || u.Host != pageURL.Host {
+			return
+		}
+		u.Fragment = ""
+		if seen[u.String()] {
+			return
+		}
+		seen[u.String()] = true
+		assets = append(assets, pageAsset{url: u, assetType: assetType})
+	}
+
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			switch n.Data {
+			case "base":
+				if href := htmlAttr(n, "href"); href != "" && !baseSeen {
+					if u, err := pageURL.Parse(href); err == nil {
+						base = u
+					}
+					baseSeen = true
+				}
+			case "script":
+				add(htmlAttr(n, "src"), "script")
+			case "link":
+				for _, rel := range strings.Fields(strings.ToLower(htmlAttr(n, "rel"))) {
+					if rel == "stylesheet" {
+						add(htmlAttr(n, "href"), "stylesheet")
+						break
+					}
+				}
+			case "img":
+				add(htmlAttr(n, "src"), "image")
+			}
+		}
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+	}
+	walk(doc)
+	return assets
+}
+
+// htmlAttr returns the value of an attribute of an element
+func htmlAttr(n *html.Node, name string) string {
+	for _, a := range n.Attr {
+		if a.Key == name {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+// trackRedirects returns a copy of client that updates final with the URL of
+// every redirect it follows
+func trackRedirects(client *http.Client, final *url.URL) *http.Client {
+	tracking := *client
+	tracking.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if err := checkRedirect(client, req, via); err != nil {
+			return err
+		}
+		*final = *req.URL
+		return nil
+	}
+	return &tracking
+}
@@ -0,0 +1,99 @@
+package checker
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/manu/octo/pkg/config"
+)
+
+func TestChecker_Check_Page(t *testing.T) {
+	const page = `<!DOCTYPE html>
+<html><head>
+<link rel="stylesheet" href="/static/app.css">
+<link rel="preload stylesheet" href="/static/app.css">
+<link rel="icon" href="/favicon.ico">
+<script src="static/app.js"></script>
+<script src="https://cdn.example.com/lib.js"></script>
+<script>inline()</script>
+</head><body>
+<img src="/img/logo.png#top">
+<img src="data:image/png;base64,AAAA">
+</body></html>`
+
+	var brokenJS atomic.Bool
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/app/", http.StatusFound)
+	})
+	mux.HandleFunc("/app/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(page))
+	})
+	mux.HandleFunc("/static/app.css", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(strings.Repeat("c", 1000)))
+	})
+	mux.HandleFunc("/app/static/app.js", func(w http.ResponseWriter, r *http.Request) {
+		if brokenJS.Load() {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(strings.Repeat("j", 2000)))
+	})
+	mux.HandleFunc("/img/logo.png", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(strings.Repeat("i", 3000)))
+	})
+	mux.HandleFunc("/json", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{}`))
+	})
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	c := NewChecker()
+	endpoint := config.EndpointConfig{ID: "home", Type: TypePage, URL: ts.URL + "/", Method: "GET", Page: config.PageConfig{Concurrency: 2}}
+
+	result := c.Check(context.Background(), endpoint)
+	if !result.Success {
+		t.Fatalf("Expected success, got failure: %s", result.Error)
+	}
+	// The cross-origin script, the icon, inline data and duplicates are skipped
+	want := map[string]string{
+		ts.URL + "/static/app.css":    "stylesheet",
+		ts.URL + "/app/static/app.js": "script",
+		ts.URL + "/img/logo.png":      "image",
+	}
+	if len(result.Assets) != len(want) {
+		t.Fatalf("Expected %d assets, got %+v", len(want), result.Assets)
+	}
+	for _, a := range result.Assets {
+		if want[a.URL] != a.Type || a.StatusCode != http.StatusOK {
+			t.Errorf("Unexpected asset %+v", a)
+		}
+	}
+	if wantWeight := int64(len(page) + 6000); result.PageWeight != wantWeight {
+		t.Errorf("Expected a page weight of %d bytes, got %d", wantWeight, result.PageWeight)
+	}
+	if result.PageLoadDuration < result.Duration {
+		t.Errorf("Expected the load time %v to include the page request %v", result.PageLoadDuration, result.Duration)
+	}
+
+	brokenJS.Store(true)
+	result = c.Check(context.Background(), endpoint)
+	if result.Success || !strings.Contains(result.Error, "1 of 3 assets failed, script "+ts.URL+"/app/static/app.js: status code 404") {
+		t.Errorf("Expected the broken script to fail the check, got %q", result.Error)
+	}
+
+	endpoint.Page.MaxAssets = 1
+	if result := c.Check(context.Background(), endpoint); !result.Success || len(result.Assets) != 1 {
+		t.Errorf("Expected only the first asset to be fetched, got %+v", result.Assets)
+	}
+
+	if result := c.Check(context.Background(), config.EndpointConfig{ID: "json", Type: TypePage, URL: ts.URL + "/json", Method: "GET"}); result.Success || !strings.Contains(result.Error, "expects an HTML document") {
+		t.Errorf("Expected a non-HTML page to fail, got %q", result.Error)
+	}
+}
@@ -0,0 +1,85 @@
+package checker
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/http/httpproxy"
+
+	"github.com/manu/octo/pkg/config"
+)
+
+// proxyFunc picks the proxy for a request URL, nil meaning a direct connection
+type proxyFunc func(*url.URL) (*url.URL, error)
+
+// newProxyFunc parses a proxy setting. The no_proxy list follows the NO_PROXY
+// environment variable conventions; loopback targets are always direct.
+func newProxyFunc(cfg config.ProxyConfig) (proxyFunc, error) {
+	if cfg.URL == "" || cfg.URL == config.ProxyDirect {
+		return nil, nil
+	}
+
+	u, err := url.Parse(cfg.URL)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid proxy url %q", cfg.URL)
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return nil, fmt.Errorf("unsupported proxy scheme %q", u.Scheme)
+	}
+
+	if cfg.Username != "" {
+		username, err := resolveSecret(cfg.Username, true)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy username: %w", err)
+		}
+		password, err := resolveSecret(cfg.Password, false)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy password: %w", err)
+		}
+		u.User = url.UserPassword(username, password)
+	}
+
+	env := &httpproxy.Config{
+		HTTPProxy:  u.String(),
+		HTTPSProxy: u.String(),
+		NoProxy:    strings.Join(cfg.NoProxy, ","),
+	}
+	proxy := env.ProxyFunc()
+	return func(target *url.URL) (*url.URL, error) {
+		// WebSocket URLs are proxied like their HTTP counterparts
+		if target.Scheme == "ws" || target.Scheme == "wss" {
+			t := *target
+			t.Scheme = strings.Replace(target.Scheme, "ws", "http", 1)
+			target = &t
+		}
+		return proxy(target)
+	}, nil
+}
+
+// proxyFor returns the proxy used to reach a URL, if any
+func (d *netDialer) proxyFor(target *url.URL) (*url.URL, error) {
+	if d.proxy == nil {
+		return nil, nil
+	}
+	return d.proxy(target)
+}
+
+// proxyRequest is the transport's Proxy hook, using the proxy of the
+// endpoint being checked
+func proxyRequest(req *http.Request) (*url.URL, error) {
+	return dialerFrom(req.Context()).proxyFor(req.URL)
+}
+
+// recordProxy notes the proxy, without credentials, that carries a request.
+// It runs before the request so failures through the proxy show it, and
+// again for the URL a redirect ended at.
+func recordProxy(result *Result, d *netDialer, target *url.URL) {
+	result.Proxy = ""
+	if p, err := d.proxyFor(target); err == nil && p != nil {
+		result.Proxy = p.Redacted()
+	}
+}
@@ -0,0 +1,152 @@
+package checker
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/manu/octo/pkg/config"
+)
+
+// serveSOCKS5 is a minimal SOCKS5 server requiring the login monitor/s3cret
+// and connecting every request to target
+func serveSOCKS5(target string) func(net.Conn) {
+	return func(conn net.Conn) {
+		buf := make([]byte, 512)
+		// Greeting: version, methods; answer with username/password auth
+		if _, err := io.ReadFull(conn, buf[:2]); err != nil {
+			return
+		}
+		if _, err := io.ReadFull(conn, buf[:buf[1]]); err != nil {
+			return
+		}
+		conn.Write([]byte{5, 2})
+
+		// Username/password sub-negotiation
+		if _, err := io.ReadFull(conn, buf[:2]); err != nil {
+			return
+		}
+		user := make([]byte, buf[1])
+		io.ReadFull(conn, user)
+		io.ReadFull(conn, buf[:1])
+		pass := make([]byte, buf[0])
+		io.ReadFull(conn, pass)
+		if string(user) != "monitor" || string(pass) != "s3cret" {
+			conn.Write([]byte{1, 1})
+			return
+		}
+		conn.Write([]byte{1, 0})
+
+		// Connect request: version, command, reserved, address
+		if _, err := io.ReadFull(conn, buf[:4]); err != nil {
+			return
+		}
+		switch buf[3] {
+		case 1:
+			io.ReadFull(conn, buf[:4+2])
+		case 3:
+			io.ReadFull(conn, buf[:1])
+			io.ReadFull(conn, buf[:int(buf[0])+2])
+		case 4:
+			io.ReadFull(conn, buf[:16+2])
+		}
+
+		upstream, err := net.Dial("tcp", target)
+		if err != nil {
+			conn.Write([]byte{5, 5, 0, 1, 0, 0, 0, 0, 0, 0})
+			return
+		}
+		defer upstream.Close()
+		reply := []byte{5, 0, 0, 1, 127, 0, 0, 1, 0, 0}
+		binary.BigEndian.PutUint16(reply[8:], uint16(upstream.LocalAddr().(*net.TCPAddr).Port))
+		conn.Write(reply)
+
+		go io.Copy(upstream, conn)
+		io.Copy(conn, upstream)
+	}
+}
+
+func TestChecker_Check_Proxy(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "direct")
+	}))
+	defer target.Close()
+	_, targetPort, _ := net.SplitHostPort(target.Listener.Addr().String())
+
+	// The HTTP proxy answers absolute-form requests itself
+	wantAuth := "Basic " + base64.StdEncoding.EncodeToString([]byte("monitor:s3cret"))
+	httpProxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Proxy-Authorization") != wantAuth {
+			w.WriteHeader(http.StatusProxyAuthRequired)
+			return
+		}
+		if !r.URL.IsAbs() || r.URL.Host != "target.test:"+targetPort {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		io.WriteString(w, "via proxy")
+	}))
+	defer httpProxy.Close()
+
+	socksAddr := listen(t, serveSOCKS5(target.Listener.Addr().String()))
+
+	t.Setenv("OCTO_TEST_PROXY_PASSWORD", "s3cret")
+	t.Setenv("OCTO_TEST_PROXY_WRONG", "nope")
+
+	tests := []struct {
+		name      string
+		proxy     config.ProxyConfig
+		wantBody  string
+		wantProxy string
+		wantErr   string
+	}{
+		{name: "http proxy", proxy: config.ProxyConfig{URL: httpProxy.URL, Username: "monitor", Password: "env:OCTO_TEST_PROXY_PASSWORD"},
+			wantBody: "via proxy", wantProxy: strings.Replace(httpProxy.URL, "://", "://monitor:xxxxx@", 1)},
+		{name: "http proxy credentials in url", proxy: config.ProxyConfig{URL: strings.Replace(httpProxy.URL, "://", "://monitor:s3cret@", 1)},
+			wantBody: "via proxy", wantProxy: strings.Replace(httpProxy.URL, "://", "://monitor:xxxxx@", 1)},
+		{name: "http proxy wrong password", proxy: config.ProxyConfig{URL: httpProxy.URL, Username: "monitor", Password: "env:OCTO_TEST_PROXY_WRONG"},
+			wantErr: "status code validation failed", wantProxy: strings.Replace(httpProxy.URL, "://", "://monitor:xxxxx@", 1)},
+		{name: "http proxy down", proxy: config.ProxyConfig{URL: "http://127.0.0.1:1"},
+			wantErr: "proxyconnect", wantProxy: "http://127.0.0.1:1"},
+		{name: "socks5 proxy down", proxy: config.ProxyConfig{URL: "socks5://127.0.0.1:1"},
+			wantErr: "connection refused", wantProxy: "socks5://127.0.0.1:1"},
+		{name: "socks5 proxy", proxy: config.ProxyConfig{URL: "socks5://" + socksAddr, Username: "monitor", Password: "env:OCTO_TEST_PROXY_PASSWORD"},
+			wantBody: "direct", wantProxy: "socks5://monitor:xxxxx@" + socksAddr},
+		{name: "no_proxy", proxy: config.ProxyConfig{URL: httpProxy.URL, NoProxy: []string{".test"}}, wantBody: "direct"},
+		{name: "direct", proxy: config.ProxyConfig{URL: config.ProxyDirect}, wantBody: "direct"},
+		{name: "literal password", proxy: config.ProxyConfig{URL: httpProxy.URL, Username: "monitor", Password: "s3cret"},
+			wantErr: "invalid proxy password"},
+		{name: "unsupported scheme", proxy: config.ProxyConfig{URL: "ftp://proxy:21"}, wantErr: "unsupported proxy scheme"},
+	}
+
+	c := NewChecker()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			endpoint := config.EndpointConfig{
+				ID: "proxied", URL: "http://target.test:" + targetPort + "/", Method: "GET",
+				Resolve: []string{"target.test:*:127.0.0.1"},
+				Proxy:   tt.proxy,
+				Validation: config.ValidationConfig{
+					ContentMatch: config.ContentMatch{Type: "exact", Pattern: tt.wantBody},
+				},
+			}
+			result := c.Check(context.Background(), endpoint)
+			if tt.wantErr != "" {
+				if result.Success || !strings.Contains(result.Error, tt.wantErr) {
+					t.Errorf("Expected error containing %q, got success=%v error=%q", tt.wantErr, result.Success, result.Error)
+				}
+			} else if !result.Success {
+				t.Fatalf("Expected success, got failure: %s", result.Error)
+			}
+			if result.Proxy != tt.wantProxy {
+				t.Errorf("Expected proxy %q, got %q", tt.wantProxy, result.Proxy)
+			}
+		})
+	}
+}
@@ -0,0 +1,6 @@
+//go:build race
+
+package checker
+
+// The race detector maps shadow memory that does not fit a data limit
+const raceEnabled = true
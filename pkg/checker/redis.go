@@ -0,0 +1,163 @@
+package checker
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/manu/octo/pkg/config"
+)
+
+// maxRedisReply bounds the size of a reply read from Redis
+const maxRedisReply = 1 << 20
+
+// checkRedis connects to a Redis server, authenticates, selects the database
+// and runs the probe command (PING by default). ConnDuration covers the
+// connection setup and authentication, TTFB the probe command.
+func (c *Checker) checkRedis(ctx context.Context, endpoint config.EndpointConfig) Result {
+	result := newResult(endpoint)
+	db := endpoint.Database
+
+	address, username, password, expect, err := c.databaseParams(endpoint, "6379")
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	host, _, _ := net.SplitHostPort(address)
+
+	command := strings.Fields(db.Query)
+	if len(command) == 0 {
+		command = []string{"PING"}
+	}
+
+	start := time.Now()
+	conn, err := dialerFrom(ctx).DialContext(ctx, "tcp", address)
+	if err != nil {
+		result.ConnDuration = time.Since(start)
+		result.Duration = time.Since(start)
+		result.Error = "connection failed: " + err.Error()
+		return result
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	if db.TLS {
+		tlsConn := tls.Client(conn, c.tlsConfig(host))
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			result.Duration = time.Since(start)
+			result.Error = "tls handshake failed: " + err.Error()
+			return result
+		}
+		conn = tlsConn
+	}
+
+	rc := &redisConn{conn: conn, r: bufio.NewReader(conn)}
+
+	if password != "" {
+		auth := []string{"AUTH", password}
+		if username != "" {
+			auth = []string{"AUTH", username, password}
+		}
+		if _, err := rc.do(auth...); err != nil {
+			result.Duration = time.Since(start)
+			result.Error = "authentication failed: " + err.Error()
+			return result
+		}
+	}
+	if db.Database != "" {
+		if _, err := rc.do("SELECT", db.Database); err != nil {
+			result.Duration = time.Since(start)
+			result.Error = "select failed: " + err.Error()
+			return result
+		}
+	}
+	result.ConnDuration = time.Since(start)
+
+	queryStart := time.Now()
+	value, err := rc.do(command...)
+	result.TTFB = time.Since(queryStart)
+	result.Duration = time.Since(start)
+
+	return finishQuery(result, value, err, expect)
+}
+
+// redisConn speaks just enough RESP to send commands and read their replies
+type redisConn struct {
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+// redisError is an error reply sent by the server
+type redisError string
+
+func (e redisError) Error() string { return string(e) }
+
+func (rc *redisConn) do(args ...string) (string, error) {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&sb, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	if _, err := io.WriteString(rc.conn, sb.String()); err != nil {
+		return "", err
+	}
+	return rc.readReply()
+}
+
+// readReply reads one reply, rendering arrays as newline separated elements
+func (rc *redisConn) readReply() (string, error) {
+	line, err := rc.r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if line == "" {
+		return "", errors.New("empty reply")
+	}
+
+	switch line[0] {
+	case '+', ':':
+		return line[1:], nil
+	case '-':
+		return "", redisError(line[1:])
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil || n > maxRedisReply {
+			return "", fmt.Errorf("invalid bulk reply %q", line)
+		}
+		if n < 0 {
+			return "", nil
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(rc.r, buf); err != nil {
+			return "", err
+		}
+		return string(buf[:n]), nil
+	case '*':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return "", fmt.Errorf("invalid array reply %q", line)
+		}
+		elems := make([]string, 0, max(n, 0))
+		for i := 0; i < n; i++ {
+			elem, err := rc.readReply()
+			if err != nil {
+				return "", err
+			}
+			elems = append(elems, elem)
+		}
+		return strings.Join(elems, "\n"), nil
+	default:
+		return "", fmt.Errorf("unsupported reply %q", line)
+	}
+}
@@ -0,0 +1,145 @@
+package checker
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+
+	"github.com/go-sql-driver/mysql"
+
+	"github.com/manu/octo/pkg/config"
+)
+
+// mysqlNetwork is the MySQL driver network that dials through the endpoint
+// dialer carried by the connection context
+const mysqlNetwork = "octo"
+
+func init() {
+	mysql.RegisterDialContext(mysqlNetwork, func(ctx context.Context, addr string) (net.Conn, error) {
+		return dialerFrom(ctx).DialContext(ctx, "tcp", addr)
+	})
+}
+
+// netDialer opens connections for one endpoint, applying its resolve
+// overrides, DNS server and IP version, and holds its proxy
+type netDialer struct {
+	overrides map[string]string // "host:port" or "host:*" to an IP address
+	resolver  *net.Resolver
+	family    string // "4", "6", or empty for either
+	proxy     proxyFunc
+}
+
+// newNetDialer parses the dialing options of an endpoint. Resolve entries
+// use curl's syntax, "host:port:address", with "*" matching any port and
+// IPv6 addresses in brackets.
+func newNetDialer(endpoint config.EndpointConfig) (*netDialer, error) {
+	d := &netDialer{overrides: make(map[string]string)}
+
+	var err error
+	if d.proxy, err = newProxyFunc(endpoint.Proxy); err != nil {
+		return nil, err
+	}
+
+	switch endpoint.IPVersion {
+	case "", "both":
+	case "4", "6":
+		d.family = endpoint.IPVersion
+	default:
+		return nil, fmt.Errorf("unsupported ip_version %q", endpoint.IPVersion)
+	}
+
+	for _, entry := range endpoint.Resolve {
+		host, rest, ok := strings.Cut(entry, ":")
+		port, address, ok2 := strings.Cut(rest, ":")
+		address = strings.TrimSuffix(strings.TrimPrefix(address, "["), "]")
+		if !ok || !ok2 || host == "" || port == "" || net.ParseIP(address) == nil {
+			return nil, fmt.Errorf("invalid resolve entry %q, expected host:port:address", entry)
+		}
+		d.overrides[strings.ToLower(host)+":"+port] = address
+	}
+
+	if endpoint.DNSServer != "" {
+		server := endpoint.DNSServer
+		if _, _, err := net.SplitHostPort(server); err != nil {
+			server = net.JoinHostPort(strings.Trim(server, "[]"), "53")
+		}
+		d.resolver = &net.Resolver{
+			PreferGo: true,
+			Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+				var dialer net.Dialer
+				return dialer.DialContext(ctx, network, server)
+			},
+		}
+	}
+	return d, nil
+}
+
+// custom reports whether the dialer changes how addresses are resolved or
+// which family is used, in which case connections must not be pooled with
+// those of other endpoints
+func (d *netDialer) custom() bool {
+	return len(d.overrides) > 0 || d.resolver != nil || d.family != ""
+}
+
+// DialContext connects to address, substituting an overridden IP and
+// restricting the address family
+func (d *netDialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	if host, port, err := net.SplitHostPort(address); err == nil {
+		host = strings.ToLower(host)
+		if ip, ok := d.overrides[host+":"+port]; ok {
+			address = net.JoinHostPort(ip, port)
+		} else if ip, ok := d.overrides[host+":*"]; ok {
+			address = net.JoinHostPort(ip, port)
+		}
+	}
+	if d.family != "" && (network == "tcp" || network == "udp") {
+		network += d.family
+	}
+
+	dialer := net.Dialer{Resolver: d.resolver}
+	return dialer.DialContext(ctx, network, address)
+}
+
+type dialerKey struct{}
+
+// withDialer returns a context carrying the dialer of the endpoint being checked
+func withDialer(ctx context.Context, d *netDialer) context.Context {
+	return context.WithValue(ctx, dialerKey{}, d)
+}
+
+// dialerFrom returns the dialer carried by ctx, or one without overrides
+func dialerFrom(ctx context.Context) *netDialer {
+	if d, ok := ctx.Value(dialerKey{}).(*netDialer); ok {
+		return d
+	}
+	return &netDialer{}
+}
+
+// CheckAll runs the check of an endpoint. With ip_version "both" it runs once
+// per address family, concurrently, and returns one result for each so a
+// broken A or AAAA record is reported on its own.
+func (c *Checker) CheckAll(ctx context.Context, endpoint config.EndpointConfig) []Result {
+	if endpoint.IPVersion != "both" {
+		return []Result{c.Check(ctx, endpoint)}
+	}
+
+	families := []string{"4", "6"}
+	results := make([]Result, len(families))
+	var wg sync.WaitGroup
+	for i, family := range families {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ep := endpoint
+			ep.IPVersion = family
+			results[i] = c.Check(ctx, ep)
+			if results[i].Error != "" {
+				results[i].Error = "IPv" + family + ": " + results[i].Error
+			}
+		}()
+	}
+	wg.Wait()
+	return results
+}
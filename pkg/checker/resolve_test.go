@@ -0,0 +1,116 @@
+package checker
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/manu/octo/pkg/config"
+)
+
+func TestChecker_Check_Resolve(t *testing.T) {
+	// httptest certificates are issued for example.com
+	var gotHost, gotSNI string
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotHost, gotSNI = r.Host, r.TLS.ServerName
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+	_, port, _ := net.SplitHostPort(ts.Listener.Addr().String())
+
+	c := newInsecureChecker()
+
+	for _, entry := range []string{"example.com:" + port + ":127.0.0.1", "EXAMPLE.com:*:127.0.0.1"} {
+		result := c.Check(context.Background(), config.EndpointConfig{
+			ID: "pinned", URL: "https://example.com:" + port + "/", Method: "GET",
+			Resolve: []string{entry},
+		})
+		if !result.Success {
+			t.Fatalf("Expected success with %q, got failure: %s", entry, result.Error)
+		}
+		if gotHost != "example.com:"+port || gotSNI != "example.com" {
+			t.Errorf("Expected Host and SNI example.com, got %q and %q", gotHost, gotSNI)
+		}
+		if result.DNSDuration != 0 {
+			t.Errorf("Expected no DNS lookup for a pinned name, got %v", result.DNSDuration)
+		}
+	}
+
+	result := c.Check(context.Background(), config.EndpointConfig{
+		ID: "pinned", URL: "https://example.com:" + port + "/", Method: "GET",
+		Resolve: []string{"example.com:" + port + ":[::1]"}, IPVersion: "4",
+	})
+	if result.Success {
+		t.Error("Expected an IPv6 override to fail with ip_version 4")
+	}
+
+	for _, bad := range []config.EndpointConfig{
+		{ID: "bad", URL: ts.URL, Resolve: []string{"example.com:443"}},
+		{ID: "bad", URL: ts.URL, Resolve: []string{"example.com:443:not-an-ip"}},
+		{ID: "bad", URL: ts.URL, IPVersion: "5"},
+	} {
+		if result := c.Check(context.Background(), bad); result.Success || result.Error == "" {
+			t.Errorf("Expected %+v to be rejected", bad)
+		}
+	}
+}
+
+func TestChecker_Check_DNSServer(t *testing.T) {
+	resolver := startDNSServer(t)
+	addr := listen(t, func(net.Conn) {})
+	_, port, _ := net.SplitHostPort(addr)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	// loopback.example.test only exists on the test server
+	c := NewChecker()
+	result := c.Check(ctx, config.EndpointConfig{
+		ID: "tcp", Type: TypeTCP, URL: "loopback.example.test:" + port, DNSServer: resolver,
+	})
+	if !result.Success {
+		t.Errorf("Expected the custom resolver to be used, got failure: %s", result.Error)
+	}
+
+	result = c.Check(ctx, config.EndpointConfig{ID: "tcp", Type: TypeTCP, URL: "loopback.example.test:" + port})
+	if result.Success {
+		t.Error("Expected the system resolver not to know loopback.example.test")
+	}
+}
+
+func TestChecker_CheckAll_IPVersionBoth(t *testing.T) {
+	ln, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	ts.Listener = ln
+	ts.Start()
+	defer ts.Close()
+	_, port, _ := net.SplitHostPort(ln.Addr().String())
+
+	// Only IPv4 is served, so the IPv6 check of localhost must fail
+	c := NewChecker()
+	results := c.CheckAll(context.Background(), config.EndpointConfig{
+		ID: "dual", URL: "http://localhost:" + port + "/", Method: "GET", IPVersion: "both",
+	})
+	if len(results) != 2 {
+		t.Fatalf("Expected one result per address family, got %d", len(results))
+	}
+	if results[0].IPVersion != "4" || !results[0].Success {
+		t.Errorf("Expected IPv4 to succeed, got version=%q error=%q", results[0].IPVersion, results[0].Error)
+	}
+	if results[1].IPVersion != "6" || results[1].Success || !strings.HasPrefix(results[1].Error, "IPv6: ") {
+		t.Errorf("Expected IPv6 to fail on its own, got version=%q success=%v error=%q", results[1].IPVersion, results[1].Success, results[1].Error)
+	}
+
+	if results := c.CheckAll(context.Background(), config.EndpointConfig{ID: "single", URL: ts.URL, Method: "GET"}); len(results) != 1 || !results[0].Success {
+		t.Errorf("Expected a single successful result without ip_version both, got %+v", results)
+	}
+}
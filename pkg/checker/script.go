@@ -0,0 +1,369 @@
+package checker
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"os"
+	"os/exec"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/dop251/goja"
+
+	"github.com/manu/octo/pkg/config"
+)
+
+// Default limits of a validation script
+const (
+	defaultScriptTimeout = 100 * time.Millisecond
+	defaultScriptMemory  = 64 << 20
+	maxScriptCallStack   = 1024
+
+	// Time given to a script runner to start on top of the script timeout
+	scriptStartup = 2 * time.Second
+	// Bounds what a failing script runner writes to stderr
+	maxScriptStderr = 64 << 10
+)
+
+// scriptRunnerEnv marks a child process started to run a validation script
+const scriptRunnerEnv = "OCTO_SCRIPT_RUNNER"
+
+// Scripts run in a child process of the current executable, which becomes a
+// script runner before main when started with scriptRunnerEnv set
+func init() {
+	if os.Getenv(scriptRunnerEnv) == "1" {
+		os.Exit(serveScript(os.Stdin, os.Stdout))
+	}
+}
+
+// scriptInput is what a script runner is given
+type scriptInput struct {
+	Script   string         `json:"script"`
+	Timeout  time.Duration  `json:"timeout"`
+	Memory   int64          `json:"memory"`
+	Response scriptResponse `json:"response"`
+}
+
+// scriptResponse is the response argument of a script. Timings are in
+// milliseconds and header names are lower case.
+type scriptResponse struct {
+	Status    int                `json:"status"`
+	Protocol  string             `json:"protocol"`
+	URL       string             `json:"url"`
+	Headers   map[string]any     `json:"headers"`
+	Body      string             `json:"body"`
+	Truncated bool               `json:"truncated"`
+	Timings   map[string]float64 `json:"timings"`
+}
+
+// scriptOutput is what a script runner reports
+type scriptOutput struct {
+	Error   string             `json:"error,omitempty"`
+	Metrics map[string]float64 `json:"metrics,omitempty"`
+}
+
+// runScript evaluates the validation script of an endpoint. The script is
+// the body of a function receiving the response; it passes unless it throws
+// or returns false, and may return {pass, message, metrics} to report
+// custom metrics, stored in result.Metrics.
+//
+// Scripts have no I/O. Each runs in its own process, whose heap is limited
+// to the script memory and which is interrupted after the script timeout,
+// so a script can neither exhaust the memory of the checker nor hold it up.
+// Limits beyond config.MaxScriptTimeout and config.MaxScriptMemory are
+// lowered to them.
+func runScript(ctx context.Context, v config.ValidationConfig, result *Result, resp *http.Response, body []byte, truncated bool) error {
+	timeout := v.ScriptTimeout
+	if timeout <= 0 {
+		timeout = defaultScriptTimeout
+	}
+	memory := v.ScriptMemory
+	if memory <= 0 {
+		memory = defaultScriptMemory
+	}
+
+	headers := make(map[string]any, len(resp.Header))
+	for name, values := range resp.Header {
+		headers[strings.ToLower(name)] = strings.Join(values, ", ")
+	}
+	ms := func(d time.Duration) float64 { return float64(d) / float64(time.Millisecond) }
+
+	out, err := execScript(ctx, scriptInput{
+		Script:  v.Script,
+		Timeout: min(timeout, config.MaxScriptTimeout),
+		Memory:  min(memory, config.MaxScriptMemory),
+		Response: scriptResponse{
+			Status:    resp.StatusCode,
+			Protocol:  resp.Proto,
+			URL:       resp.Request.URL.String(),
+			Headers:   headers,
+			Body:      string(body),
+			Truncated: truncated,
+			Timings: map[string]float64{
+				"total":    ms(result.Duration),
+				"dns":      ms(result.DNSDuration),
+				"connect":  ms(result.ConnDuration),
+				"tls":      ms(result.TLSDuration),
+				"write":    ms(result.WriteDuration),
+				"ttfb":     ms(result.TTFB),
+				"transfer": ms(result.TransferDuration),
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	for name, value := range out.Metrics {
+		if result.Metrics == nil {
+			result.Metrics = make(map[string]float64)
+		}
+		result.Metrics[name] = value
+	}
+	if out.Error != "" {
+		return errors.New(out.Error)
+	}
+	return nil
+}
+
+// execScript runs a script in a script runner
+func execScript(ctx context.Context, in scriptInput) (scriptOutput, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return scriptOutput{}, fmt.Errorf("script runner unavailable: %w", err)
+	}
+	input, err := json.Marshal(in)
+	if err != nil {
+		return scriptOutput{}, err
+	}
+
+	runCtx, cancel := context.WithTimeout(ctx, in.Timeout+scriptStartup)
+	defer cancel()
+
+	// The runner inherits nothing from the environment of the checker
+	var stdout bytes.Buffer
+	stderr := &limitedBuffer{max: maxScriptStderr}
+	cmd := exec.CommandContext(runCtx, exe)
+	cmd.Env = []string{scriptRunnerEnv + "=1"}
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout = &stdout
+	cmd.Stderr = stderr
+
+	if err := cmd.Run(); err != nil {
+		switch {
+		case ctx.Err() != nil:
+			return scriptOutput{}, errors.New("script interrupted: check cancelled")
+		case strings.Contains(stderr.String(), "out of memory"), strings.Contains(stderr.String(), "cannot allocate memory"):
+			return scriptOutput{}, fmt.Errorf("script interrupted: memory limit of %d bytes exceeded", in.Memory)
+		case runCtx.Err() != nil, exceededCPU(err):
+			return scriptOutput{}, fmt.Errorf("script interrupted: time limit of %v exceeded", in.Timeout)
+		}
+		return scriptOutput{}, fmt.Errorf("script runner failed: %v", err)
+	}
+
+	var out scriptOutput
+	if err := json.Unmarshal(stdout.Bytes(), &out); err != nil {
+		return scriptOutput{}, fmt.Errorf("script runner failed: invalid output: %w", err)
+	}
+	return out, nil
+}
+
+// serveScript is the main function of a script runner: it reads a
+// scriptInput, limits its own resources and writes the scriptOutput
+func serveScript(r io.Reader, w io.Writer) int {
+	var in scriptInput
+	if err := json.NewDecoder(r).Decode(&in); err != nil {
+		fmt.Fprintf(os.Stderr, "invalid script input: %v\n", err)
+		return 1
+	}
+
+	// The collector runs well before the hard limit is reached
+	if err := limitScriptProcess(in.Memory, in.Timeout); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to limit the script runner: %v\n", err)
+		return 1
+	}
+	debug.SetMemoryLimit(in.Memory / 2)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var out scriptOutput
+	if err := evalScript(ctx, in, &out); err != nil {
+		out.Error = err.Error()
+	}
+	if err := json.NewEncoder(w).Encode(out); err != nil {
+		return 1
+	}
+	return 0
+}
+
+// evalScript evaluates a script in a new runtime
+func evalScript(ctx context.Context, in scriptInput, out *scriptOutput) error {
+	prog, err := goja.Compile("script", "(function(response) {\n"+in.Script+"\n})", true)
+	if err != nil {
+		return fmt.Errorf("invalid script: %w", err)
+	}
+
+	vm := goja.New()
+	vm.SetMaxCallStackSize(maxScriptCallStack)
+	vm.Set("crypto", map[string]any{
+		"sha256": func(s string) string {
+			sum := sha256.Sum256([]byte(s))
+			return hex.EncodeToString(sum[:])
+		},
+		"md5": func(s string) string {
+			sum := md5.Sum([]byte(s))
+			return hex.EncodeToString(sum[:])
+		},
+		"hmacSHA256": func(key, s string) string {
+			mac := hmac.New(sha256.New, []byte(key))
+			mac.Write([]byte(s))
+			return hex.EncodeToString(mac.Sum(nil))
+		},
+	})
+
+	stop := watchScript(ctx, vm, in.Timeout)
+	defer stop()
+
+	fn, err := vm.RunProgram(prog)
+	if err != nil {
+		return scriptError(err)
+	}
+	call, _ := goja.AssertFunction(fn)
+	ret, err := call(goja.Undefined(), scriptArgument(vm, in.Response))
+	if err != nil {
+		return scriptError(err)
+	}
+
+	pass := true
+	var message string
+	switch v := ret.Export().(type) {
+	case nil:
+	case bool:
+		pass = v
+	case map[string]any:
+		if p, ok := v["pass"]; ok {
+			pass, _ = p.(bool)
+		}
+		if m, ok := v["message"]; ok {
+			message = fmt.Sprint(m)
+		}
+		if m, ok := v["metrics"].(map[string]any); ok {
+			if err := setScriptMetrics(out, m); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("script returned %T, expected a boolean or {pass, message, metrics}", v)
+	}
+
+	if !pass {
+		if message != "" {
+			return fmt.Errorf("script validation failed: %s", message)
+		}
+		return fmt.Errorf("script validation failed")
+	}
+	return nil
+}
+
+// scriptArgument builds the response argument of a script
+func scriptArgument(vm *goja.Runtime, resp scriptResponse) goja.Value {
+	obj := vm.NewObject()
+	obj.Set("status", resp.Status)
+	obj.Set("protocol", resp.Protocol)
+	obj.Set("url", resp.URL)
+	obj.Set("headers", resp.Headers)
+	obj.Set("body", resp.Body)
+	obj.Set("truncated", resp.Truncated)
+	timings := make(map[string]any, len(resp.Timings))
+	for name, value := range resp.Timings {
+		timings[name] = value
+	}
+	obj.Set("timings", timings)
+	obj.Set("json", func(goja.FunctionCall) goja.Value {
+		parse, _ := goja.AssertFunction(vm.Get("JSON").ToObject(vm).Get("parse"))
+		v, err := parse(goja.Undefined(), vm.ToValue(resp.Body))
+		if err != nil {
+			panic(err)
+		}
+		return v
+	})
+	return obj
+}
+
+// setScriptMetrics records the numeric metrics returned by a script
+func setScriptMetrics(out *scriptOutput, m map[string]any) error {
+	for name, value := range m {
+		var f float64
+		switch n := value.(type) {
+		case int64:
+			f = float64(n)
+		case float64:
+			f = n
+		default:
+			return fmt.Errorf("script metric %q is %T, expected a number", name, value)
+		}
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			return fmt.Errorf("script metric %q is not a finite number", name)
+		}
+		if out.Metrics == nil {
+			out.Metrics = make(map[string]float64)
+		}
+		out.Metrics[name] = f
+	}
+	return nil
+}
+
+// watchScript interrupts the runtime when the check is cancelled or the
+// timeout expires
+func watchScript(ctx context.Context, vm *goja.Runtime, timeout time.Duration) func() {
+	done := make(chan struct{})
+	go func() {
+		deadline := time.NewTimer(timeout)
+		defer deadline.Stop()
+		select {
+		case <-done:
+		case <-ctx.Done():
+			vm.Interrupt("check cancelled")
+		case <-deadline.C:
+			vm.Interrupt(fmt.Sprintf("time limit of %v exceeded", timeout))
+		}
+	}()
+	return func() { close(done) }
+}
+
+// scriptError describes an exception thrown or an interruption
+func scriptError(err error) error {
+	var interrupted *goja.InterruptedError
+	if errors.As(err, &interrupted) {
+		return fmt.Errorf("script interrupted: %v", interrupted.Value())
+	}
+	var exception *goja.Exception
+	if errors.As(err, &exception) {
+		return fmt.Errorf("script validation failed: %s", exception.Value().String())
+	}
+	return fmt.Errorf("script validation failed: %w", err)
+}
+
+// limitedBuffer keeps the first max bytes written to it
+type limitedBuffer struct {
+	bytes.Buffer
+	max int
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if room := b.max - b.Len(); room > 0 {
+		b.Buffer.Write(p[:min(len(p), room)])
+	}
+	return len(p), nil
+}
@@ -0,0 +1,19 @@
+//go:build !unix
+
+package checker
+
+import "time"
+
+// scriptMemoryLimited reports whether scripts fail past their memory limit
+const scriptMemoryLimited = false
+
+// limitScriptProcess leaves the script runner unbounded apart from its
+// timeout: resource limits are only set on Unix
+func limitScriptProcess(memory int64, timeout time.Duration) error {
+	return nil
+}
+
+// exceededCPU reports whether the script runner was killed for its CPU time
+func exceededCPU(err error) bool {
+	return false
+}
@@ -0,0 +1,78 @@
+package checker
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/manu/octo/pkg/config"
+)
+
+func TestChecker_Check_Script(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"used": 30, "limit": 40, "items": [1, 2, 3]}`))
+	}))
+	defer ts.Close()
+
+	tests := []struct {
+		name        string
+		script      string
+		timeout     time.Duration
+		memory      int64
+		wantErr     string
+		wantMetrics map[string]float64
+	}{
+		{name: "compare fields", script: `const doc = response.json(); return doc.used < doc.limit;`},
+		{name: "compare fields failing", script: `const doc = response.json(); return doc.used > doc.limit;`,
+			wantErr: "script validation failed"},
+		{name: "no return passes", script: `if (response.status !== 200) throw new Error("bad status");`},
+		{name: "throw", script: `throw new Error("quota almost used");`, wantErr: "quota almost used"},
+		{name: "message and metrics", script: `
+			const doc = response.json();
+			return {
+				pass: doc.used / doc.limit < 0.5,
+				message: "usage at " + (100 * doc.used / doc.limit) + "%",
+				metrics: {usage_ratio: doc.used / doc.limit, items: doc.items.length},
+			};`,
+			wantErr: "usage at 75%", wantMetrics: map[string]float64{"usage_ratio": 0.75, "items": 3}},
+		{name: "headers and timings", script: `return response.headers["content-type"] === "application/json" && response.timings.total > 0;`},
+		{name: "signature", script: `return crypto.sha256("") === "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855" &&
+			crypto.hmacSHA256("key", "msg").length === 64;`},
+		{name: "invalid metric", script: `return {pass: true, metrics: {bad: "high"}};`, wantErr: `metric "bad" is string`},
+		{name: "syntax error", script: `return (`, wantErr: "invalid script"},
+		{name: "no io", script: `return typeof require === "undefined" && typeof fetch === "undefined";`},
+		{name: "cpu limit", script: `while (true) {}`, timeout: 50 * time.Millisecond, wantErr: "script interrupted: time limit of 50ms exceeded"},
+		{name: "memory limit", script: `const a = []; while (true) { a.push("x".repeat(1024)); }`, timeout: 5 * time.Second, memory: 32 << 20,
+			wantErr: "script interrupted: memory limit of 33554432 bytes exceeded"},
+		{name: "stack limit", script: `function f() { return f(); } return f();`, wantErr: "script"},
+	}
+
+	c := NewChecker()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.memory > 0 && !scriptMemoryLimited {
+				t.Skip("script memory is not limited")
+			}
+			result := c.Check(context.Background(), config.EndpointConfig{
+				ID: "scripted", URL: ts.URL, Method: "GET",
+				Validation: config.ValidationConfig{Script: tt.script, ScriptTimeout: tt.timeout, ScriptMemory: tt.memory},
+			})
+			if tt.wantErr == "" {
+				if !result.Success {
+					t.Errorf("Expected success, got failure: %s", result.Error)
+				}
+			} else if result.Success || !strings.Contains(result.Error, tt.wantErr) {
+				t.Errorf("Expected error containing %q, got success=%v error=%q", tt.wantErr, result.Success, result.Error)
+			}
+			for name, want := range tt.wantMetrics {
+				if got, ok := result.Metrics[name]; !ok || got != want {
+					t.Errorf("Expected metric %s = %v, got %v", name, want, result.Metrics)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,38 @@
+//go:build unix
+
+package checker
+
+import (
+	"errors"
+	"os/exec"
+	"syscall"
+	"time"
+)
+
+// scriptMemoryLimited reports whether scripts fail past their memory limit
+const scriptMemoryLimited = !raceEnabled
+
+// limitScriptProcess bounds the heap and the CPU time of the script runner.
+// The runtime aborts the process once an allocation goes over the data
+// limit, and the kernel kills it once it used its CPU time.
+func limitScriptProcess(memory int64, timeout time.Duration) error {
+	if scriptMemoryLimited {
+		data := syscall.Rlimit{Cur: uint64(memory), Max: uint64(memory)}
+		if err := syscall.Setrlimit(syscall.RLIMIT_DATA, &data); err != nil {
+			return err
+		}
+	}
+	seconds := uint64(timeout.Round(time.Second)/time.Second) + 1
+	cpu := syscall.Rlimit{Cur: seconds, Max: seconds + 1}
+	return syscall.Setrlimit(syscall.RLIMIT_CPU, &cpu)
+}
+
+// exceededCPU reports whether the script runner was killed for its CPU time
+func exceededCPU(err error) bool {
+	var exit *exec.ExitError
+	if !errors.As(err, &exit) {
+		return false
+	}
+	status, ok := exit.Sys().(syscall.WaitStatus)
+	return ok && status.Signaled() && (status.Signal() == syscall.SIGXCPU || status.Signal() == syscall.SIGKILL)
+}
@@ -0,0 +1,390 @@
+package checker
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/textproto"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+
+	"github.com/manu/octo/pkg/config"
+)
+
+// serveSMTP fakes an SMTP server offering STARTTLS with the given certificate
+func serveSMTP(cert tls.Certificate) func(net.Conn) {
+	return func(conn net.Conn) {
+		tp := textproto.NewConn(conn)
+		tp.PrintfLine("220 mail.example.com ESMTP Postfix")
+		for {
+			line, err := tp.ReadLine()
+			if err != nil {
+				return
+			}
+			switch verb, _, _ := strings.Cut(line, " "); strings.ToUpper(verb) {
+			case "EHLO":
+				tp.PrintfLine("250-mail.example.com")
+				tp.PrintfLine("250-SIZE 10240000")
+				tp.PrintfLine("250 STARTTLS")
+			case "STARTTLS":
+				tp.PrintfLine("220 Ready to start TLS")
+				tlsConn := tls.Server(conn, &tls.Config{Certificates: []tls.Certificate{cert}})
+				if err := tlsConn.Handshake(); err != nil {
+					return
+				}
+				conn = tlsConn
+				tp = textproto.NewConn(conn)
+			case "QUIT":
+				tp.PrintfLine("221 Bye")
+				return
+			default:
+				tp.PrintfLine("502 Command not implemented")
+			}
+		}
+	}
+}
+
+// serveIMAP fakes an IMAP server accepting the login monitor/s3cret
+func serveIMAP(conn net.Conn) {
+	tp := textproto.NewConn(conn)
+	tp.PrintfLine("* OK [CAPABILITY IMAP4rev1 LOGINDISABLED] Dovecot ready.")
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			return
+		}
+		tag := fields[0]
+		switch strings.ToUpper(fields[1]) {
+		case "LOGIN":
+			if line == tag+` LOGIN "monitor" "s3cret"` {
+				tp.PrintfLine("%s OK Logged in", tag)
+			} else {
+				tp.PrintfLine("%s NO [AUTHENTICATIONFAILED] Authentication failed.", tag)
+			}
+		case "LOGOUT":
+			tp.PrintfLine("* BYE Logging out")
+			tp.PrintfLine("%s OK Logout completed.", tag)
+			return
+		default:
+			tp.PrintfLine("%s BAD Error in IMAP command", tag)
+		}
+	}
+}
+
+// serveFTP fakes an FTP server accepting the login monitor/s3cret and
+// listing two files over an EPSV data connection, with AUTH TLS support.
+// Passwords received are counted in logins when it is set.
+func serveFTP(cert tls.Certificate, logins *atomic.Int32) func(net.Conn) {
+	return func(conn net.Conn) {
+		serveFTPConn(conn, &tls.Config{Certificates: []tls.Certificate{cert}}, logins)
+	}
+}
+
+func serveFTPConn(conn net.Conn, tlsConfig *tls.Config, logins *atomic.Int32) {
+	tp := textproto.NewConn(conn)
+	tp.PrintfLine("220 (vsFTPd 3.0.5)")
+	protected := false
+	var data net.Listener
+	defer func() {
+		if data != nil {
+			data.Close()
+		}
+	}()
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+		verb, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(verb) {
+		case "AUTH":
+			tp.PrintfLine("234 Proceed with negotiation.")
+			conn = tls.Server(conn, tlsConfig)
+			tp = textproto.NewConn(conn)
+		case "PBSZ":
+			tp.PrintfLine("200 PBSZ set to 0.")
+		case "PROT":
+			protected = arg == "P"
+			tp.PrintfLine("200 PROT now %s.", arg)
+		case "USER":
+			tp.PrintfLine("331 Please specify the password.")
+		case "PASS":
+			if logins != nil {
+				logins.Add(1)
+			}
+			if arg != "s3cret" {
+				tp.PrintfLine("530 Login incorrect.")
+				continue
+			}
+			tp.PrintfLine("230 Login successful.")
+		case "FEAT":
+			tp.PrintfLine("211-Features:\r\n EPSV\r\n211 End")
+		case "TYPE":
+			tp.PrintfLine("200 Switching to Binary mode.")
+		case "EPSV":
+			if data, err = net.Listen("tcp", "127.0.0.1:0"); err != nil {
+				return
+			}
+			tp.PrintfLine("229 Entering Extended Passive Mode (|||%d|)", data.Addr().(*net.TCPAddr).Port)
+		case "LIST":
+			if data == nil {
+				tp.PrintfLine("425 Use PORT or PASV first.")
+				continue
+			}
+			tp.PrintfLine("150 Here comes the directory listing.")
+			dc, err := data.Accept()
+			if err != nil {
+				return
+			}
+			if protected {
+				dc = tls.Server(dc, tlsConfig)
+			}
+			io.WriteString(dc, "-rw-r--r--    1 0        0            1024 Jan 02 10:00 backup.tar\r\n")
+			io.WriteString(dc, "-rw-r--r--    1 0        0              12 Jan 02 10:00 README\r\n")
+			dc.Close()
+			data.Close()
+			data = nil
+			tp.PrintfLine("226 Directory send OK.")
+		case "QUIT":
+			tp.PrintfLine("221 Goodbye.")
+			return
+		default:
+			tp.PrintfLine("502 Command not implemented.")
+		}
+	}
+}
+
+// serveSFTP returns an SSH server handler accepting monitor/s3cret and
+// serving the SFTP subsystem rooted at dir, along with its host key
+func serveSFTP(t *testing.T, dir string) (func(net.Conn), ssh.PublicKey) {
+	t.Helper()
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate host key: %v", err)
+	}
+	signer, err := ssh.NewSignerFromKey(key)
+	if err != nil {
+		t.Fatalf("Failed to create signer: %v", err)
+	}
+	cfg := &ssh.ServerConfig{
+		ServerVersion: "SSH-2.0-OpenSSH_9.6",
+		PasswordCallback: func(meta ssh.ConnMetadata, password []byte) (*ssh.Permissions, error) {
+			if meta.User() == "monitor" && string(password) == "s3cret" {
+				return nil, nil
+			}
+			return nil, fmt.Errorf("password rejected for %s", meta.User())
+		},
+	}
+	cfg.AddHostKey(signer)
+
+	handle := func(conn net.Conn) {
+		_, chans, reqs, err := ssh.NewServerConn(conn, cfg)
+		if err != nil {
+			return
+		}
+		go ssh.DiscardRequests(reqs)
+		for newChan := range chans {
+			if newChan.ChannelType() != "session" {
+				newChan.Reject(ssh.UnknownChannelType, "unsupported")
+				continue
+			}
+			ch, requests, err := newChan.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				for req := range requests {
+					ok := req.Type == "subsystem" && string(req.Payload[4:]) == "sftp"
+					req.Reply(ok, nil)
+					if !ok {
+						continue
+					}
+					server, err := sftp.NewServer(ch, sftp.WithServerWorkingDirectory(dir))
+					if err != nil {
+						ch.Close()
+						return
+					}
+					server.Serve()
+					server.Close()
+				}
+			}()
+		}
+	}
+	return handle, signer.PublicKey()
+}
+
+func TestChecker_Check_Services(t *testing.T) {
+	pki := newTestPKI(t, "")
+	cert := tls.Certificate{Certificate: [][]byte{pki.leaf.Raw, pki.caCert.Raw}, PrivateKey: pki.leafKey}
+
+	dir := t.TempDir()
+	for _, name := range []string{"a.txt", "b.txt", "c.txt"} {
+		if err := os.WriteFile(dir+"/"+name, []byte(name), 0o600); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+	}
+	sftpHandler, hostKey := serveSFTP(t, dir)
+
+	smtpAddr := listen(t, serveSMTP(cert))
+	imapAddr := listen(t, serveIMAP)
+	ftpAddr := listen(t, serveFTP(cert, nil))
+	sftpAddr := listen(t, sftpHandler)
+
+	t.Setenv("OCTO_TEST_SERVICE_PASSWORD", "s3cret")
+	t.Setenv("OCTO_TEST_SERVICE_WRONG", "nope")
+
+	tests := []struct {
+		name       string
+		checkType  string
+		addr       string
+		svc        config.ServiceConfig
+		wantBanner string
+		wantValue  string
+		wantCert   bool
+		wantErr    string
+	}{
+		{name: "smtp banner", checkType: TypeSMTP, addr: smtpAddr,
+			svc: config.ServiceConfig{Expect: `ESMTP Postfix`}, wantBanner: "mail.example.com ESMTP Postfix"},
+		{name: "smtp starttls", checkType: TypeSMTP, addr: "smtp://" + smtpAddr,
+			svc: config.ServiceConfig{StartTLS: true}, wantBanner: "mail.example.com", wantCert: true},
+		{name: "smtp expect mismatch", checkType: TypeSMTP, addr: smtpAddr,
+			svc: config.ServiceConfig{Expect: `Exim`}, wantErr: `banner does not match "Exim"`},
+		{name: "smtp refused", checkType: TypeSMTP, addr: "127.0.0.1:1", wantErr: "connection failed"},
+		{name: "imap greeting", checkType: TypeIMAP, addr: imapAddr, wantBanner: "* OK [CAPABILITY IMAP4rev1"},
+		{name: "imap login", checkType: TypeIMAP, addr: imapAddr,
+			svc: config.ServiceConfig{Username: "monitor", Password: "env:OCTO_TEST_SERVICE_PASSWORD"}, wantBanner: "* OK"},
+		{name: "imap wrong password", checkType: TypeIMAP, addr: imapAddr,
+			svc: config.ServiceConfig{Username: "monitor", Password: "env:OCTO_TEST_SERVICE_WRONG"}, wantErr: "login failed: NO [AUTHENTICATIONFAILED]"},
+		{name: "ftp list", checkType: TypeFTP, addr: "ftp://" + ftpAddr,
+			svc: config.ServiceConfig{Username: "monitor", Password: "env:OCTO_TEST_SERVICE_PASSWORD"}, wantValue: "2 entries"},
+		{name: "ftps explicit", checkType: TypeFTP, addr: ftpAddr,
+			svc: config.ServiceConfig{Username: "monitor", Password: "env:OCTO_TEST_SERVICE_PASSWORD", StartTLS: true}, wantValue: "2 entries", wantCert: true},
+		{name: "ftp wrong password", checkType: TypeFTP, addr: ftpAddr,
+			svc: config.ServiceConfig{Username: "monitor", Password: "env:OCTO_TEST_SERVICE_WRONG"}, wantErr: "login failed: 530"},
+		{name: "sftp list", checkType: TypeSFTP, addr: sftpAddr,
+			svc:        config.ServiceConfig{Username: "monitor", Password: "env:OCTO_TEST_SERVICE_PASSWORD", HostKey: ssh.FingerprintSHA256(hostKey)},
+			wantBanner: "SSH-2.0-OpenSSH_9.6", wantValue: "3 entries"},
+		{name: "sftp host key mismatch", checkType: TypeSFTP, addr: sftpAddr,
+			svc:     config.ServiceConfig{Username: "monitor", Password: "env:OCTO_TEST_SERVICE_PASSWORD", HostKey: "SHA256:AAAA"},
+			wantErr: "does not match SHA256:AAAA"},
+		{name: "sftp host key not set", checkType: TypeSFTP, addr: sftpAddr,
+			svc:     config.ServiceConfig{Username: "monitor", Password: "env:OCTO_TEST_SERVICE_PASSWORD"},
+			wantErr: "host key " + ssh.FingerprintSHA256(hostKey) + " is not trusted"},
+		{name: "sftp wrong password", checkType: TypeSFTP, addr: sftpAddr,
+			svc:     config.ServiceConfig{Username: "monitor", Password: "env:OCTO_TEST_SERVICE_WRONG", HostKey: ssh.FingerprintSHA256(hostKey)},
+			wantErr: "ssh login failed: ssh: handshake failed: ssh: unable to authenticate"},
+	}
+
+	c := newInsecureChecker()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			result := c.Check(ctx, config.EndpointConfig{ID: "svc", Type: tt.checkType, URL: tt.addr, Service: tt.svc})
+			if tt.wantErr != "" {
+				if result.Success || !strings.Contains(result.Error, tt.wantErr) {
+					t.Errorf("Expected error containing %q, got success=%v error=%q", tt.wantErr, result.Success, result.Error)
+				}
+				return
+			}
+			if !result.Success {
+				t.Fatalf("Expected success, got failure: %s", result.Error)
+			}
+			if !strings.Contains(result.Banner, tt.wantBanner) {
+				t.Errorf("Expected banner containing %q, got %q", tt.wantBanner, result.Banner)
+			}
+			if result.QueryResult != tt.wantValue {
+				t.Errorf("Expected listing %q, got %q", tt.wantValue, result.QueryResult)
+			}
+			if tt.wantCert {
+				if result.CertExpiry.IsZero() || !result.CertExpiry.Equal(pki.leaf.NotAfter) {
+					t.Errorf("Expected cert expiry %v, got %v", pki.leaf.NotAfter, result.CertExpiry)
+				}
+				// The FTP library handshakes lazily, so only the certificate is captured
+				if tt.checkType != TypeFTP && result.TLSDuration == 0 {
+					t.Error("Expected TLS handshake time to be recorded")
+				}
+			}
+		})
+	}
+}
+
+func TestChecker_Check_FTPSRejectedBeforeLogin(t *testing.T) {
+	pki := newTestPKI(t, "")
+	cert := tls.Certificate{Certificate: [][]byte{pki.leaf.Raw, pki.caCert.Raw}, PrivateKey: pki.leafKey}
+	tlsConfig := &tls.Config{Certificates: []tls.Certificate{cert}}
+
+	var logins atomic.Int32
+	explicitAddr := listen(t, serveFTP(cert, &logins))
+	implicitAddr := listen(t, func(conn net.Conn) {
+		serveFTPConn(tls.Server(conn, tlsConfig), tlsConfig, &logins)
+	})
+	t.Setenv("OCTO_TEST_SERVICE_PASSWORD", "s3cret")
+
+	tests := []struct {
+		name string
+		addr string
+		svc  config.ServiceConfig
+	}{
+		{name: "explicit", addr: explicitAddr, svc: config.ServiceConfig{StartTLS: true}},
+		{name: "implicit", addr: "ftps://" + implicitAddr},
+	}
+
+	c := newInsecureChecker()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			tt.svc.Username, tt.svc.Password = "monitor", "env:OCTO_TEST_SERVICE_PASSWORD"
+			result := c.Check(ctx, config.EndpointConfig{
+				ID: "ftps", Type: TypeFTP, URL: tt.addr, Service: tt.svc,
+				SSL: config.SSLConfig{AllowedIssuers: []string{"Other CA"}},
+			})
+			if result.Success || !result.CertMismatch || !strings.Contains(result.Error, "unexpected certificate") {
+				t.Errorf("Expected the issuer to fail the check, got success=%v error=%q", result.Success, result.Error)
+			}
+			if n := logins.Load(); n != 0 {
+				t.Errorf("Expected no password to be sent, got %d", n)
+			}
+		})
+	}
+}
+
+func TestChecker_Check_FTPSilentServer(t *testing.T) {
+	done := make(chan struct{})
+	t.Cleanup(func() { close(done) })
+	addr := listen(t, func(conn net.Conn) { <-done })
+
+	c := newInsecureChecker()
+	for _, url := range []string{addr, "ftps://" + addr} {
+		t.Run(url, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+			defer cancel()
+
+			start := time.Now()
+			result := c.Check(ctx, config.EndpointConfig{ID: "ftp", Type: TypeFTP, URL: url})
+			if result.Success {
+				t.Fatal("Expected a server that never greets to fail the check")
+			}
+			if elapsed := time.Since(start); elapsed > 2*time.Second {
+				t.Errorf("Expected the check to end at its deadline, took %v", elapsed)
+			}
+		})
+	}
+}
@@ -0,0 +1,201 @@
+package checker
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/textproto"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/manu/octo/pkg/config"
+)
+
+// maxBanner bounds the greeting recorded with a check
+const maxBanner = 512
+
+// serviceTarget holds the resolved settings shared by the SMTP, IMAP, FTP
+// and SFTP check types
+type serviceTarget struct {
+	address  string
+	host     string
+	useTLS   bool
+	username string
+	password string
+	expect   *regexp.Regexp
+}
+
+// resolveServiceTarget applies the default ports and reads the credentials.
+// A scheme ending in "s" (smtps://, imaps://, ftps://) implies TLS.
+func resolveServiceTarget(endpoint config.EndpointConfig, plainPort, tlsPort string) (serviceTarget, error) {
+	svc := endpoint.Service
+	t := serviceTarget{useTLS: svc.TLS}
+	if scheme, _, ok := strings.Cut(endpoint.URL, "://"); ok && strings.HasSuffix(scheme, "s") && tlsPort != "" {
+		t.useTLS = true
+	}
+
+	port := plainPort
+	if t.useTLS {
+		port = tlsPort
+	}
+	var err error
+	if t.address, err = targetAddress(endpoint.URL, port); err != nil {
+		return t, err
+	}
+	t.host, _, _ = net.SplitHostPort(t.address)
+
+	if t.username, err = resolveSecret(svc.Username, true); err != nil {
+		return t, fmt.Errorf("invalid username: %w", err)
+	}
+	if t.password, err = resolveSecret(svc.Password, false); err != nil {
+		return t, fmt.Errorf("invalid password: %w", err)
+	}
+	if svc.Expect != "" {
+		if t.expect, err = regexp.Compile(svc.Expect); err != nil {
+			return t, fmt.Errorf("invalid expect regex: %w", err)
+		}
+	}
+	return t, nil
+}
+
+// dialService connects to the target, performing the TLS handshake right
+// away for implicit TLS. The connection inherits the context deadline.
+func (c *Checker) dialService(ctx context.Context, result *Result, t serviceTarget) (net.Conn, error) {
+	start := time.Now()
+	conn, err := dialerFrom(ctx).DialContext(ctx, "tcp", t.address)
+	result.ConnDuration = time.Since(start)
+	if err != nil {
+		return nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	if !t.useTLS {
+		return conn, nil
+	}
+
+	tlsConn, err := c.handshake(ctx, result, conn, t.host)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return tlsConn, nil
+}
+
+// handshake upgrades conn to TLS, recording the handshake time
+func (c *Checker) handshake(ctx context.Context, result *Result, conn net.Conn, host string) (*tls.Conn, error) {
+	start := time.Now()
+	tlsConn := tls.Client(conn, c.tlsConfig(host))
+	err := tlsConn.HandshakeContext(ctx)
+	result.TLSDuration = time.Since(start)
+	if err != nil {
+		return nil, fmt.Errorf("tls handshake failed: %w", err)
+	}
+	return tlsConn, nil
+}
+
+func setBanner(result *Result, banner string) {
+	if len(banner) > maxBanner {
+		banner = banner[:maxBanner]
+	}
+	result.Banner = banner
+}
+
+// checkSMTP reads the greeting, sends EHLO and optionally upgrades with
+// STARTTLS. The certificate feeds the same tracking as HTTPS checks.
+func (c *Checker) checkSMTP(ctx context.Context, endpoint config.EndpointConfig) Result {
+	result := newResult(endpoint)
+
+	t, err := resolveServiceTarget(endpoint, "25", "465")
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	start := time.Now()
+	conn, err := c.dialService(ctx, &result, t)
+	if err != nil {
+		result.Duration = time.Since(start)
+		result.Error = "connection failed: " + err.Error()
+		return result
+	}
+	defer conn.Close()
+
+	fail := func(format string, args ...any) Result {
+		result.Duration = time.Since(start)
+		result.Error = fmt.Sprintf(format, args...)
+		return result
+	}
+
+	tp := textproto.NewConn(conn)
+	_, greeting, err := tp.ReadResponse(220)
+	result.TTFB = time.Since(start)
+	if err != nil {
+		return fail("unexpected greeting: %v", err)
+	}
+
+	ehlo, err := smtpCmd(tp, 250, "EHLO %s", "octo.localhost")
+	if err != nil {
+		return fail("EHLO failed: %v", err)
+	}
+	setBanner(&result, greeting+"\n"+ehlo)
+
+	if endpoint.Service.StartTLS && !t.useTLS {
+		if !smtpHasExtension(ehlo, "STARTTLS") {
+			return fail("server does not offer STARTTLS")
+		}
+		if _, err := smtpCmd(tp, 220, "STARTTLS"); err != nil {
+			return fail("STARTTLS failed: %v", err)
+		}
+		tlsConn, err := c.handshake(ctx, &result, conn, t.host)
+		if err != nil {
+			return fail("%v", err)
+		}
+		conn = tlsConn
+		tp = textproto.NewConn(conn)
+		if _, err := smtpCmd(tp, 250, "EHLO %s", "octo.localhost"); err != nil {
+			return fail("EHLO after STARTTLS failed: %v", err)
+		}
+	}
+
+	if tlsConn, ok := conn.(*tls.Conn); ok {
+		state := tlsConn.ConnectionState()
+		if err := c.inspectTLS(ctx, &result, endpoint.SSL, t.host, &state); err != nil {
+			return fail("%v", err)
+		}
+	}
+
+	smtpCmd(tp, 221, "QUIT")
+	result.Duration = time.Since(start)
+
+	if t.expect != nil && !t.expect.MatchString(result.Banner) {
+		result.Error = fmt.Sprintf("banner does not match %q", t.expect.String())
+		return result
+	}
+
+	result.Success = true
+	return result
+}
+
+func smtpCmd(tp *textproto.Conn, expectCode int, format string, args ...any) (string, error) {
+	id, err := tp.Cmd(format, args...)
+	if err != nil {
+		return "", err
+	}
+	tp.StartResponse(id)
+	defer tp.EndResponse(id)
+	_, msg, err := tp.ReadResponse(expectCode)
+	return msg, err
+}
+
+// smtpHasExtension reports whether an EHLO response advertises ext
+func smtpHasExtension(ehlo, ext string) bool {
+	for _, line := range strings.Split(ehlo, "\n") {
+		if fields := strings.Fields(line); len(fields) > 0 && strings.EqualFold(fields[0], ext) {
+			return true
+		}
+	}
+	return false
+}
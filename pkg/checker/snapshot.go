@@ -0,0 +1,82 @@
+package checker
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/manu/octo/pkg/config"
+)
+
+const (
+	// defaultSnapshotBody is the number of body bytes kept when a check fails
+	defaultSnapshotBody = 4 << 10
+	// maxSnapshotBody caps max_body so failures cannot bloat storage
+	maxSnapshotBody = 64 << 10
+
+	redactedValue = "[REDACTED]"
+)
+
+// defaultRedactedHeaders are never stored in a snapshot, in canonical form
+var defaultRedactedHeaders = []string{
+	"Authorization",
+	"Proxy-Authorization",
+	"Cookie",
+	"Set-Cookie",
+	"X-Api-Key",
+	"X-Auth-Token",
+}
+
+// Snapshot is what a server returned for a failed check: the address
+// connected to, the response headers with sensitive values redacted, and
+// the start of the body
+type Snapshot struct {
+	RemoteIP  string              `json:"remote_ip,omitempty"`
+	Headers   map[string][]string `json:"headers,omitempty"`
+	Body      string              `json:"body,omitempty"`
+	Truncated bool                `json:"truncated,omitempty"`
+}
+
+// newSnapshot builds the snapshot of a failed response. The body is the one
+// already read, or when nil the start of unread is read. Either may be nil.
+func newSnapshot(cfg config.SnapshotConfig, remoteAddr string, header http.Header, body []byte, unread io.Reader) *Snapshot {
+	limit := cfg.MaxBody
+	if limit <= 0 {
+		limit = defaultSnapshotBody
+	}
+	limit = min(limit, maxSnapshotBody)
+
+	s := &Snapshot{}
+	if host, _, err := net.SplitHostPort(remoteAddr); err == nil {
+		s.RemoteIP = host
+	}
+
+	if header != nil {
+		redacted := make(map[string]bool)
+		for _, name := range append(defaultRedactedHeaders, cfg.RedactHeaders...) {
+			redacted[http.CanonicalHeaderKey(name)] = true
+		}
+		s.Headers = make(map[string][]string, len(header))
+		for name, values := range header {
+			if redacted[http.CanonicalHeaderKey(name)] {
+				values = []string{redactedValue}
+			}
+			s.Headers[name] = values
+		}
+	}
+
+	if body == nil && unread != nil {
+		body, _ = io.ReadAll(io.LimitReader(unread, int64(limit)+1))
+	}
+	if len(body) > limit {
+		body, s.Truncated = body[:limit], true
+	}
+	// Stored as JSON, which must be valid UTF-8 and, in PostgreSQL, free of NUL
+	s.Body = strings.ReplaceAll(strings.ToValidUTF8(string(body), "\uFFFD"), "\x00", "\uFFFD")
+
+	if s.RemoteIP == "" && s.Headers == nil && s.Body == "" {
+		return nil
+	}
+	return s
+}
@@ -0,0 +1,162 @@
+package checker
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/manu/octo/pkg/config"
+)
+
+// sseEvent is one dispatched Server-Sent Event
+type sseEvent struct {
+	Type string
+	Data string
+}
+
+// checkSSE subscribes to an event stream and waits for a matching event.
+// ConnDuration covers the request up to the response headers and TTFB the
+// wait for the first event.
+func (c *Checker) checkSSE(ctx context.Context, endpoint config.EndpointConfig) Result {
+	result := newResult(endpoint)
+	result.Method = http.MethodGet
+
+	var expect *regexp.Regexp
+	if endpoint.SSE.Expect != "" {
+		var err error
+		expect, err = regexp.Compile(endpoint.SSE.Expect)
+		if err != nil {
+			result.Error = "invalid expect regex: " + err.Error()
+			return result
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint.URL, nil)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	req.Header.Set("Cache-Control", "no-cache")
+	for k, v := range endpoint.Headers {
+		req.Header.Add(k, v)
+	}
+
+	client, release, err := c.httpClient(ctx, endpoint)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	defer release()
+
+	recordProxy(&result, dialerFrom(ctx), req.URL)
+	start := time.Now()
+	resp, err := client.Do(req)
+	result.ConnDuration = time.Since(start)
+	if err != nil {
+		result.Duration = time.Since(start)
+		result.Error = err.Error()
+		return result
+	}
+	defer resp.Body.Close()
+
+	result.StatusCode = resp.StatusCode
+	if resp.Request.URL != req.URL {
+		recordProxy(&result, dialerFrom(ctx), resp.Request.URL)
+	}
+
+	if resp.TLS != nil {
+		if err := c.inspectTLS(ctx, &result, endpoint.SSL, resp.Request.URL.Hostname(), resp.TLS); err != nil {
+			result.Error = err.Error()
+			return result
+		}
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		result.Duration = time.Since(start)
+		result.Error = fmt.Sprintf("unexpected status code %d", resp.StatusCode)
+		return result
+	}
+	if mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type")); mediaType != "text/event-stream" {
+		result.Duration = time.Since(start)
+		result.Error = fmt.Sprintf("unexpected content type %q", resp.Header.Get("Content-Type"))
+		return result
+	}
+
+	subscribed := time.Now()
+	n, err := readSSE(resp.Body, func(ev sseEvent) bool {
+		if result.TTFB == 0 {
+			result.TTFB = time.Since(subscribed)
+		}
+		if endpoint.SSE.Event != "" && ev.Type != endpoint.SSE.Event {
+			return false
+		}
+		return expect == nil || expect.MatchString(ev.Data)
+	})
+	result.BytesReceived = n
+	result.Duration = time.Since(start)
+
+	if err != nil {
+		if ctx.Err() != nil {
+			err = ctx.Err()
+		}
+		result.Error = fmt.Sprintf("expected event not received: %v", err)
+		return result
+	}
+
+	result.Success = true
+	return result
+}
+
+// readSSE parses an event stream, calling onEvent for each dispatched event
+// until it returns true. It returns the number of bytes consumed.
+func readSSE(r io.Reader, onEvent func(sseEvent) bool) (int64, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 4096), maxMessageSize)
+
+	var n int64
+	var eventType string
+	var data []string
+	for scanner.Scan() {
+		line := scanner.Text()
+		n += int64(len(line)) + 1
+
+		if line == "" {
+			// Events without data are not dispatched
+			if len(data) > 0 {
+				ev := sseEvent{Type: eventType, Data: strings.Join(data, "\n")}
+				if ev.Type == "" {
+					ev.Type = "message"
+				}
+				if onEvent(ev) {
+					return n, nil
+				}
+			}
+			eventType, data = "", nil
+			continue
+		}
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "event":
+			eventType = value
+		case "data":
+			data = append(data, value)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return n, err
+	}
+	return n, io.ErrUnexpectedEOF
+}
@@ -0,0 +1,291 @@
+package checker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/manu/octo/pkg/config"
+)
+
+// StepResult holds the outcome of one step of a multi-step check
+type StepResult struct {
+	Name          string        `json:"name"`
+	URL           string        `json:"url"`
+	Method        string        `json:"method"`
+	StatusCode    int           `json:"status_code"`
+	Duration      time.Duration `json:"duration"`
+	DNSDuration   time.Duration `json:"dns_duration"`
+	ConnDuration  time.Duration `json:"conn_duration"`
+	TLSDuration   time.Duration `json:"tls_duration"`
+	TTFB          time.Duration `json:"ttfb"`
+	BytesReceived int64         `json:"bytes_received"`
+	Success       bool          `json:"success"`
+	Error         string        `json:"error,omitempty"`
+}
+
+// checkSteps runs the steps of a transaction in order, feeding extracted
+// values into later steps, and stops at the first failing step. Timings and
+// bytes are summed over the executed steps; TLS details come from the first
+// step that negotiated TLS.
+func (c *Checker) checkSteps(ctx context.Context, client *http.Client, endpoint config.EndpointConfig) Result {
+	result := newResult(endpoint)
+	vars := make(map[string]string)
+	tlsSeen := false
+
+	for i, step := range endpoint.Steps {
+		name := step.Name
+		if name == "" {
+			name = fmt.Sprintf("step %d", i+1)
+		}
+
+		sr := StepResult{Name: name, URL: step.URL, Method: step.Method}
+		stepEndpoint, err := renderStep(endpoint, step, vars)
+		if err == nil {
+			r, header, body := c.doHTTP(ctx, client, stepEndpoint)
+			sr = newStepResult(name, r)
+			result.Snapshot = r.Snapshot
+			if r.Success {
+				if err = extractValues(step.Extract, header, body, vars); err != nil {
+					result.Snapshot = newSnapshot(endpoint.Snapshot, "", header, body, nil)
+				}
+			}
+
+			result.StatusCode = r.StatusCode
+			result.Duration += r.Duration
+			result.DNSDuration += r.DNSDuration
+			result.ConnDuration += r.ConnDuration
+			result.TLSDuration += r.TLSDuration
+			result.WriteDuration += r.WriteDuration
+			result.TTFB += r.TTFB
+			result.TransferDuration += r.TransferDuration
+			result.BytesReceived += r.BytesReceived
+			for name, value := range r.Metrics {
+				if result.Metrics == nil {
+					result.Metrics = make(map[string]float64)
+				}
+				result.Metrics[name] = value
+			}
+			if !tlsSeen && r.TLSVersion != "" {
+				copyTLSInfo(&result, r)
+				tlsSeen = true
+			}
+		}
+		if err != nil {
+			sr.Success = false
+			sr.Error = err.Error()
+		}
+		result.Steps = append(result.Steps, sr)
+
+		if !sr.Success {
+			result.FailedStep = name
+			result.Error = fmt.Sprintf("step %q failed: %s", name, sr.Error)
+			return result
+		}
+	}
+
+	result.Success = true
+	return result
+}
+
+func newStepResult(name string, r Result) StepResult {
+	return StepResult{
+		Name:          name,
+		URL:           r.URL,
+		Method:        r.Method,
+		StatusCode:    r.StatusCode,
+		Duration:      r.Duration,
+		DNSDuration:   r.DNSDuration,
+		ConnDuration:  r.ConnDuration,
+		TLSDuration:   r.TLSDuration,
+		TTFB:          r.TTFB,
+		BytesReceived: r.BytesReceived,
+		Success:       r.Success,
+		Error:         r.Error,
+	}
+}
+
+// copyTLSInfo carries the connection and certificate details of a step
+// over to the transaction result
+func copyTLSInfo(dst *Result, src Result) {
+	dst.CertExpiry = src.CertExpiry
+	dst.CertIssuer = src.CertIssuer
+	dst.CertSubject = src.CertSubject
+	dst.CertNotBefore = src.CertNotBefore
+	dst.CertNotAfter = src.CertNotAfter
+	dst.TLSVersion = src.TLSVersion
+	dst.CipherSuite = src.CipherSuite
+	dst.ALPN = src.ALPN
+	dst.CertSANs = src.CertSANs
+	dst.CertKeyType = src.CertKeyType
+	dst.CertKeySize = src.CertKeySize
+	dst.CertFingerprint = src.CertFingerprint
+	dst.CertChain = src.CertChain
+	dst.OCSPStatus = src.OCSPStatus
+	dst.OCSPStapled = src.OCSPStapled
+	dst.OCSPError = src.OCSPError
+}
+
+// renderStep builds the request of a step, expanding the templates in its
+// URL, headers and body with the variables extracted so far
+func renderStep(endpoint config.EndpointConfig, step config.StepConfig, vars map[string]string) (config.EndpointConfig, error) {
+	url, err := renderTemplate("url", step.URL, vars)
+	if err != nil {
+		return config.EndpointConfig{}, err
+	}
+	if err := checkStepOrigin(step.URL, url); err != nil {
+		return config.EndpointConfig{}, err
+	}
+	body, err := renderTemplate("body", step.Body, vars)
+	if err != nil {
+		return config.EndpointConfig{}, err
+	}
+
+	headers := make(map[string]string, len(endpoint.Headers)+len(step.Headers))
+	for k, v := range endpoint.Headers {
+		headers[k] = v
+	}
+	for k, v := range step.Headers {
+		rendered, err := renderTemplate("header "+k, v, vars)
+		if err != nil {
+			return config.EndpointConfig{}, err
+		}
+		headers[k] = rendered
+	}
+
+	method := step.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+
+	return config.EndpointConfig{
+		ID:           endpoint.ID,
+		Type:         TypeHTTP,
+		URL:          url,
+		Method:       method,
+		Headers:      headers,
+		Body:         body,
+		Validation:   step.Validation,
+		SSL:          endpoint.SSL,
+		Snapshot:     endpoint.Snapshot,
+		MaxBodyBytes: endpoint.MaxBodyBytes,
+	}, nil
+}
+
+// stepFuncs escape extracted values for the part of a URL they go into:
+// {{ pathescape .id }} for a path segment and {{ urlquery .q }} (built in)
+// for a query value. Values are otherwise inserted verbatim.
+var stepFuncs = template.FuncMap{
+	"pathescape": url.PathEscape,
+}
+
+func renderTemplate(name, text string, vars map[string]string) (string, error) {
+	if !strings.Contains(text, "{{") {
+		return text, nil
+	}
+	tmpl, err := template.New(name).Funcs(stepFuncs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("invalid template in %s: %w", name, err)
+	}
+	var sb strings.Builder
+	if err := tmpl.Execute(&sb, vars); err != nil {
+		return "", fmt.Errorf("failed to render %s: %w", name, err)
+	}
+	return sb.String(), nil
+}
+
+// checkStepOrigin refuses a rendered step URL whose scheme or host differs
+// from the ones its template spells out literally, so an extracted value
+// cannot send the step, and the endpoint headers, to another server. Only
+// the port may follow the literal host as a variable, unless the template
+// takes the whole host from one, e.g. "https://{{ .host }}/orders".
+func checkStepOrigin(text, rendered string) error {
+	literal, _, templated := strings.Cut(text, "{{")
+	if !templated {
+		return nil
+	}
+	scheme, rest, ok := strings.Cut(literal, "://")
+	if !ok {
+		return nil
+	}
+	authority, terminated := rest, false
+	if end := strings.IndexAny(rest, "/?#"); end >= 0 {
+		authority, terminated = rest[:end], true
+	}
+	if i := strings.LastIndex(authority, "@"); i >= 0 {
+		authority = authority[i+1:]
+	}
+	if authority == "" {
+		return nil
+	}
+
+	u, err := url.Parse(rendered)
+	if err != nil {
+		return fmt.Errorf("invalid url: %w", err)
+	}
+	want, got := scheme+"://"+authority, u.Scheme+"://"+u.Host
+	if !terminated {
+		host := authority
+		if i := strings.LastIndex(host, ":"); i > strings.LastIndex(host, "]") {
+			host = host[:i]
+		}
+		want, got = scheme+"://"+strings.Trim(host, "[]"), u.Scheme+"://"+u.Hostname()
+	}
+	if !strings.EqualFold(got, want) {
+		return fmt.Errorf("url template leads to %s instead of %s", got, want)
+	}
+	return nil
+}
+
+// extractValues stores the values captured from a step response in vars
+func extractValues(extractions []config.Extraction, header http.Header, body []byte, vars map[string]string) error {
+	var doc any
+	decoded := false
+
+	for _, ex := range extractions {
+		var value string
+		switch {
+		case ex.JSONPath != "":
+			if !decoded {
+				if err := json.Unmarshal(body, &doc); err != nil {
+					return fmt.Errorf("cannot extract %s: response is not valid JSON: %w", ex.Name, err)
+				}
+				decoded = true
+			}
+			v, err := lookupJSONPath(doc, ex.JSONPath)
+			if err != nil {
+				return fmt.Errorf("cannot extract %s: %w", ex.Name, err)
+			}
+			value = jsonString(v)
+		case ex.Regex != "":
+			re, err := regexp.Compile(ex.Regex)
+			if err != nil {
+				return fmt.Errorf("cannot extract %s: invalid regex: %w", ex.Name, err)
+			}
+			m := re.FindSubmatch(body)
+			if m == nil {
+				return fmt.Errorf("cannot extract %s: no match for %q", ex.Name, ex.Regex)
+			}
+			value = string(m[0])
+			if len(m) > 1 {
+				value = string(m[1])
+			}
+		case ex.Header != "":
+			value = header.Get(ex.Header)
+			if value == "" {
+				return fmt.Errorf("cannot extract %s: header %s not present", ex.Name, ex.Header)
+			}
+		default:
+			return fmt.Errorf("extraction %s has no json_path, regex or header", ex.Name)
+		}
+		vars[ex.Name] = value
+	}
+
+	return nil
+}
@@ -0,0 +1,178 @@
+package checker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/manu/octo/pkg/config"
+)
+
+// newShopServer issues a token on POST /login and serves /orders only to
+// requests presenting it
+func newShopServer() *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /login", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if !strings.Contains(string(body), `"user":"monitor"`) {
+			http.Error(w, "bad credentials", http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("X-Session", "sess-42")
+		json.NewEncoder(w).Encode(map[string]any{"data": map[string]any{"token": "tok-123"}})
+	})
+	mux.HandleFunc("GET /orders/{session}", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer tok-123" || r.PathValue("session") != "sess-42" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprint(w, `{"orders":[{"id":7,"status":"shipped"}]}`)
+	})
+	return httptest.NewServer(mux)
+}
+
+// newSessionServer hands out the given session and serves the orders of
+// that session only
+func newSessionServer(session string) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /login", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Session", session)
+		w.Header().Set("X-Token", "tok-123")
+	})
+	mux.HandleFunc("GET /orders/{session}", func(w http.ResponseWriter, r *http.Request) {
+		if r.PathValue("session") != session || r.URL.RawQuery != "" {
+			http.Error(w, "unknown session", http.StatusNotFound)
+			return
+		}
+		fmt.Fprint(w, `{"orders":[{"id":7,"status":"shipped"}]}`)
+	})
+	return httptest.NewServer(mux)
+}
+
+func TestChecker_Check_Steps(t *testing.T) {
+	ts := newShopServer()
+	defer ts.Close()
+
+	login := config.StepConfig{
+		Name:   "login",
+		URL:    ts.URL + "/login",
+		Method: http.MethodPost,
+		Body:   `{"user":"monitor"}`,
+		Extract: []config.Extraction{
+			{Name: "token", JSONPath: "$.data.token"},
+			{Name: "session", Header: "X-Session"},
+		},
+	}
+	orders := config.StepConfig{
+		Name:    "orders",
+		URL:     ts.URL + "/orders/{{ .session }}",
+		Headers: map[string]string{"Authorization": "Bearer {{ .token }}"},
+		Validation: config.ValidationConfig{
+			JSONAssertions: []string{`$.orders[0].status == "shipped"`},
+		},
+		Extract: []config.Extraction{{Name: "order_id", Regex: `"id":(\d+)`}},
+	}
+
+	c := NewChecker()
+	result := c.Check(context.Background(), config.EndpointConfig{ID: "shop", Steps: []config.StepConfig{login, orders}})
+	if !result.Success {
+		t.Fatalf("Expected success, got failure: %s", result.Error)
+	}
+	if len(result.Steps) != 2 || !result.Steps[0].Success || !result.Steps[1].Success {
+		t.Fatalf("Expected two successful steps, got %+v", result.Steps)
+	}
+	if result.Duration != result.Steps[0].Duration+result.Steps[1].Duration {
+		t.Error("Expected total duration to be the sum of the steps")
+	}
+
+	t.Run("failing validation", func(t *testing.T) {
+		bad := orders
+		bad.Validation = config.ValidationConfig{JSONAssertions: []string{`$.orders.length == 2`}}
+
+		result := c.Check(context.Background(), config.EndpointConfig{ID: "shop", Steps: []config.StepConfig{login, bad}})
+		if result.Success || result.FailedStep != "orders" {
+			t.Fatalf("Expected orders step to fail, got success=%v failed_step=%q", result.Success, result.FailedStep)
+		}
+		if !strings.HasPrefix(result.Error, `step "orders" failed: `) {
+			t.Errorf("Unexpected error %q", result.Error)
+		}
+	})
+
+	t.Run("failing extraction", func(t *testing.T) {
+		bad := login
+		bad.Extract = []config.Extraction{{Name: "token", JSONPath: "$.token"}}
+
+		result := c.Check(context.Background(), config.EndpointConfig{ID: "shop", Steps: []config.StepConfig{bad, orders}})
+		if result.Success || result.FailedStep != "login" || len(result.Steps) != 1 {
+			t.Fatalf("Expected login step to fail, got success=%v failed_step=%q steps=%d", result.Success, result.FailedStep, len(result.Steps))
+		}
+		if !strings.Contains(result.Error, "cannot extract token") {
+			t.Errorf("Unexpected error %q", result.Error)
+		}
+	})
+
+	t.Run("escaped value", func(t *testing.T) {
+		// The extracted session would otherwise turn the path into a query
+		server := newSessionServer("sess/42?admin=1")
+		defer server.Close()
+		step := orders
+		step.URL = server.URL + "/orders/{{ pathescape .session }}"
+
+		result := c.Check(context.Background(), config.EndpointConfig{ID: "shop", Steps: []config.StepConfig{
+			{Name: "login", URL: server.URL + "/login", Extract: []config.Extraction{{Name: "session", Header: "X-Session"}, {Name: "token", Header: "X-Token"}}},
+			step,
+		}})
+		if !result.Success {
+			t.Fatalf("Expected the escaped session to reach the path, got failure: %s", result.Error)
+		}
+	})
+
+	t.Run("host change", func(t *testing.T) {
+		server := newSessionServer("@evil.example/")
+		defer server.Close()
+		step := orders
+		step.URL = server.URL + "{{ .session }}orders/x"
+
+		result := c.Check(context.Background(), config.EndpointConfig{ID: "shop", Steps: []config.StepConfig{
+			{Name: "login", URL: server.URL + "/login", Extract: []config.Extraction{{Name: "session", Header: "X-Session"}, {Name: "token", Header: "X-Token"}}},
+			step,
+		}})
+		if result.Success || !strings.Contains(result.Error, "url template leads to") {
+			t.Errorf("Expected the host change to be refused, got success=%v error=%q", result.Success, result.Error)
+		}
+	})
+
+	t.Run("undefined variable", func(t *testing.T) {
+		result := c.Check(context.Background(), config.EndpointConfig{ID: "shop", Steps: []config.StepConfig{orders}})
+		if result.Success || result.FailedStep != "orders" || !strings.Contains(result.Error, `no entry for key "session"`) {
+			t.Errorf("Expected template error, got success=%v error=%q", result.Success, result.Error)
+		}
+	})
+}
+
+func TestCheckStepOrigin(t *testing.T) {
+	tests := []struct {
+		text, rendered string
+		wantErr        bool
+	}{
+		{text: "https://api.example.com/orders/{{ .id }}", rendered: "https://api.example.com/orders/7"},
+		{text: "https://api.example.com:{{ .port }}/orders", rendered: "https://api.example.com:8443/orders"},
+		{text: "https://{{ .host }}/orders", rendered: "https://eu.example.com/orders"},
+		{text: "https://monitor@api.example.com/{{ .id }}", rendered: "https://monitor@api.example.com/7"},
+		{text: "https://api.example.com{{ .path }}", rendered: "https://api.example.com/orders"},
+		{text: "https://api.example.com{{ .path }}", rendered: "https://api.example.com@evil.example/", wantErr: true},
+		{text: "https://api.example.com{{ .path }}", rendered: "https://api.example.com.evil.example/", wantErr: true},
+		{text: "https://api.example.com:{{ .port }}/orders", rendered: "https://api.example.com:1@evil.example/orders", wantErr: true},
+	}
+	for _, tt := range tests {
+		err := checkStepOrigin(tt.text, tt.rendered)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("checkStepOrigin(%q, %q) = %v, want error %v", tt.text, tt.rendered, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package checker
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/manu/octo/pkg/config"
+)
+
+// maxBannerSize bounds how much of a TCP response is read while waiting for a match
+const maxBannerSize = 64 * 1024
+
+// checkTCP connects to the target and optionally exchanges a banner
+func (c *Checker) checkTCP(ctx context.Context, endpoint config.EndpointConfig) Result {
+	result := newResult(endpoint)
+
+	address, err := targetAddress(endpoint.URL, "")
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	start := time.Now()
+	conn, err := dialerFrom(ctx).DialContext(ctx, "tcp", address)
+	result.ConnDuration = time.Since(start)
+	if err != nil {
+		result.Duration = time.Since(start)
+		result.Error = err.Error()
+		return result
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	if endpoint.TCP.Send != "" {
+		if _, err := conn.Write([]byte(endpoint.TCP.Send)); err != nil {
+			result.Duration = time.Since(start)
+			result.Error = "failed to send: " + err.Error()
+			return result
+		}
+	}
+
+	if endpoint.TCP.Expect != "" {
+		re, err := regexp.Compile(endpoint.TCP.Expect)
+		if err != nil {
+			result.Error = "invalid expect regex: " + err.Error()
+			return result
+		}
+
+		sent := time.Now()
+		received, matched, err := readUntilMatch(conn, re)
+		result.TTFB = time.Since(sent)
+		result.BytesReceived = int64(len(received))
+		result.Duration = time.Since(start)
+
+		if !matched {
+			if err != nil {
+				result.Error = fmt.Sprintf("expected response not received: %v", err)
+			} else {
+				result.Error = "response does not match expected pattern"
+			}
+			return result
+		}
+	}
+
+	result.Duration = time.Since(start)
+	result.Success = true
+	return result
+}
+
+// readUntilMatch reads from conn until the data read so far matches re, the
+// peer closes the connection, or maxBannerSize bytes have been read
+func readUntilMatch(conn net.Conn, re *regexp.Regexp) ([]byte, bool, error) {
+	var received []byte
+	buf := make([]byte, 4096)
+	for len(received) < maxBannerSize {
+		n, err := conn.Read(buf)
+		received = append(received, buf[:n]...)
+		if re.Match(received) {
+			return received, true, nil
+		}
+		if err != nil {
+			return received, false, err
+		}
+	}
+	return received, false, nil
+}
+
+// targetAddress normalizes an endpoint target ("host:port", "host" or
+// "scheme://host:port") to a dialable address
+func targetAddress(target, defaultPort string) (string, error) {
+	if strings.Contains(target, "://") {
+		u, err := url.Parse(target)
+		if err != nil {
+			return "", fmt.Errorf("invalid target %q: %w", target, err)
+		}
+		target = u.Host
+	}
+
+	if _, _, err := net.SplitHostPort(target); err == nil {
+		return target, nil
+	}
+	if defaultPort == "" {
+		return "", fmt.Errorf("invalid target %q: port is required", target)
+	}
+	return net.JoinHostPort(strings.Trim(target, "[]"), defaultPort), nil
+}
@@ -0,0 +1,138 @@
+package checker
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/manu/octo/pkg/config"
+)
+
+// startBannerServer answers every line it receives with "+PONG" after
+// greeting the client with a banner
+func startBannerServer(t *testing.T) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn) {
+				defer conn.Close()
+				conn.Write([]byte("220 octo-test ready\r\n"))
+				scanner := bufio.NewScanner(conn)
+				for scanner.Scan() {
+					conn.Write([]byte("+PONG\r\n"))
+				}
+			}(conn)
+		}
+	}()
+
+	return ln.Addr().String()
+}
+
+func TestChecker_Check_TCP(t *testing.T) {
+	addr := startBannerServer(t)
+
+	tests := []struct {
+		name    string
+		url     string
+		tcp     config.TCPConfig
+		wantErr string
+	}{
+		{name: "connect only", url: addr},
+		{name: "banner", url: "tcp://" + addr, tcp: config.TCPConfig{Expect: `^220 `}},
+		{name: "send and expect", url: addr, tcp: config.TCPConfig{Send: "PING\r\n", Expect: `\+PONG`}},
+		{name: "unexpected response", url: addr, tcp: config.TCPConfig{Expect: `^SSH-`}, wantErr: "expected response not received"},
+		{name: "missing port", url: "127.0.0.1", wantErr: "port is required"},
+	}
+
+	c := NewChecker()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+			defer cancel()
+
+			result := c.Check(ctx, config.EndpointConfig{ID: "tcp", Type: TypeTCP, URL: tt.url, TCP: tt.tcp})
+			if result.Type != TypeTCP {
+				t.Errorf("Expected result type %q, got %q", TypeTCP, result.Type)
+			}
+			if tt.wantErr == "" {
+				if !result.Success {
+					t.Errorf("Expected success, got failure: %s", result.Error)
+				}
+				return
+			}
+			if result.Success || !strings.Contains(result.Error, tt.wantErr) {
+				t.Errorf("Expected error containing %q, got success=%v error=%q", tt.wantErr, result.Success, result.Error)
+			}
+		})
+	}
+}
+
+func TestChecker_Check_TLSHandshakeOnly(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ts.Close()
+
+	c := newInsecureChecker()
+	target := strings.TrimPrefix(ts.URL, "https://")
+
+	result := c.Check(context.Background(), config.EndpointConfig{
+		ID:   "tls-only",
+		Type: TypeTLS,
+		URL:  target,
+		SSL:  config.SSLConfig{MinTLSVersion: "1.2"},
+	})
+	if !result.Success {
+		t.Fatalf("Expected success, got failure: %s", result.Error)
+	}
+	if result.CertExpiry.IsZero() || len(result.CertChain) == 0 || result.TLSVersion == "" {
+		t.Error("Expected certificate and protocol details to be recorded")
+	}
+	if result.StatusCode != 0 {
+		t.Errorf("Expected no status code for a handshake-only check, got %d", result.StatusCode)
+	}
+
+	result = c.Check(context.Background(), config.EndpointConfig{
+		ID:   "tls-only-san",
+		Type: TypeTLS,
+		URL:  "tls://" + target,
+		SSL:  config.SSLConfig{RequiredSANs: []string{"mail.example.com"}},
+	})
+	if result.Success || !strings.Contains(result.Error, "do not include mail.example.com") {
+		t.Errorf("Expected SAN assertion failure, got success=%v error=%q", result.Success, result.Error)
+	}
+}
+
+func TestChecker_Check_UnknownType(t *testing.T) {
+	result := NewChecker().Check(context.Background(), config.EndpointConfig{ID: "x", Type: "gopher"})
+	if result.Success || result.Error != `unknown check type "gopher"` {
+		t.Errorf("Expected unknown type error, got success=%v error=%q", result.Success, result.Error)
+	}
+}
+
+func TestChecker_RegisterProber(t *testing.T) {
+	c := NewChecker()
+	c.RegisterProber("custom", ProberFunc(func(ctx context.Context, endpoint config.EndpointConfig) Result {
+		result := newResult(endpoint)
+		result.Success = true
+		return result
+	}))
+
+	result := c.Check(context.Background(), config.EndpointConfig{ID: "custom", Type: "custom"})
+	if !result.Success || result.Type != "custom" || result.EndpointID != "custom" {
+		t.Errorf("Expected custom prober result, got %+v", result)
+	}
+}
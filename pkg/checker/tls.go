@@ -0,0 +1,285 @@
+package checker
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/manu/octo/pkg/config"
+)
+
+// CertInfo describes one certificate of the chain presented by the server
+type CertInfo struct {
+	Subject            string    `json:"subject"`
+	Issuer             string    `json:"issuer"`
+	SerialNumber       string    `json:"serial_number"`
+	NotBefore          time.Time `json:"not_before"`
+	NotAfter           time.Time `json:"not_after"`
+	SANs               []string  `json:"sans,omitempty"`
+	KeyType            string    `json:"key_type"`
+	KeySize            int       `json:"key_size"`
+	SignatureAlgorithm string    `json:"signature_algorithm"`
+	Fingerprint        string    `json:"fingerprint"` // SHA-256 of the DER encoding
+	SPKI               string    `json:"spki"`        // "sha256/<base64>" pin of the public key
+	IsCA               bool      `json:"is_ca"`
+}
+
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// newCertInfo extracts the details we track from a parsed certificate
+func newCertInfo(cert *x509.Certificate) CertInfo {
+	keyType, keySize := publicKeyInfo(cert)
+	sum := sha256.Sum256(cert.Raw)
+
+	return CertInfo{
+		Subject:            cert.Subject.String(),
+		Issuer:             cert.Issuer.String(),
+		SerialNumber:       cert.SerialNumber.String(),
+		NotBefore:          cert.NotBefore,
+		NotAfter:           cert.NotAfter,
+		SANs:               certSANs(cert),
+		KeyType:            keyType,
+		KeySize:            keySize,
+		SignatureAlgorithm: cert.SignatureAlgorithm.String(),
+		Fingerprint:        hex.EncodeToString(sum[:]),
+		SPKI:               spkiPin(cert),
+		IsCA:               cert.IsCA,
+	}
+}
+
+// certSANs flattens the DNS, IP, email and URI subject alternative names
+func certSANs(cert *x509.Certificate) []string {
+	var sans []string
+	sans = append(sans, cert.DNSNames...)
+	for _, ip := range cert.IPAddresses {
+		sans = append(sans, ip.String())
+	}
+	sans = append(sans, cert.EmailAddresses...)
+	for _, u := range cert.URIs {
+		sans = append(sans, u.String())
+	}
+	return sans
+}
+
+// spkiPin returns the HPKP style pin of the public key of a certificate
+func spkiPin(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	return "sha256/" + base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// issuerAllowed reports whether an issuer matches one of the allowed
+// names: its common name, one of its organizations or its full DN
+func issuerAllowed(allowed []string, issuer pkix.Name) bool {
+	names := append([]string{issuer.CommonName, issuer.String()}, issuer.Organization...)
+	for _, a := range allowed {
+		for _, name := range names {
+			if name != "" && strings.EqualFold(strings.TrimSpace(a), name) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func publicKeyInfo(cert *x509.Certificate) (string, int) {
+	switch key := cert.PublicKey.(type) {
+	case *rsa.PublicKey:
+		return "RSA", key.N.BitLen()
+	case *ecdsa.PublicKey:
+		return "ECDSA", key.Curve.Params().BitSize
+	case ed25519.PublicKey:
+		return "Ed25519", 256
+	default:
+		return cert.PublicKeyAlgorithm.String(), 0
+	}
+}
+
+// applyTLSState copies the negotiated connection parameters and the presented
+// certificate chain into the result
+func applyTLSState(result *Result, state *tls.ConnectionState) {
+	result.TLSVersion = tls.VersionName(state.Version)
+	result.CipherSuite = tls.CipherSuiteName(state.CipherSuite)
+	result.ALPN = state.NegotiatedProtocol
+
+	if len(state.PeerCertificates) == 0 {
+		return
+	}
+
+	cert := state.PeerCertificates[0]
+	result.CertExpiry = cert.NotAfter
+	result.CertNotAfter = cert.NotAfter
+	result.CertNotBefore = cert.NotBefore
+	result.CertIssuer = cert.Issuer.String()
+	result.CertSubject = cert.Subject.String()
+
+	result.CertChain = make([]CertInfo, 0, len(state.PeerCertificates))
+	for _, c := range state.PeerCertificates {
+		result.CertChain = append(result.CertChain, newCertInfo(c))
+	}
+
+	leaf := result.CertChain[0]
+	result.CertSANs = leaf.SANs
+	result.CertKeyType = leaf.KeyType
+	result.CertKeySize = leaf.KeySize
+	result.CertFingerprint = leaf.Fingerprint
+}
+
+// validateTLS evaluates the SSL assertions of an endpoint against the
+// negotiated connection. host is the name the client connected to.
+func validateTLS(ssl config.SSLConfig, host string, state *tls.ConnectionState) error {
+	if ssl.MinTLSVersion != "" {
+		min, ok := tlsVersions[strings.TrimPrefix(ssl.MinTLSVersion, "TLS")]
+		if !ok {
+			return fmt.Errorf("invalid min_tls_version %q", ssl.MinTLSVersion)
+		}
+		if state.Version < min {
+			return fmt.Errorf("tls version %s is below minimum %s", tls.VersionName(state.Version), ssl.MinTLSVersion)
+		}
+	}
+
+	if len(state.PeerCertificates) == 0 {
+		return fmt.Errorf("server presented no certificate")
+	}
+	leaf := state.PeerCertificates[0]
+
+	if err := leaf.VerifyHostname(host); err != nil {
+		return fmt.Errorf("certificate is not valid for host %s", host)
+	}
+
+	if len(ssl.RequiredSANs) > 0 {
+		sans := make(map[string]bool)
+		for _, san := range certSANs(leaf) {
+			sans[strings.ToLower(san)] = true
+		}
+		for _, required := range ssl.RequiredSANs {
+			if !sans[strings.ToLower(required)] {
+				return fmt.Errorf("certificate SANs do not include %s", required)
+			}
+		}
+	}
+
+	if ssl.IssuerPattern != "" {
+		re, err := regexp.Compile(ssl.IssuerPattern)
+		if err != nil {
+			return fmt.Errorf("invalid issuer_pattern: %w", err)
+		}
+		if !re.MatchString(leaf.Issuer.String()) {
+			return fmt.Errorf("certificate issuer %q does not match %q", leaf.Issuer.String(), ssl.IssuerPattern)
+		}
+	}
+
+	return nil
+}
+
+// verifyIssuer checks the certificates presented against the allowed
+// issuers and the SPKI pins of an endpoint. Pins only match the verified
+// chains, which include the root the server did not send: any server can
+// present a pinned certificate it does not hold the key of.
+func verifyIssuer(ssl config.SSLConfig, state *tls.ConnectionState) error {
+	leaf := state.PeerCertificates[0]
+	if len(ssl.AllowedIssuers) > 0 && !issuerAllowed(ssl.AllowedIssuers, leaf.Issuer) {
+		return fmt.Errorf("certificate issuer %q is not allowed", leaf.Issuer.String())
+	}
+
+	if len(ssl.SPKIPins) == 0 {
+		return nil
+	}
+	if len(state.VerifiedChains) == 0 {
+		return fmt.Errorf("no verified chain to match the SPKI pins against")
+	}
+	for _, chain := range state.VerifiedChains {
+		for _, cert := range chain {
+			pin := spkiPin(cert)
+			for _, want := range ssl.SPKIPins {
+				if strings.TrimPrefix(want, "sha256/") == strings.TrimPrefix(pin, "sha256/") {
+					return nil
+				}
+			}
+		}
+	}
+	return fmt.Errorf("no certificate of the chain matches the SPKI pins (leaf %s)", spkiPin(leaf))
+}
+
+// inspectTLS records the negotiated connection, then evaluates the SSL
+// assertions and the revocation status. A non-nil error fails the check.
+func (c *Checker) inspectTLS(ctx context.Context, result *Result, ssl config.SSLConfig, host string, state *tls.ConnectionState) error {
+	applyTLSState(result, state)
+	if err := validateTLS(ssl, host, state); err != nil {
+		return fmt.Errorf("tls validation failed: %w", err)
+	}
+	if err := verifyIssuer(ssl, state); err != nil {
+		result.CertMismatch = true
+		return fmt.Errorf("unexpected certificate: %w", err)
+	}
+
+	status, stapled, err := c.checkOCSP(ctx, state, ssl.OCSPCheck)
+	result.OCSPStatus = status
+	result.OCSPStapled = stapled
+	if err != nil {
+		result.OCSPStatus = OCSPStatusUnknown
+		result.OCSPError = err.Error()
+	}
+	if result.OCSPStatus == OCSPStatusRevoked {
+		return fmt.Errorf("certificate has been revoked")
+	}
+	return nil
+}
+
+// checkTLS performs only a TLS handshake, for services that do not speak HTTP.
+// The SSL assertions and OCSP settings of the endpoint apply as for HTTPS.
+func (c *Checker) checkTLS(ctx context.Context, endpoint config.EndpointConfig) Result {
+	result := newResult(endpoint)
+
+	address, err := targetAddress(endpoint.URL, "443")
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	host, _, _ := net.SplitHostPort(address)
+
+	start := time.Now()
+	rawConn, err := dialerFrom(ctx).DialContext(ctx, "tcp", address)
+	result.ConnDuration = time.Since(start)
+	if err != nil {
+		result.Duration = time.Since(start)
+		result.Error = err.Error()
+		return result
+	}
+	defer rawConn.Close()
+
+	tlsStart := time.Now()
+	conn := tls.Client(rawConn, c.tlsConfig(host))
+	err = conn.HandshakeContext(ctx)
+	result.TLSDuration = time.Since(tlsStart)
+	result.Duration = time.Since(start)
+	if err != nil {
+		result.Error = "tls handshake failed: " + err.Error()
+		return result
+	}
+
+	state := conn.ConnectionState()
+	if err := c.inspectTLS(ctx, &result, endpoint.SSL, host, &state); err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	result.Success = true
+	return result
+}
@@ -0,0 +1,200 @@
+package checker
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/manu/octo/pkg/config"
+)
+
+func newInsecureChecker() *Checker {
+	c := NewChecker()
+	transport := c.client.Transport.(*http.Transport)
+	transport.TLSClientConfig.InsecureSkipVerify = true
+	return c
+}
+
+// newTrustingChecker returns a checker verifying certificates against roots
+func newTrustingChecker(roots ...*x509.Certificate) *Checker {
+	c := NewChecker()
+	pool := x509.NewCertPool()
+	for _, root := range roots {
+		pool.AddCert(root)
+	}
+	c.client.Transport.(*http.Transport).TLSClientConfig.RootCAs = pool
+	return c
+}
+
+func TestChecker_Check_TLSDetails(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	c := newInsecureChecker()
+	result := c.Check(context.Background(), config.EndpointConfig{
+		ID:     "tls-details",
+		URL:    ts.URL,
+		Method: "GET",
+	})
+
+	if !result.Success {
+		t.Fatalf("Expected success, got failure: %s", result.Error)
+	}
+	if result.TLSVersion == "" || result.CipherSuite == "" {
+		t.Errorf("Expected TLS version and cipher suite, got %q / %q", result.TLSVersion, result.CipherSuite)
+	}
+	if len(result.CertChain) == 0 {
+		t.Fatal("Expected certificate chain to be captured")
+	}
+	if result.CertFingerprint != result.CertChain[0].Fingerprint {
+		t.Error("Expected leaf fingerprint to match first chain entry")
+	}
+	if result.CertKeyType == "" || result.CertKeySize == 0 {
+		t.Errorf("Expected key type and size, got %q / %d", result.CertKeyType, result.CertKeySize)
+	}
+
+	foundSAN := false
+	for _, san := range result.CertSANs {
+		if san == "example.com" {
+			foundSAN = true
+		}
+	}
+	if !foundSAN {
+		t.Errorf("Expected SANs to include example.com, got %v", result.CertSANs)
+	}
+}
+
+func TestChecker_Check_TLSAssertions(t *testing.T) {
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	ts.TLS = &tls.Config{MaxVersion: tls.VersionTLS12}
+	ts.StartTLS()
+	defer ts.Close()
+
+	tests := []struct {
+		name    string
+		ssl     config.SSLConfig
+		wantErr string
+	}{
+		{
+			name: "all assertions pass",
+			ssl: config.SSLConfig{
+				MinTLSVersion: "1.2",
+				RequiredSANs:  []string{"example.com"},
+				IssuerPattern: "Acme Co",
+			},
+		},
+		{
+			name:    "tls version below minimum",
+			ssl:     config.SSLConfig{MinTLSVersion: "1.3"},
+			wantErr: "below minimum",
+		},
+		{
+			name:    "missing SAN",
+			ssl:     config.SSLConfig{RequiredSANs: []string{"api.example.com"}},
+			wantErr: "do not include api.example.com",
+		},
+		{
+			name:    "issuer mismatch",
+			ssl:     config.SSLConfig{IssuerPattern: "Let's Encrypt"},
+			wantErr: "does not match",
+		},
+	}
+
+	c := newInsecureChecker()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := c.Check(context.Background(), config.EndpointConfig{
+				ID:     "tls-assert",
+				URL:    ts.URL,
+				Method: "GET",
+				SSL:    tt.ssl,
+			})
+
+			if tt.wantErr == "" {
+				if !result.Success {
+					t.Errorf("Expected success, got failure: %s", result.Error)
+				}
+				return
+			}
+			if result.Success {
+				t.Fatal("Expected failure, got success")
+			}
+			if !strings.Contains(result.Error, tt.wantErr) {
+				t.Errorf("Expected error containing %q, got %q", tt.wantErr, result.Error)
+			}
+		})
+	}
+}
+
+func TestChecker_Check_TLSIssuerAllowList(t *testing.T) {
+	pki := newTestPKI(t, "")
+	ts := pki.startServer(t, nil)
+	defer ts.Close()
+
+	tests := []struct {
+		name     string
+		ssl      config.SSLConfig
+		insecure bool
+		wantErr  string
+	}{
+		{name: "issuer organization allowed", ssl: config.SSLConfig{AllowedIssuers: []string{"Let's Encrypt", "octo"}}},
+		{name: "issuer DN allowed", ssl: config.SSLConfig{AllowedIssuers: []string{"CN=Octo Test CA,O=Octo"}}},
+		{name: "CA pinned", ssl: config.SSLConfig{SPKIPins: []string{spkiPin(pki.caCert)}}},
+		{name: "leaf pinned without prefix", ssl: config.SSLConfig{SPKIPins: []string{strings.TrimPrefix(spkiPin(pki.leaf), "sha256/")}}},
+		{
+			name:    "issuer not allowed",
+			ssl:     config.SSLConfig{AllowedIssuers: []string{"Let's Encrypt", "Octo Test"}},
+			wantErr: `issuer "CN=Octo Test CA,O=Octo" is not allowed`,
+		},
+		{
+			name:    "pin mismatch",
+			ssl:     config.SSLConfig{SPKIPins: []string{"sha256/AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA="}},
+			wantErr: "no certificate of the chain matches the SPKI pins",
+		},
+		{
+			name:     "pin without verification",
+			ssl:      config.SSLConfig{SPKIPins: []string{spkiPin(pki.leaf)}},
+			insecure: true,
+			wantErr:  "no verified chain to match the SPKI pins against",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTrustingChecker(pki.caCert)
+			if tt.insecure {
+				c = newInsecureChecker()
+			}
+			result := c.Check(context.Background(), config.EndpointConfig{
+				ID:     "tls-issuer",
+				URL:    ts.URL,
+				Method: "GET",
+				SSL:    tt.ssl,
+			})
+
+			if result.CertChain[1].SPKI != spkiPin(pki.caCert) {
+				t.Errorf("Expected the SPKI pin of the CA in the chain, got %q", result.CertChain[1].SPKI)
+			}
+			if tt.wantErr == "" {
+				if !result.Success {
+					t.Errorf("Expected success, got failure: %s", result.Error)
+				}
+				return
+			}
+			if result.Success || !result.CertMismatch {
+				t.Fatalf("Expected a certificate mismatch, got success=%v mismatch=%v", result.Success, result.CertMismatch)
+			}
+			if !strings.Contains(result.Error, tt.wantErr) {
+				t.Errorf("Expected error containing %q, got %q", tt.wantErr, result.Error)
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package checker
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/manu/octo/pkg/config"
+)
+
+// maxMessageSize bounds a single WebSocket message or Server-Sent Event
+const maxMessageSize = 1 << 20
+
+// checkWebSocket performs the upgrade and optionally exchanges a message.
+// ConnDuration covers the handshake and TTFB the wait for the first message.
+func (c *Checker) checkWebSocket(ctx context.Context, endpoint config.EndpointConfig) Result {
+	result := newResult(endpoint)
+
+	u, err := url.Parse(endpoint.URL)
+	if err != nil || (u.Scheme != "ws" && u.Scheme != "wss") {
+		result.Error = "websocket check requires a ws:// or wss:// URL"
+		return result
+	}
+
+	var expect *regexp.Regexp
+	if endpoint.WebSocket.Expect != "" {
+		expect, err = regexp.Compile(endpoint.WebSocket.Expect)
+		if err != nil {
+			result.Error = "invalid expect regex: " + err.Error()
+			return result
+		}
+	}
+
+	header := make(http.Header)
+	for k, v := range endpoint.Headers {
+		header.Add(k, v)
+	}
+
+	dialer := websocket.Dialer{
+		TLSClientConfig: c.tlsConfig(u.Hostname()),
+		Subprotocols:    endpoint.WebSocket.Subprotocols,
+		NetDialContext:  dialerFrom(ctx).DialContext,
+		Proxy:           proxyRequest,
+	}
+
+	recordProxy(&result, dialerFrom(ctx), u)
+	start := time.Now()
+	conn, resp, err := dialer.DialContext(ctx, endpoint.URL, header)
+	result.ConnDuration = time.Since(start)
+	if resp != nil {
+		result.StatusCode = resp.StatusCode
+	}
+	if err != nil {
+		result.Duration = time.Since(start)
+		result.Error = "websocket handshake failed: " + err.Error()
+		return result
+	}
+	defer conn.Close()
+
+	// Unblock reads once the check times out
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+	conn.SetReadLimit(maxMessageSize)
+
+	if tlsConn, ok := conn.NetConn().(*tls.Conn); ok {
+		state := tlsConn.ConnectionState()
+		if err := c.inspectTLS(ctx, &result, endpoint.SSL, u.Hostname(), &state); err != nil {
+			result.Error = err.Error()
+			return result
+		}
+	}
+
+	if endpoint.WebSocket.Send != "" {
+		if err := conn.WriteMessage(websocket.TextMessage, []byte(endpoint.WebSocket.Send)); err != nil {
+			result.Duration = time.Since(start)
+			result.Error = "failed to send: " + err.Error()
+			return result
+		}
+	}
+
+	if endpoint.WebSocket.Send != "" || expect != nil {
+		sent := time.Now()
+		for {
+			_, msg, err := conn.ReadMessage()
+			if err != nil {
+				result.Duration = time.Since(start)
+				if ctx.Err() != nil {
+					err = ctx.Err()
+				}
+				result.Error = fmt.Sprintf("expected message not received: %v", err)
+				return result
+			}
+			if result.TTFB == 0 {
+				result.TTFB = time.Since(sent)
+			}
+			result.BytesReceived += int64(len(msg))
+			if expect == nil || expect.Match(msg) {
+				break
+			}
+		}
+	}
+
+	result.Duration = time.Since(start)
+	conn.WriteControl(websocket.CloseMessage,
+		websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""),
+		time.Now().Add(time.Second))
+
+	result.Success = true
+	return result
+}
@@ -0,0 +1,167 @@
+package checker
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/manu/octo/pkg/config"
+)
+
+// wsHandler greets each client, then answers "ping" with "pong" and echoes
+// anything else. Upgrades carrying a wrong token are refused.
+func wsHandler(t *testing.T) http.Handler {
+	upgrader := websocket.Upgrader{Subprotocols: []string{"octo.v1"}}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") == "Bearer wrong" {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Logf("upgrade failed: %v", err)
+			return
+		}
+		defer conn.Close()
+
+		conn.WriteMessage(websocket.TextMessage, []byte(`{"type":"hello"}`))
+		for {
+			_, msg, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			reply := msg
+			if string(msg) == "ping" {
+				reply = []byte("pong")
+			}
+			conn.WriteMessage(websocket.TextMessage, reply)
+		}
+	})
+}
+
+func TestChecker_Check_WebSocket(t *testing.T) {
+	ts := httptest.NewServer(wsHandler(t))
+	defer ts.Close()
+	wsURL := "ws" + strings.TrimPrefix(ts.URL, "http")
+
+	tests := []struct {
+		name    string
+		ws      config.WebSocketConfig
+		headers map[string]string
+		wantErr string
+	}{
+		{name: "upgrade only"},
+		{name: "greeting", ws: config.WebSocketConfig{Expect: `"hello"`}},
+		{name: "send and expect", ws: config.WebSocketConfig{Send: "ping", Expect: `^pong$`, Subprotocols: []string{"octo.v1"}}},
+		{name: "no matching reply", ws: config.WebSocketConfig{Send: "ping", Expect: `^PONG$`}, wantErr: "expected message not received"},
+		{name: "refused upgrade", headers: map[string]string{"Authorization": "Bearer wrong"}, wantErr: "websocket handshake failed"},
+	}
+
+	c := NewChecker()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+			defer cancel()
+
+			result := c.Check(ctx, config.EndpointConfig{
+				ID:        "ws",
+				Type:      TypeWebSocket,
+				URL:       wsURL,
+				Headers:   tt.headers,
+				WebSocket: tt.ws,
+			})
+			if tt.wantErr == "" {
+				if !result.Success {
+					t.Errorf("Expected success, got failure: %s", result.Error)
+				}
+				if result.StatusCode != http.StatusSwitchingProtocols {
+					t.Errorf("Expected status 101, got %d", result.StatusCode)
+				}
+				if tt.ws.Expect != "" && result.TTFB == 0 {
+					t.Error("Expected first message latency to be recorded")
+				}
+				return
+			}
+			if result.Success || !strings.Contains(result.Error, tt.wantErr) {
+				t.Errorf("Expected error containing %q, got success=%v error=%q", tt.wantErr, result.Success, result.Error)
+			}
+		})
+	}
+}
+
+func TestChecker_Check_WebSocketTLS(t *testing.T) {
+	ts := httptest.NewTLSServer(wsHandler(t))
+	defer ts.Close()
+
+	result := newInsecureChecker().Check(context.Background(), config.EndpointConfig{
+		ID:        "wss",
+		Type:      TypeWebSocket,
+		URL:       "wss" + strings.TrimPrefix(ts.URL, "https"),
+		WebSocket: config.WebSocketConfig{Expect: "hello"},
+	})
+	if !result.Success {
+		t.Fatalf("Expected success, got failure: %s", result.Error)
+	}
+	if result.TLSVersion == "" || len(result.CertChain) == 0 {
+		t.Error("Expected TLS details to be recorded")
+	}
+}
+
+func TestChecker_Check_SSE(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/plain" {
+			fmt.Fprint(w, "not a stream")
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream; charset=utf-8")
+		flusher := w.(http.Flusher)
+
+		fmt.Fprint(w, ": keep-alive\n\n")
+		fmt.Fprint(w, "data: {\"seq\":1}\n\n")
+		fmt.Fprint(w, "event: price\ndata: {\"symbol\":\"ACME\",\ndata: \"price\":42}\n\n")
+		flusher.Flush()
+		<-r.Context().Done()
+	}))
+	defer ts.Close()
+
+	tests := []struct {
+		name    string
+		path    string
+		sse     config.SSEConfig
+		wantErr string
+	}{
+		{name: "any event"},
+		{name: "named event", sse: config.SSEConfig{Event: "price", Expect: `"price":42`}},
+		{name: "data match", sse: config.SSEConfig{Expect: `"seq":1`}},
+		{name: "missing event", sse: config.SSEConfig{Event: "trade"}, wantErr: "expected event not received"},
+		{name: "not an event stream", path: "/plain", wantErr: "unexpected content type"},
+	}
+
+	c := NewChecker()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+			defer cancel()
+
+			result := c.Check(ctx, config.EndpointConfig{ID: "sse", Type: TypeSSE, URL: ts.URL + tt.path, SSE: tt.sse})
+			if tt.wantErr == "" {
+				if !result.Success {
+					t.Errorf("Expected success, got failure: %s", result.Error)
+				}
+				if result.TTFB == 0 || result.BytesReceived == 0 {
+					t.Error("Expected first event latency and bytes to be recorded")
+				}
+				return
+			}
+			if result.Success || !strings.Contains(result.Error, tt.wantErr) {
+				t.Errorf("Expected error containing %q, got success=%v error=%q", tt.wantErr, result.Success, result.Error)
+			}
+		})
+	}
+}
@@ -19,11 +19,47 @@ type Config struct {
 	AlertChannels []AlertChannel    `yaml:"alert_channels" json:"alert_channels"`
 	AlertRules    []AlertRule       `yaml:"alert_rules" json:"alert_rules"`
 	Satellites    []SatelliteConfig `yaml:"satellites" json:"satellites"`
+
+	// DomainExpiry checks the registration of every registrable domain
+	// among the endpoint URLs
+	DomainExpiry DomainExpiryConfig `yaml:"domain_expiry,omitempty" json:"domain_expiry,omitempty"`
+
+	// CertTransparency watches Certificate Transparency logs for
+	// certificates issued for the endpoint domains
+	CertTransparency CertTransparencyConfig `yaml:"cert_transparency,omitempty" json:"cert_transparency,omitempty"`
 }
 
 type GlobalConfig struct {
 	CheckInterval  time.Duration `yaml:"check_interval" json:"check_interval"`
 	RequestTimeout time.Duration `yaml:"request_timeout" json:"request_timeout"`
+	Proxy          ProxyConfig   `yaml:"proxy,omitempty" json:"proxy,omitempty"` // Default for checks run by the master and satellites
+}
+
+// ProxyConfig routes HTTP, SSE and WebSocket checks through an outbound
+// proxy. Credentials may also be given in the URL user info.
+type ProxyConfig struct {
+	URL      string   `yaml:"url,omitempty" json:"url,omitempty"` // http://, https:// or socks5://; "direct" disables an inherited proxy
+	Username string   `yaml:"username,omitempty" json:"username,omitempty"`
+	Password string   `yaml:"password,omitempty" json:"password,omitempty"` // env:OCTO_NAME or file:path
+	NoProxy  []string `yaml:"no_proxy,omitempty" json:"no_proxy,omitempty"` // Hosts, ".domain" suffixes and CIDRs reached directly
+}
+
+// ProxyDirect is the proxy URL that disables an inherited proxy
+const ProxyDirect = "direct"
+
+// ProxyFor returns the proxy used for an endpoint checked from a satellite
+// ("" for the master): the endpoint's own, else the satellite's, else the
+// global one
+func (c *Config) ProxyFor(endpoint EndpointConfig, satelliteID string) ProxyConfig {
+	if endpoint.Proxy.URL != "" {
+		return endpoint.Proxy
+	}
+	for _, sat := range c.Satellites {
+		if satelliteID != "" && sat.ID == satelliteID && sat.Proxy.URL != "" {
+			return sat.Proxy
+		}
+	}
+	return c.Global.Proxy
 }
 
 type AuthConfig struct {
@@ -42,33 +78,354 @@ type UserConfig struct {
 type EndpointConfig struct {
 	ID         string            `yaml:"id" json:"id"`
 	Name       string            `yaml:"name" json:"name"`
-	URL        string            `yaml:"url" json:"url"`
+	Type       string            `yaml:"type,omitempty" json:"type,omitempty"` // "http" (default) or another checker.Type* value
+	URL        string            `yaml:"url" json:"url"`                       // URL for http, target (host:port or name) for other types
 	Method     string            `yaml:"method" json:"method"`
 	Interval   time.Duration     `yaml:"interval" json:"interval"`
 	Timeout    time.Duration     `yaml:"timeout" json:"timeout"`
 	Headers    map[string]string `yaml:"headers" json:"headers"`
+	Body       string            `yaml:"body,omitempty" json:"body,omitempty"`
 	Validation ValidationConfig  `yaml:"validation" json:"validation"`
 	SSL        SSLConfig         `yaml:"ssl" json:"ssl"`
 	Tags       map[string]string `yaml:"tags" json:"tags"`
 	Satellites []string          `yaml:"satellites" json:"satellites"`
+
+	// FreshConnection opens new connections for every HTTP check (the
+	// default) so DNS, connect and TLS timings are measured each time. Set
+	// it to false to reuse kept-alive connections between checks.
+	FreshConnection *bool `yaml:"fresh_connection,omitempty" json:"fresh_connection,omitempty"`
+
+	// MaxBodyBytes bounds the part of an HTTP response body kept in memory,
+	// 10 MiB by default. Checksum, substring and regex validators still see
+	// the whole body; JSON, XPath and CSS validators fail on a larger one.
+	MaxBodyBytes int64 `yaml:"max_body_bytes,omitempty" json:"max_body_bytes,omitempty"`
+
+	// HTTPVersion restricts the HTTP protocol: "auto" (default, HTTP/2 with
+	// HTTP/1.1 fallback), "1.1", "2" or "h2c" (HTTP/2 without TLS)
+	HTTPVersion string `yaml:"http_version,omitempty" json:"http_version,omitempty"`
+
+	// Name resolution and address family, for every type that connects to
+	// the target. Resolve pins names like curl --resolve, e.g.
+	// "api.example.com:443:10.0.0.12", keeping the Host header and SNI.
+	// IPVersion "both" runs the check once over IPv4 and once over IPv6.
+	Resolve   []string `yaml:"resolve,omitempty" json:"resolve,omitempty"`
+	DNSServer string   `yaml:"dns_server,omitempty" json:"dns_server,omitempty"` // e.g. "10.0.0.2:53"
+	IPVersion string   `yaml:"ip_version,omitempty" json:"ip_version,omitempty"` // "4", "6" or "both"
+
+	// Proxy overrides the satellite and global proxy settings
+	Proxy ProxyConfig `yaml:"proxy,omitempty" json:"proxy,omitempty"`
+
+	// CookieJar keeps the cookies set by the server: "check" for the
+	// redirects and steps of one check, "persistent" across checks. Without
+	// it cookies are not sent back.
+	CookieJar string `yaml:"cookie_jar,omitempty" json:"cookie_jar,omitempty"`
+
+	// Drift flags unexpected changes of the body of an HTTP or page check
+	Drift DriftConfig `yaml:"drift,omitempty" json:"drift,omitempty"`
+
+	// Snapshot bounds what is kept of the response of a failed HTTP check
+	Snapshot SnapshotConfig `yaml:"snapshot,omitempty" json:"snapshot,omitempty"`
+
+	// Steps turn an HTTP endpoint into a multi-step transaction. When set,
+	// the endpoint URL, method, body and validation are ignored.
+	Steps []StepConfig `yaml:"steps,omitempty" json:"steps,omitempty"`
+
+	// Type specific settings
+	TCP       TCPConfig       `yaml:"tcp,omitempty" json:"tcp,omitempty"`
+	DNS       DNSConfig       `yaml:"dns,omitempty" json:"dns,omitempty"`
+	GRPC      GRPCConfig      `yaml:"grpc,omitempty" json:"grpc,omitempty"`
+	WebSocket WebSocketConfig `yaml:"websocket,omitempty" json:"websocket,omitempty"`
+	SSE       SSEConfig       `yaml:"sse,omitempty" json:"sse,omitempty"`
+	Push      PushConfig      `yaml:"push,omitempty" json:"push,omitempty"`
+	Database  DatabaseConfig  `yaml:"database,omitempty" json:"database,omitempty"`
+	Service   ServiceConfig   `yaml:"service,omitempty" json:"service,omitempty"`
+	Page      PageConfig      `yaml:"page,omitempty" json:"page,omitempty"`
+	Domain    DomainConfig    `yaml:"domain,omitempty" json:"domain,omitempty"`
+	CT        CTLogConfig     `yaml:"ct,omitempty" json:"ct,omitempty"`
+}
+
+// UsesFreshConnection reports whether HTTP checks open new connections
+func (e EndpointConfig) UsesFreshConnection() bool {
+	return e.FreshConnection == nil || *e.FreshConnection
+}
+
+// Validate rejects an endpoint the checker would not run within its limits
+func (e EndpointConfig) Validate() error {
+	if err := e.Validation.Validate(); err != nil {
+		return fmt.Errorf("endpoint %q: validation: %w", e.ID, err)
+	}
+	for _, step := range e.Steps {
+		if err := step.Validation.Validate(); err != nil {
+			return fmt.Errorf("endpoint %q: step %q: validation: %w", e.ID, step.Name, err)
+		}
+	}
+	return nil
+}
+
+// Validate rejects a configuration holding an invalid endpoint
+func (c *Config) Validate() error {
+	for _, endpoint := range c.Endpoints {
+		if err := endpoint.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SnapshotConfig limits the response snapshot taken when a check fails.
+// Authorization, cookie and API key headers are always redacted.
+type SnapshotConfig struct {
+	MaxBody       int      `yaml:"max_body,omitempty" json:"max_body,omitempty"`             // Bytes of body kept, default 4 KiB, at most 64 KiB
+	RedactHeaders []string `yaml:"redact_headers,omitempty" json:"redact_headers,omitempty"` // Extra headers whose values are not stored
+}
+
+// DriftConfig compares each response body with a reference one by SimHash,
+// after removing the ignored regions, and fails the check once when the
+// similarity drops below the threshold; the body then becomes the
+// reference. Multi-step checks are not compared.
+type DriftConfig struct {
+	Enabled   bool     `yaml:"enabled" json:"enabled"`
+	Threshold float64  `yaml:"threshold,omitempty" json:"threshold,omitempty"` // Minimum similarity from 0 to 1, default 0.9
+	Ignore    []string `yaml:"ignore,omitempty" json:"ignore,omitempty"`       // Regexes of changing regions, e.g. timestamps or CSRF tokens
+}
+
+// StepConfig is one request of a multi-step transaction. The URL, headers and
+// body are templates that can reference values extracted by earlier steps,
+// e.g. "Bearer {{ .token }}". Values are inserted verbatim; in a URL, escape
+// them with {{ pathescape .id }} in the path or {{ urlquery .q }} in the
+// query. A value cannot change the scheme or host the URL spells out.
+// Endpoint headers and SSL settings apply to every step.
+type StepConfig struct {
+	Name       string            `yaml:"name" json:"name"`
+	URL        string            `yaml:"url" json:"url"`
+	Method     string            `yaml:"method,omitempty" json:"method,omitempty"` // Defaults to GET
+	Headers    map[string]string `yaml:"headers,omitempty" json:"headers,omitempty"`
+	Body       string            `yaml:"body,omitempty" json:"body,omitempty"`
+	Validation ValidationConfig  `yaml:"validation,omitempty" json:"validation,omitempty"`
+	Extract    []Extraction      `yaml:"extract,omitempty" json:"extract,omitempty"`
+}
+
+// Extraction captures a value from a step response into a variable. Exactly
+// one source must be set.
+type Extraction struct {
+	Name     string `yaml:"name" json:"name"`
+	JSONPath string `yaml:"json_path,omitempty" json:"json_path,omitempty"` // e.g. $.data.token
+	Regex    string `yaml:"regex,omitempty" json:"regex,omitempty"`         // First capture group, or the whole match
+	Header   string `yaml:"header,omitempty" json:"header,omitempty"`       // Response header name
+}
+
+// TCPConfig configures a raw TCP connect check with an optional banner exchange
+type TCPConfig struct {
+	Send   string `yaml:"send,omitempty" json:"send,omitempty"`     // Written after connecting
+	Expect string `yaml:"expect,omitempty" json:"expect,omitempty"` // Regex the response must match
+}
+
+// GRPCConfig configures a check against the grpc.health.v1.Health service.
+// The endpoint URL holds the target as host:port.
+type GRPCConfig struct {
+	Service        string            `yaml:"service,omitempty" json:"service,omitempty"` // Empty checks the server as a whole
+	TLS            bool              `yaml:"tls,omitempty" json:"tls,omitempty"`         // Plaintext when false
+	Metadata       map[string]string `yaml:"metadata,omitempty" json:"metadata,omitempty"`
+	ExpectedStatus string            `yaml:"expected_status,omitempty" json:"expected_status,omitempty"` // Defaults to SERVING
+}
+
+// WebSocketConfig configures a WebSocket check. The endpoint URL is the ws://
+// or wss:// address and endpoint headers are sent with the upgrade request.
+// The reply must arrive within the endpoint timeout.
+type WebSocketConfig struct {
+	Send         string   `yaml:"send,omitempty" json:"send,omitempty"`     // Text message sent after the upgrade
+	Expect       string   `yaml:"expect,omitempty" json:"expect,omitempty"` // Regex a received message must match
+	Subprotocols []string `yaml:"subprotocols,omitempty" json:"subprotocols,omitempty"`
+}
+
+// SSEConfig configures a Server-Sent Events check. The check passes once a
+// matching event arrives within the endpoint timeout.
+type SSEConfig struct {
+	Event  string `yaml:"event,omitempty" json:"event,omitempty"`   // Event type to wait for, any type if empty
+	Expect string `yaml:"expect,omitempty" json:"expect,omitempty"` // Regex the event data must match
+}
+
+// PushConfig configures a passive heartbeat monitor. The monitored job calls
+// /api/v1/push/{token} and the endpoint fails when no ping arrives within
+// period (or the next cron occurrence of schedule) plus grace.
+type PushConfig struct {
+	Token      string        `yaml:"token,omitempty" json:"token,omitempty"`             // Generated when created through the API
+	Period     time.Duration `yaml:"period,omitempty" json:"period,omitempty"`           // Expected time between pings
+	Schedule   string        `yaml:"schedule,omitempty" json:"schedule,omitempty"`       // Cron expression, e.g. "0 2 * * *"; overrides period
+	Grace      time.Duration `yaml:"grace,omitempty" json:"grace,omitempty"`             // Allowed lateness
+	MaxRuntime time.Duration `yaml:"max_runtime,omitempty" json:"max_runtime,omitempty"` // Limit between start and finish pings
+}
+
+// DatabaseConfig configures a PostgreSQL, MySQL or Redis check. The endpoint
+// URL holds the server as host[:port]. Username and password are references
+// of the form "env:OCTO_NAME" or "file:path" so secrets stay out of the
+// config; the username may also be given literally. Variables must start
+// with OCTO_ and files lie under $OCTO_SECRETS_DIR (default /run/secrets).
+type DatabaseConfig struct {
+	Username string `yaml:"username,omitempty" json:"username,omitempty"`
+	Password string `yaml:"password,omitempty" json:"password,omitempty"`
+	Database string `yaml:"database,omitempty" json:"database,omitempty"` // Database name, or index for Redis
+	Query    string `yaml:"query,omitempty" json:"query,omitempty"`       // Defaults to "SELECT 1", or "PING" for Redis
+	Expect   string `yaml:"expect,omitempty" json:"expect,omitempty"`     // Regex the first value of the result must match
+	TLS      bool   `yaml:"tls,omitempty" json:"tls,omitempty"`
+}
+
+// ServiceConfig configures SMTP, IMAP, FTP and SFTP checks. The endpoint URL
+// holds the server as host[:port]; the smtps://, imaps:// and ftps:// schemes
+// imply TLS. Credentials use the same references as DatabaseConfig.
+type ServiceConfig struct {
+	Username string `yaml:"username,omitempty" json:"username,omitempty"`
+	Password string `yaml:"password,omitempty" json:"password,omitempty"`
+	TLS      bool   `yaml:"tls,omitempty" json:"tls,omitempty"`           // TLS from the start of the connection
+	StartTLS bool   `yaml:"starttls,omitempty" json:"starttls,omitempty"` // Upgrade a plaintext SMTP, IMAP or FTP connection
+	Expect   string `yaml:"expect,omitempty" json:"expect,omitempty"`     // Regex the greeting must match (SMTP, IMAP, SFTP)
+	Path     string `yaml:"path,omitempty" json:"path,omitempty"`         // Directory listed by FTP and SFTP checks
+	HostKey  string `yaml:"host_key,omitempty" json:"host_key,omitempty"` // SFTP host key, "SHA256:..." as printed by ssh-keygen -l; required
+}
+
+// PageConfig configures a page check, which loads the same-origin scripts,
+// stylesheets and images of an HTML page
+type PageConfig struct {
+	Concurrency int `yaml:"concurrency,omitempty" json:"concurrency,omitempty"` // Assets fetched at once, default 6
+	MaxAssets   int `yaml:"max_assets,omitempty" json:"max_assets,omitempty"`   // Assets fetched at most, default 100
+}
+
+// DomainConfig configures a domain registration check. The endpoint URL
+// holds a URL or host name, checked at its registrable domain.
+type DomainConfig struct {
+	RDAPServer  string `yaml:"rdap_server,omitempty" json:"rdap_server,omitempty"`   // Base URL, e.g. "https://rdap.verisign.com/com/v1"; from the IANA registry if empty
+	WHOISServer string `yaml:"whois_server,omitempty" json:"whois_server,omitempty"` // host[:port] queried when RDAP fails; referred by whois.iana.org if empty
+}
+
+// DomainExpiryConfig monitors the registration of the domains of the
+// endpoints. Each domain is checked once per interval and alerts are sent
+// to the channels once per threshold as its expiration date comes closer.
+type DomainExpiryConfig struct {
+	Enabled      bool          `yaml:"enabled" json:"enabled"`
+	Interval     time.Duration `yaml:"interval,omitempty" json:"interval,omitempty"`     // Default 12h
+	AlertDays    []int         `yaml:"alert_days,omitempty" json:"alert_days,omitempty"` // Default 30, 14, 7 and 1
+	Severity     string        `yaml:"severity,omitempty" json:"severity,omitempty"`
+	Channels     []string      `yaml:"channels,omitempty" json:"channels,omitempty"`
+	Exclude      []string      `yaml:"exclude,omitempty" json:"exclude,omitempty"` // Registrable domains not checked
+	DomainConfig `yaml:",inline"`
+}
+
+// CTLogConfig configures a Certificate Transparency log check. The endpoint
+// URL holds the base URL of an RFC 6962 log, e.g.
+// "https://ct.googleapis.com/logs/us1/argon2025h2/". Each check reads the
+// entries added since the previous one, starting from the tree size at the
+// first check, and fails when a certificate for one of the domains has an
+// issuer outside AllowedIssuers. Entries not read within the timeout are
+// reported as ct_backlog and read by the next check.
+type CTLogConfig struct {
+	Domains        []string `yaml:"domains,omitempty" json:"domains,omitempty"`                 // Subdomains included
+	AllowedIssuers []string `yaml:"allowed_issuers,omitempty" json:"allowed_issuers,omitempty"` // As in SSLConfig; any issuer when empty
+	MaxEntries     int      `yaml:"max_entries,omitempty" json:"max_entries,omitempty"`         // Entries asked per request, default 1000
+}
+
+// CertTransparencyConfig polls Certificate Transparency logs and alerts the
+// channels when a certificate for the domains is issued by a CA outside
+// the allow-list. The domains default to the registrable domains of the
+// endpoints.
+type CertTransparencyConfig struct {
+	Enabled     bool          `yaml:"enabled" json:"enabled"`
+	Logs        []string      `yaml:"logs,omitempty" json:"logs,omitempty"`         // Base URLs of the logs
+	Interval    time.Duration `yaml:"interval,omitempty" json:"interval,omitempty"` // Default 5m
+	Severity    string        `yaml:"severity,omitempty" json:"severity,omitempty"`
+	Channels    []string      `yaml:"channels,omitempty" json:"channels,omitempty"`
+	CTLogConfig `yaml:",inline"`
+}
+
+// DNSConfig configures a DNS resolution check. The endpoint URL holds the name to resolve.
+type DNSConfig struct {
+	RecordType string   `yaml:"record_type,omitempty" json:"record_type,omitempty"` // A (default), AAAA, CNAME, MX, NS or TXT
+	Expected   []string `yaml:"expected,omitempty" json:"expected,omitempty"`       // Answers that must all be present
+	Resolver   string   `yaml:"resolver,omitempty" json:"resolver,omitempty"`       // host[:port], system resolver if empty
 }
 
 type ValidationConfig struct {
 	StatusCodes  []int        `yaml:"status_codes" json:"status_codes"`
 	ContentMatch ContentMatch `yaml:"content_match" json:"content_match"`
+
+	// JSONAssertions are evaluated against the decoded body,
+	// e.g. `$.status == "ok"`, `$.items.length > 0` or `$.version matches ^2\.`
+	JSONAssertions []string `yaml:"json_assertions,omitempty" json:"json_assertions,omitempty"`
+	JSONSchema     string   `yaml:"json_schema,omitempty" json:"json_schema,omitempty"` // JSON Schema file under $OCTO_SCHEMAS_DIR (default /etc/octo/schemas)
+
+	Headers         []HeaderAssertion `yaml:"headers,omitempty" json:"headers,omitempty"`
+	BodySize        BodySizeRange     `yaml:"body_size,omitempty" json:"body_size,omitempty"`
+	Checksum        ChecksumConfig    `yaml:"checksum,omitempty" json:"checksum,omitempty"`
+	ContentNotMatch ContentMatch      `yaml:"content_not_match,omitempty" json:"content_not_match,omitempty"` // The body must NOT match
+	HTTPVersion     string            `yaml:"http_version,omitempty" json:"http_version,omitempty"`           // Negotiated version the response must use, "1.1" or "2"
+	Cookies         []CookieAssertion `yaml:"cookies,omitempty" json:"cookies,omitempty"`                     // Attributes of Set-Cookie headers, redirects included
+
+	// Script is the body of a JavaScript function given the response
+	// (status, headers, body, json(), timings). It fails the check by
+	// throwing or returning false, and may return {pass, message, metrics}
+	// to record custom metrics. Scripts have no I/O, are interrupted after
+	// ScriptTimeout (100ms, at most MaxScriptTimeout) and fail once they
+	// allocate more than ScriptMemory (64 MiB, at most MaxScriptMemory).
+	Script        string        `yaml:"script,omitempty" json:"script,omitempty"`
+	ScriptTimeout time.Duration `yaml:"script_timeout,omitempty" json:"script_timeout,omitempty"`
+	ScriptMemory  int64         `yaml:"script_memory,omitempty" json:"script_memory,omitempty"` // Bytes
+}
+
+// Hard limits of validation scripts
+const (
+	MaxScriptTimeout = 5 * time.Second
+	MaxScriptMemory  = 256 << 20
+)
+
+// Validate rejects limits beyond the hard limits of validation scripts
+func (v ValidationConfig) Validate() error {
+	if v.ScriptTimeout < 0 || v.ScriptTimeout > MaxScriptTimeout {
+		return fmt.Errorf("script_timeout must be between 0 and %v", MaxScriptTimeout)
+	}
+	if v.ScriptMemory < 0 || v.ScriptMemory > MaxScriptMemory {
+		return fmt.Errorf("script_memory must be between 0 and %d bytes", MaxScriptMemory)
+	}
+	return nil
 }
 
 type ContentMatch struct {
-	Type    string `yaml:"type" json:"type"` // "regex" or "exact"
+	Type    string `yaml:"type" json:"type"` // "regex", "exact", "xpath" or "css"
 	Pattern string `yaml:"pattern" json:"pattern"`
 }
 
+// HeaderAssertion checks a response header. With neither Equals nor Matches
+// set, the header only has to be present.
+type HeaderAssertion struct {
+	Name    string `yaml:"name" json:"name"`
+	Equals  string `yaml:"equals,omitempty" json:"equals,omitempty"`
+	Matches string `yaml:"matches,omitempty" json:"matches,omitempty"` // Regex
+}
+
+// CookieAssertion checks the attributes of a cookie set by the response or
+// one of its redirects. An empty name applies the assertion to every cookie.
+type CookieAssertion struct {
+	Name     string `yaml:"name,omitempty" json:"name,omitempty"`
+	Secure   bool   `yaml:"secure,omitempty" json:"secure,omitempty"`
+	HTTPOnly bool   `yaml:"http_only,omitempty" json:"http_only,omitempty"`
+	SameSite string `yaml:"same_site,omitempty" json:"same_site,omitempty"` // "Strict", "Lax" or "None"
+}
+
+type BodySizeRange struct {
+	Min int64 `yaml:"min,omitempty" json:"min,omitempty"` // Bytes
+	Max int64 `yaml:"max,omitempty" json:"max,omitempty"` // Bytes, 0 means unbounded
+}
+
+type ChecksumConfig struct {
+	Algorithm string `yaml:"algorithm,omitempty" json:"algorithm,omitempty"` // "sha256" or "md5"
+	Value     string `yaml:"value,omitempty" json:"value,omitempty"`         // Hex encoded digest
+}
+
 type SatelliteConfig struct {
 	ID         string            `yaml:"id" json:"id"`
 	Name       string            `yaml:"name" json:"name"`
 	URL        string            `yaml:"url,omitempty" json:"url,omitempty"`
 	APIKeyHash string            `yaml:"api_key_hash" json:"api_key_hash"`
 	Location   SatelliteLocation `yaml:"location" json:"location"`
+	Proxy      ProxyConfig       `yaml:"proxy,omitempty" json:"proxy,omitempty"` // Egress proxy of the satellite's network
 }
 
 type SatelliteLocation struct {
@@ -79,6 +436,23 @@ type SatelliteLocation struct {
 
 type SSLConfig struct {
 	ExpirationAlertDays []int `yaml:"expiration_alert_days" json:"expiration_alert_days"`
+
+	// Assertions on the negotiated connection and presented certificate
+	MinTLSVersion string   `yaml:"min_tls_version,omitempty" json:"min_tls_version,omitempty"` // e.g. "1.2"
+	RequiredSANs  []string `yaml:"required_sans,omitempty" json:"required_sans,omitempty"`
+	IssuerPattern string   `yaml:"issuer_pattern,omitempty" json:"issuer_pattern,omitempty"` // Regex matched against the leaf issuer DN
+
+	// Certificates expected from our CAs only. The leaf issuer must match
+	// one of AllowedIssuers by common name, organization or full DN, case
+	// insensitively, and a certificate of the verified chain must match one
+	// of the SPKIPins, "sha256/<base64>" hashes of its public key as in HPKP.
+	// Mismatches fail the check and set cert_mismatch.
+	AllowedIssuers []string `yaml:"allowed_issuers,omitempty" json:"allowed_issuers,omitempty"`
+	SPKIPins       []string `yaml:"spki_pins,omitempty" json:"spki_pins,omitempty"`
+
+	// OCSPCheck queries the OCSP responder from the certificate's AIA extension
+	// when the server did not staple a response
+	OCSPCheck bool `yaml:"ocsp_check,omitempty" json:"ocsp_check,omitempty"`
 }
 
 type AlertChannel struct {
@@ -138,6 +512,9 @@ func (m *Manager) Load() error {
 	if err := decoder.Decode(&cfg); err != nil {
 		return fmt.Errorf("failed to decode config file: %w", err)
 	}
+	if err := cfg.Validate(); err != nil {
+		return fmt.Errorf("invalid config: %w", err)
+	}
 
 	// Set defaults if needed
 	if cfg.Global.CheckInterval == 0 {
@@ -215,6 +592,9 @@ func (m *Manager) UpdateConfig(updater func(*Config) error) error {
 	// For simplicity in MVP, we modify in place but rollback on save error?
 	// Actually, simpler: just let updater modify.
 	err := updater(m.config)
+	if err == nil {
+		err = m.config.Validate()
+	}
 	m.mu.Unlock()
 
 	if err != nil {
@@ -0,0 +1,184 @@
+package push
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/robfig/cron/v3"
+
+	"github.com/manu/octo/pkg/checker"
+	"github.com/manu/octo/pkg/config"
+)
+
+// ErrUnknownToken is returned for pings whose token matches no push endpoint
+var ErrUnknownToken = errors.New("unknown push token")
+
+// Ping is a report sent by a monitored job when it finishes
+type Ping struct {
+	Success  bool
+	Duration time.Duration // Run time reported by the job, zero if unknown
+	Message  string
+}
+
+// state holds the last pings received for one endpoint
+type state struct {
+	lastPing  time.Time
+	last      Ping
+	startedAt time.Time // Set by a start ping until the run finishes
+}
+
+// Manager records the pings of push endpoints. It implements checker.Prober
+// so the scheduler reports missing or failed runs like any other check.
+type Manager struct {
+	mu      sync.Mutex
+	states  map[string]*state
+	cfgMgr  *config.Manager
+	started time.Time
+	now     func() time.Time
+}
+
+// NewManager creates a push manager. Endpoints that have never been pinged
+// are expected to ping within their window counted from now.
+func NewManager(cfgMgr *config.Manager) *Manager {
+	return &Manager{
+		states:  make(map[string]*state),
+		cfgMgr:  cfgMgr,
+		started: time.Now(),
+		now:     time.Now,
+	}
+}
+
+// NewToken returns a random token for a push URL
+func NewToken() string {
+	return rand.Text()
+}
+
+// Start records the start of a run
+func (m *Manager) Start(token string) error {
+	endpoint, ok := m.endpointForToken(token)
+	if !ok {
+		return ErrUnknownToken
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.stateFor(endpoint.ID).startedAt = m.now()
+	return nil
+}
+
+// Finish records a completed run. Without a reported duration, the time
+// since the start ping is used.
+func (m *Manager) Finish(token string, ping Ping) error {
+	endpoint, ok := m.endpointForToken(token)
+	if !ok {
+		return ErrUnknownToken
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	st := m.stateFor(endpoint.ID)
+	if ping.Duration == 0 && !st.startedAt.IsZero() {
+		ping.Duration = now.Sub(st.startedAt)
+	}
+	st.lastPing = now
+	st.last = ping
+	st.startedAt = time.Time{}
+	return nil
+}
+
+// Probe reports whether the endpoint has been pinged in time and whether its
+// last run succeeded
+func (m *Manager) Probe(ctx context.Context, endpoint config.EndpointConfig) checker.Result {
+	now := m.now()
+	result := checker.Result{
+		Timestamp:  now,
+		EndpointID: endpoint.ID,
+		URL:        endpoint.URL,
+	}
+
+	m.mu.Lock()
+	var st state
+	if s, ok := m.states[endpoint.ID]; ok {
+		st = *s
+	}
+	m.mu.Unlock()
+
+	since := m.started
+	if !st.lastPing.IsZero() {
+		since = st.lastPing
+		result.Duration = st.last.Duration
+	}
+
+	deadline, err := expectedBy(endpoint.Push, since)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	// A run still within its max runtime is not late, whatever the deadline
+	running := !st.startedAt.IsZero() && endpoint.Push.MaxRuntime > 0
+	switch {
+	case running && now.Sub(st.startedAt) > endpoint.Push.MaxRuntime:
+		result.Error = fmt.Sprintf("run started at %s has not finished within %s",
+			st.startedAt.Format(time.RFC3339), endpoint.Push.MaxRuntime)
+	case !running && now.After(deadline) && st.lastPing.IsZero():
+		result.Error = fmt.Sprintf("no ping received, expected by %s", deadline.Format(time.RFC3339))
+	case !running && now.After(deadline):
+		result.Error = fmt.Sprintf("no ping received since %s, expected by %s",
+			st.lastPing.Format(time.RFC3339), deadline.Format(time.RFC3339))
+	case !st.lastPing.IsZero() && !st.last.Success:
+		result.Error = "job reported failure"
+		if st.last.Message != "" {
+			result.Error += ": " + st.last.Message
+		}
+	default:
+		result.Success = true
+	}
+
+	return result
+}
+
+// expectedBy returns the latest time the ping following one at since may
+// arrive
+func expectedBy(cfg config.PushConfig, since time.Time) (time.Time, error) {
+	if cfg.Schedule != "" {
+		schedule, err := cron.ParseStandard(cfg.Schedule)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid schedule %q: %w", cfg.Schedule, err)
+		}
+		return schedule.Next(since).Add(cfg.Grace), nil
+	}
+	if cfg.Period <= 0 {
+		return time.Time{}, fmt.Errorf("push endpoint requires a period or schedule")
+	}
+	return since.Add(cfg.Period + cfg.Grace), nil
+}
+
+func (m *Manager) endpointForToken(token string) (config.EndpointConfig, bool) {
+	if token == "" {
+		return config.EndpointConfig{}, false
+	}
+	for _, ep := range m.cfgMgr.GetConfig().Endpoints {
+		if ep.Type == checker.TypePush && ep.Push.Token == token {
+			return ep, true
+		}
+	}
+	return config.EndpointConfig{}, false
+}
+
+// stateFor returns the state of an endpoint, creating it if needed.
+// The caller must hold m.mu.
+func (m *Manager) stateFor(endpointID string) *state {
+	st, ok := m.states[endpointID]
+	if !ok {
+		st = &state{}
+		m.states[endpointID] = st
+	}
+	return st
+}
@@ -0,0 +1,189 @@
+package push
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/manu/octo/pkg/config"
+)
+
+const testConfig = `
+endpoints:
+  - id: backup
+    name: Nightly Backup
+    type: push
+    push:
+      token: backup-token
+      schedule: "0 2 * * *"
+      grace: 30m
+      max_runtime: 1h
+  - id: worker
+    name: Batch Worker
+    type: push
+    push:
+      token: worker-token
+      period: 5m
+      grace: 1m
+  - id: api
+    name: API
+    url: https://example.com
+`
+
+func newTestManager(t *testing.T) (*Manager, *time.Time) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yml")
+	if err := os.WriteFile(path, []byte(testConfig), 0o600); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	cfgMgr, err := config.NewManager(path)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	now := time.Date(2026, 3, 10, 1, 0, 0, 0, time.UTC)
+	m := NewManager(cfgMgr)
+	m.started = now
+	m.now = func() time.Time { return now }
+	return m, &now
+}
+
+func endpointByID(t *testing.T, m *Manager, id string) config.EndpointConfig {
+	t.Helper()
+	for _, ep := range m.cfgMgr.GetConfig().Endpoints {
+		if ep.ID == id {
+			return ep
+		}
+	}
+	t.Fatalf("Endpoint %s not found", id)
+	return config.EndpointConfig{}
+}
+
+func TestManager_Period(t *testing.T) {
+	m, now := newTestManager(t)
+	worker := endpointByID(t, m, "worker")
+
+	if result := m.Probe(context.Background(), worker); !result.Success {
+		t.Fatalf("Expected success before the first window ends, got %q", result.Error)
+	}
+
+	*now = now.Add(7 * time.Minute)
+	result := m.Probe(context.Background(), worker)
+	if result.Success || !strings.HasPrefix(result.Error, "no ping received, expected by") {
+		t.Fatalf("Expected missing ping, got success=%v error=%q", result.Success, result.Error)
+	}
+
+	if err := m.Finish("worker-token", Ping{Success: true, Duration: 12 * time.Second}); err != nil {
+		t.Fatalf("Finish failed: %v", err)
+	}
+	result = m.Probe(context.Background(), worker)
+	if !result.Success || result.Duration != 12*time.Second {
+		t.Fatalf("Expected success with reported duration, got success=%v duration=%v error=%q", result.Success, result.Duration, result.Error)
+	}
+
+	*now = now.Add(5*time.Minute + 59*time.Second)
+	if result := m.Probe(context.Background(), worker); !result.Success {
+		t.Errorf("Expected success within grace, got %q", result.Error)
+	}
+	*now = now.Add(2 * time.Second)
+	if result := m.Probe(context.Background(), worker); result.Success || !strings.Contains(result.Error, "no ping received since") {
+		t.Errorf("Expected late ping, got success=%v error=%q", result.Success, result.Error)
+	}
+
+	if err := m.Finish("worker-token", Ping{Success: false, Message: "exit status 3"}); err != nil {
+		t.Fatalf("Finish failed: %v", err)
+	}
+	if result := m.Probe(context.Background(), worker); result.Success || result.Error != "job reported failure: exit status 3" {
+		t.Errorf("Expected reported failure, got success=%v error=%q", result.Success, result.Error)
+	}
+}
+
+func TestManager_ScheduleAndRuntime(t *testing.T) {
+	m, now := newTestManager(t)
+	backup := endpointByID(t, m, "backup")
+
+	// Started at 01:00, the first run is due at 02:00 with 30 minutes grace
+	*now = time.Date(2026, 3, 10, 2, 20, 0, 0, time.UTC)
+	if err := m.Start("backup-token"); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	if result := m.Probe(context.Background(), backup); !result.Success {
+		t.Fatalf("Expected success while running, got %q", result.Error)
+	}
+
+	*now = now.Add(90 * time.Minute)
+	result := m.Probe(context.Background(), backup)
+	if result.Success || !strings.Contains(result.Error, "has not finished within 1h0m0s") {
+		t.Fatalf("Expected overrun, got success=%v error=%q", result.Success, result.Error)
+	}
+
+	if err := m.Finish("backup-token", Ping{Success: true}); err != nil {
+		t.Fatalf("Finish failed: %v", err)
+	}
+	result = m.Probe(context.Background(), backup)
+	if !result.Success || result.Duration != 90*time.Minute {
+		t.Fatalf("Expected success with measured duration, got success=%v duration=%v", result.Success, result.Duration)
+	}
+
+	// Next run is due the following night
+	*now = time.Date(2026, 3, 11, 2, 29, 0, 0, time.UTC)
+	if result := m.Probe(context.Background(), backup); !result.Success {
+		t.Errorf("Expected success before the next deadline, got %q", result.Error)
+	}
+	*now = time.Date(2026, 3, 11, 2, 31, 0, 0, time.UTC)
+	if result := m.Probe(context.Background(), backup); result.Success {
+		t.Error("Expected failure after the next deadline")
+	}
+}
+
+func TestManager_LongRunBeyondDeadline(t *testing.T) {
+	m, now := newTestManager(t)
+	backup := endpointByID(t, m, "backup")
+
+	// Started before the 02:30 deadline, finishing within the hour is fine
+	*now = time.Date(2026, 3, 10, 2, 10, 0, 0, time.UTC)
+	if err := m.Start("backup-token"); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	*now = time.Date(2026, 3, 10, 2, 50, 0, 0, time.UTC)
+	if result := m.Probe(context.Background(), backup); !result.Success {
+		t.Fatalf("Expected success while running past the deadline, got %q", result.Error)
+	}
+
+	*now = time.Date(2026, 3, 10, 3, 20, 0, 0, time.UTC)
+	result := m.Probe(context.Background(), backup)
+	if result.Success || !strings.Contains(result.Error, "has not finished within 1h0m0s") {
+		t.Fatalf("Expected overrun, got success=%v error=%q", result.Success, result.Error)
+	}
+}
+
+func TestManager_UnknownToken(t *testing.T) {
+	m, _ := newTestManager(t)
+
+	for _, token := range []string{"", "nope"} {
+		if err := m.Finish(token, Ping{Success: true}); err != ErrUnknownToken {
+			t.Errorf("Finish(%q): expected ErrUnknownToken, got %v", token, err)
+		}
+		if err := m.Start(token); err != ErrUnknownToken {
+			t.Errorf("Start(%q): expected ErrUnknownToken, got %v", token, err)
+		}
+	}
+}
+
+func TestManager_InvalidConfig(t *testing.T) {
+	m, _ := newTestManager(t)
+
+	tests := map[string]config.PushConfig{
+		"push endpoint requires a period or schedule": {},
+		`invalid schedule "every night"`:              {Schedule: "every night"},
+	}
+	for wantErr, cfg := range tests {
+		result := m.Probe(context.Background(), config.EndpointConfig{ID: "x", Type: "push", Push: cfg})
+		if result.Success || !strings.HasPrefix(result.Error, wantErr) {
+			t.Errorf("Expected error %q, got success=%v error=%q", wantErr, result.Success, result.Error)
+		}
+	}
+}
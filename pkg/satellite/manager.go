@@ -1,6 +1,7 @@
 package satellite
 
 import (
+	"slices"
 	"sync"
 	"time"
 
@@ -21,6 +22,15 @@ type SatelliteState struct {
 	LastHeartbeat time.Time              `json:"last_heartbeat"`
 	Status        Status                 `json:"status"`
 	Config        config.SatelliteConfig `json:"config"`
+
+	// IDs of the endpoints to run out of schedule, handed to the satellite
+	// with the reply to its next heartbeat
+	pendingRuns []string
+}
+
+// HeartbeatResponse is the reply of the master to a satellite heartbeat
+type HeartbeatResponse struct {
+	Run []string `json:"run,omitempty"` // IDs of endpoints to check now
 }
 
 // Manager handles the lifecycle and state of satellites
@@ -91,6 +101,36 @@ func (m *Manager) RegisterHeartbeat(id string) {
 	}
 }
 
+// QueueRun asks a satellite to run an endpoint when it next sends a
+// heartbeat. It reports false for an unknown satellite.
+func (m *Manager) QueueRun(id, endpointID string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	sat, exists := m.satellites[id]
+	if !exists {
+		return false
+	}
+	if !slices.Contains(sat.pendingRuns, endpointID) {
+		sat.pendingRuns = append(sat.pendingRuns, endpointID)
+	}
+	return true
+}
+
+// TakeRuns returns the endpoints queued for a satellite and clears its queue
+func (m *Manager) TakeRuns(id string) []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	sat, exists := m.satellites[id]
+	if !exists {
+		return nil
+	}
+	runs := sat.pendingRuns
+	sat.pendingRuns = nil
+	return runs
+}
+
 // MarkOffline marks a satellite as offline (e.g. missed heartbeats)
 // This could be called by a background ticker
 func (m *Manager) CheckOffline(timeout time.Duration) {
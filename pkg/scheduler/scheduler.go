@@ -3,6 +3,8 @@ package scheduler
 import (
 	"context"
 	"log"
+	"slices"
+	"strings"
 	"sync"
 	"time"
 
@@ -12,6 +14,19 @@ import (
 	"github.com/manu/octo/pkg/storage"
 )
 
+// Defaults of the domain expiry checks, which are slow to change and
+// rate limited by registries
+const (
+	defaultDomainInterval = 12 * time.Hour
+	domainCheckTimeout    = 30 * time.Second
+)
+
+// Defaults of the Certificate Transparency log checks
+const (
+	defaultCTInterval = 5 * time.Minute
+	ctCheckTimeout    = time.Minute
+)
+
 type Scheduler struct {
 	cfgManager   *config.Manager
 	checker      *checker.Checker
@@ -37,6 +52,11 @@ func NewScheduler(cfgMgr *config.Manager, store storage.Provider) *Scheduler {
 	}
 }
 
+// RegisterProber adds or replaces the prober used for a check type
+func (s *Scheduler) RegisterProber(checkType string, p checker.Prober) {
+	s.checker.RegisterProber(checkType, p)
+}
+
 func (s *Scheduler) Start() {
 	// Start initial set of workers
 	s.restartWorkers()
@@ -58,15 +78,134 @@ func (s *Scheduler) Stop() {
 func (s *Scheduler) restartWorkers() {
 	cfg := s.cfgManager.GetConfig()
 
+	// Sessions of endpoints removed from the config are not needed anymore
+	var ids []string
 	for _, endpoint := range cfg.Endpoints {
-		if shouldRunOnMaster(endpoint) {
+		ids = append(ids, endpoint.ID)
+	}
+	s.checker.RetainCookieJars(ids)
+
+	for _, endpoint := range cfg.Endpoints {
+		if RunsOnMaster(endpoint) {
+			endpoint.Proxy = cfg.ProxyFor(endpoint, "")
+			s.wg.Add(1)
+			go s.runWorker(endpoint)
+		}
+	}
+
+	if cfg.DomainExpiry.Enabled {
+		for _, endpoint := range domainEndpoints(&cfg) {
+			s.wg.Add(1)
+			go s.runWorker(endpoint)
+		}
+	}
+
+	if cfg.CertTransparency.Enabled {
+		for _, endpoint := range ctEndpoints(&cfg) {
 			s.wg.Add(1)
 			go s.runWorker(endpoint)
 		}
 	}
 }
 
-func shouldRunOnMaster(endpoint config.EndpointConfig) bool {
+// endpointDomains returns the distinct registrable domains among the
+// endpoint and step URLs, sorted
+func endpointDomains(cfg *config.Config) []string {
+	var domains []string
+	for _, endpoint := range cfg.Endpoints {
+		switch endpoint.Type {
+		case checker.TypePush, checker.TypeDomain, checker.TypeCT:
+			continue
+		}
+		targets := []string{endpoint.URL}
+		for _, step := range endpoint.Steps {
+			targets = append(targets, step.URL)
+		}
+		for _, target := range targets {
+			if domain, ok := checker.RegistrableDomain(target); ok && !slices.Contains(domains, domain) {
+				domains = append(domains, domain)
+			}
+		}
+	}
+	slices.Sort(domains)
+	return domains
+}
+
+// domainEndpoints returns a domain check for each distinct registrable
+// domain among the endpoint URLs, except the excluded ones and those
+// already checked by an endpoint of type domain
+func domainEndpoints(cfg *config.Config) []config.EndpointConfig {
+	interval := cfg.DomainExpiry.Interval
+	if interval == 0 {
+		interval = defaultDomainInterval
+	}
+
+	seen := make(map[string]bool)
+	for _, domain := range cfg.DomainExpiry.Exclude {
+		seen[domain] = true
+	}
+	for _, endpoint := range cfg.Endpoints {
+		if endpoint.Type == checker.TypeDomain {
+			if domain, ok := checker.RegistrableDomain(endpoint.URL); ok {
+				seen[domain] = true
+			}
+		}
+	}
+
+	var endpoints []config.EndpointConfig
+	for _, domain := range endpointDomains(cfg) {
+		if seen[domain] {
+			continue
+		}
+		endpoints = append(endpoints, config.EndpointConfig{
+			ID:       "domain:" + domain,
+			Name:     domain,
+			Type:     checker.TypeDomain,
+			URL:      domain,
+			Interval: interval,
+			Timeout:  domainCheckTimeout,
+			Domain:   cfg.DomainExpiry.DomainConfig,
+		})
+	}
+	return endpoints
+}
+
+// ctEndpoints returns a check for each Certificate Transparency log,
+// watching the registrable domains of the endpoints unless domains are set
+func ctEndpoints(cfg *config.Config) []config.EndpointConfig {
+	interval := cfg.CertTransparency.Interval
+	if interval == 0 {
+		interval = defaultCTInterval
+	}
+	ct := cfg.CertTransparency.CTLogConfig
+	if len(ct.Domains) == 0 {
+		ct.Domains = endpointDomains(cfg)
+	}
+
+	var endpoints []config.EndpointConfig
+	for _, logURL := range cfg.CertTransparency.Logs {
+		name := strings.TrimSuffix(strings.TrimPrefix(logURL, "https://"), "/")
+		endpoints = append(endpoints, config.EndpointConfig{
+			ID:       "ct:" + name,
+			Name:     "CT log " + name,
+			Type:     checker.TypeCT,
+			URL:      logURL,
+			Interval: interval,
+			Timeout:  ctCheckTimeout,
+			CT:       ct,
+		})
+	}
+	return endpoints
+}
+
+// RunsOnMaster reports whether the master checks the endpoint, rather than
+// only its satellites
+func RunsOnMaster(endpoint config.EndpointConfig) bool {
+	// Pings are received by the master only
+	if endpoint.Type == checker.TypePush {
+		return true
+	}
+
 	// Default: Run on Master if no satellites specified
 	if len(endpoint.Satellites) == 0 {
 		return true
@@ -93,6 +232,8 @@ func (s *Scheduler) runWorker(endpoint config.EndpointConfig) {
 	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
+	s.restoreState(endpoint)
+
 	// Run immediately
 	s.executeCheck(endpoint)
 
@@ -106,26 +247,116 @@ func (s *Scheduler) runWorker(endpoint config.EndpointConfig) {
 	}
 }
 
+// restoreState seeds the checker with what the last stored checks of an
+// endpoint recorded, so drift detection and Certificate Transparency log
+// reads carry on across restarts
+func (s *Scheduler) restoreState(endpoint config.EndpointConfig) {
+	if endpoint.Type == checker.TypeCT {
+		state, err := s.storage.QueryState(s.ctx, endpoint.ID, "")
+		if err != nil {
+			log.Printf("Failed to load the state of %s: %v", endpoint.ID, err)
+			return
+		}
+		if state.CTPosition > 0 {
+			s.checker.SeedCTPosition(endpoint.ID, state.CTPosition)
+		}
+		return
+	}
+	if !endpoint.Drift.Enabled {
+		return
+	}
+
+	families := []string{endpoint.IPVersion}
+	if endpoint.IPVersion == "both" {
+		families = []string{"4", "6"}
+	}
+	for _, family := range families {
+		ep := endpoint
+		ep.IPVersion = family
+		state, err := s.storage.QueryState(s.ctx, ep.ID, family)
+		if err != nil {
+			log.Printf("Failed to load the state of %s: %v", ep.ID, err)
+			return
+		}
+		if state.ContentHash != "" {
+			if err := s.checker.SeedDriftBaseline(ep, state.ContentReference, state.ContentHash); err != nil {
+				log.Printf("Failed to restore the drift baseline of %s: %v", ep.ID, err)
+			}
+		}
+	}
+}
+
 func (s *Scheduler) executeCheck(endpoint config.EndpointConfig) {
-	ctx, cancel := context.WithTimeout(s.ctx, endpoint.Timeout)
+	s.run(s.ctx, endpoint)
+}
+
+// Check runs an endpoint now without storing the results or evaluating
+// alerts, e.g. to try an endpoint before saving it. Such checks keep their
+// own persistent cookie jar and drift baseline, apart from the scheduled ones.
+func (s *Scheduler) Check(ctx context.Context, endpoint config.EndpointConfig) []checker.Result {
+	cfg := s.cfgManager.GetConfig()
+	endpoint.Proxy = cfg.ProxyFor(endpoint, "")
+	id := endpoint.ID
+	endpoint.ID = "check:" + id
+
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout(endpoint))
+	defer cancel()
+	results := s.checker.CheckAll(ctx, endpoint)
+	for i := range results {
+		results[i].EndpointID = id
+	}
+	return results
+}
+
+// Run checks a configured endpoint out of schedule, storing the results
+// and evaluating alerts like a scheduled check
+func (s *Scheduler) Run(ctx context.Context, endpoint config.EndpointConfig) []checker.Result {
+	cfg := s.cfgManager.GetConfig()
+	endpoint.Proxy = cfg.ProxyFor(endpoint, "")
+	return s.run(ctx, endpoint)
+}
+
+func checkTimeout(endpoint config.EndpointConfig) time.Duration {
 	if endpoint.Timeout == 0 {
-		ctx, cancel = context.WithTimeout(s.ctx, 10*time.Second)
+		return 10 * time.Second
 	}
+	return endpoint.Timeout
+}
+
+func (s *Scheduler) run(ctx context.Context, endpoint config.EndpointConfig) []checker.Result {
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout(endpoint))
 	defer cancel()
 
-	result := s.checker.Check(ctx, endpoint)
+	results := s.checker.CheckAll(ctx, endpoint)
 
-	// Log result
-	if result.Success {
-		log.Printf("Check passed: %s (%s) - %v", endpoint.Name, endpoint.URL, result.Duration)
-	} else {
-		log.Printf("Check failed: %s (%s) - %s", endpoint.Name, endpoint.URL, result.Error)
-	}
+	for _, result := range results {
+		// Log result
+		if result.Success {
+			log.Printf("Check passed: %s (%s) - %v", endpoint.Name, endpoint.URL, result.Duration)
+		} else {
+			log.Printf("Check failed: %s (%s) - %s", endpoint.Name, endpoint.URL, result.Error)
+		}
 
-	if err := s.storage.WriteResult(result); err != nil {
-		log.Printf("Failed to write result to InfluxDB: %v", err)
+		if err := s.storage.WriteResult(result); err != nil {
+			log.Printf("Failed to write result to InfluxDB: %v", err)
+		}
 	}
 
-	// Evaluate Alerts
-	s.alertManager.Evaluate(ctx, endpoint, &result)
+	// Evaluate Alerts on the first failure, so a single broken address
+	// family is enough to alert
+	alertResult := results[0]
+	for _, result := range results {
+		if !result.Success {
+			alertResult = result
+			break
+		}
+	}
+	s.alertManager.Evaluate(ctx, endpoint, &alertResult)
+	switch endpoint.Type {
+	case checker.TypeDomain:
+		s.alertManager.EvaluateDomainExpiry(ctx, endpoint, &alertResult)
+	case checker.TypeCT:
+		s.alertManager.EvaluateCertTransparency(ctx, endpoint, &alertResult)
+	}
+	return results
 }
@@ -3,6 +3,8 @@ package postgres
 import (
 	"context"
 	"fmt"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/jackc/pgx/v5/pgxpool"
@@ -12,6 +14,11 @@ import (
 
 type PostgresStorage struct {
 	pool *pgxpool.Pool
+
+	// Certificate chains stored, as the fingerprints of their certificates
+	// keyed by leaf fingerprint, so unchanged chains are not written again
+	mu     sync.Mutex
+	chains map[string]string
 }
 
 func NewPostgresStorage(ctx context.Context, host, port, user, password, dbName string) (*PostgresStorage, error) {
@@ -26,7 +33,7 @@ func NewPostgresStorage(ctx context.Context, host, port, user, password, dbName
 		return nil, fmt.Errorf("unable to create connection pool: %w", err)
 	}
 
-	s := &PostgresStorage{pool: pool}
+	s := &PostgresStorage{pool: pool, chains: make(map[string]string)}
 	if err := s.init(ctx); err != nil {
 		pool.Close()
 		return nil, err
@@ -71,6 +78,40 @@ func (s *PostgresStorage) init(ctx context.Context) error {
 		"ALTER TABLE http_checks ADD COLUMN IF NOT EXISTS cert_not_before TIMESTAMPTZ",
 		"ALTER TABLE http_checks ADD COLUMN IF NOT EXISTS cert_not_after TIMESTAMPTZ",
 		"ALTER TABLE http_checks ADD COLUMN IF NOT EXISTS satellite_id TEXT",
+		"ALTER TABLE http_checks ADD COLUMN IF NOT EXISTS tls_version TEXT",
+		"ALTER TABLE http_checks ADD COLUMN IF NOT EXISTS cipher_suite TEXT",
+		"ALTER TABLE http_checks ADD COLUMN IF NOT EXISTS alpn TEXT",
+		"ALTER TABLE http_checks ADD COLUMN IF NOT EXISTS cert_sans TEXT[]",
+		"ALTER TABLE http_checks ADD COLUMN IF NOT EXISTS cert_key_type TEXT",
+		"ALTER TABLE http_checks ADD COLUMN IF NOT EXISTS cert_key_size INTEGER",
+		"ALTER TABLE http_checks ADD COLUMN IF NOT EXISTS cert_fingerprint TEXT",
+		"ALTER TABLE http_checks ADD COLUMN IF NOT EXISTS cert_chain JSONB",
+		"ALTER TABLE http_checks ADD COLUMN IF NOT EXISTS ocsp_status TEXT",
+		"ALTER TABLE http_checks ADD COLUMN IF NOT EXISTS check_type TEXT",
+		"ALTER TABLE http_checks ADD COLUMN IF NOT EXISTS failed_step TEXT",
+		"ALTER TABLE http_checks ADD COLUMN IF NOT EXISTS steps JSONB",
+		"ALTER TABLE http_checks ADD COLUMN IF NOT EXISTS write_ns BIGINT",
+		"ALTER TABLE http_checks ADD COLUMN IF NOT EXISTS transfer_ns BIGINT",
+		"ALTER TABLE http_checks ADD COLUMN IF NOT EXISTS conn_reused BOOLEAN",
+		"ALTER TABLE http_checks ADD COLUMN IF NOT EXISTS protocol TEXT",
+		"ALTER TABLE http_checks ADD COLUMN IF NOT EXISTS ip_version TEXT",
+		"ALTER TABLE http_checks ADD COLUMN IF NOT EXISTS proxy TEXT",
+		"ALTER TABLE http_checks ADD COLUMN IF NOT EXISTS snapshot JSONB",
+		"ALTER TABLE http_checks ADD COLUMN IF NOT EXISTS body_truncated BOOLEAN",
+		"ALTER TABLE http_checks ADD COLUMN IF NOT EXISTS metrics JSONB",
+		"ALTER TABLE http_checks ADD COLUMN IF NOT EXISTS assets JSONB",
+		"ALTER TABLE http_checks ADD COLUMN IF NOT EXISTS page_weight BIGINT",
+		"ALTER TABLE http_checks ADD COLUMN IF NOT EXISTS page_load_ns BIGINT",
+		"ALTER TABLE http_checks ADD COLUMN IF NOT EXISTS domain_expiry TIMESTAMPTZ",
+		"ALTER TABLE http_checks ADD COLUMN IF NOT EXISTS registrar TEXT",
+		"ALTER TABLE http_checks ADD COLUMN IF NOT EXISTS content_hash TEXT",
+		"ALTER TABLE http_checks ADD COLUMN IF NOT EXISTS content_similarity DOUBLE PRECISION",
+		"ALTER TABLE http_checks ADD COLUMN IF NOT EXISTS content_drift BOOLEAN",
+		"ALTER TABLE http_checks ADD COLUMN IF NOT EXISTS content TEXT",
+		"ALTER TABLE http_checks ADD COLUMN IF NOT EXISTS cert_mismatch BOOLEAN",
+		"ALTER TABLE http_checks ADD COLUMN IF NOT EXISTS ct_entries JSONB",
+		"ALTER TABLE http_checks ADD COLUMN IF NOT EXISTS ct_position BIGINT",
+		"ALTER TABLE http_checks ADD COLUMN IF NOT EXISTS ct_backlog BIGINT",
 	}
 
 	for _, query := range migrationQueries {
@@ -79,6 +120,19 @@ func (s *PostgresStorage) init(ctx context.Context) error {
 		}
 	}
 
+	// Certificate chains are stored once per leaf certificate rather than on
+	// every check, which only records the leaf fingerprint
+	_, err = s.pool.Exec(ctx, `
+		CREATE TABLE IF NOT EXISTS cert_chains (
+			fingerprint TEXT PRIMARY KEY,
+			chain JSONB NOT NULL,
+			updated_at TIMESTAMPTZ NOT NULL
+		);
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create table: %w", err)
+	}
+
 	// Convert to hypertable (ignore error if already hypertable)
 	// We use a DO block or simple query. TimescaleDB's create_hypertable fails if it already exists unless we handle it.
 	// The `if_not_exists => TRUE` parameter is available in recent versions.
@@ -96,13 +150,28 @@ func (s *PostgresStorage) Close() {
 }
 
 func (s *PostgresStorage) WriteResult(result checker.Result) error {
+	if err := s.writeCertChain(result); err != nil {
+		return err
+	}
+
 	_, err := s.pool.Exec(context.Background(), `
 		INSERT INTO http_checks (
 			time, endpoint_id, url, method, status_code, success,
 			duration_ns, dns_ns, conn_ns, tls_ns, ttfb_ns, bytes_received, error,
 			cert_expiry, cert_issuer, cert_subject, cert_not_before, cert_not_after,
-			satellite_id
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19)
+			satellite_id,
+			tls_version, cipher_suite, alpn, cert_sans, cert_key_type, cert_key_size,
+			cert_fingerprint, ocsp_status, check_type,
+			failed_step, steps,
+			write_ns, transfer_ns, conn_reused, protocol, ip_version, proxy,
+			snapshot, body_truncated, metrics,
+			assets, page_weight, page_load_ns,
+			domain_expiry, registrar,
+			content_hash, content_similarity, content_drift, content,
+			cert_mismatch, ct_entries, ct_position, ct_backlog
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19,
+			$20, $21, $22, $23, $24, $25, $26, $27, $28, $29, $30, $31, $32, $33, $34, $35, $36, $37, $38, $39, $40,
+			$41, $42, $43, $44, $45, $46, $47, $48, $49, $50, $51, $52)
 	`,
 		result.Timestamp,
 		result.EndpointID,
@@ -123,10 +192,78 @@ func (s *PostgresStorage) WriteResult(result checker.Result) error {
 		result.CertNotBefore,
 		result.CertNotAfter,
 		result.SatelliteID,
+		result.TLSVersion,
+		result.CipherSuite,
+		result.ALPN,
+		result.CertSANs,
+		result.CertKeyType,
+		result.CertKeySize,
+		result.CertFingerprint,
+		result.OCSPStatus,
+		result.Type,
+		result.FailedStep,
+		result.Steps,
+		result.WriteDuration.Nanoseconds(),
+		result.TransferDuration.Nanoseconds(),
+		result.ConnReused,
+		result.Protocol,
+		result.IPVersion,
+		result.Proxy,
+		result.Snapshot,
+		result.BodyTruncated,
+		result.Metrics,
+		result.Assets,
+		result.PageWeight,
+		result.PageLoadDuration.Nanoseconds(),
+		result.DomainExpiry,
+		result.Registrar,
+		result.ContentHash,
+		result.ContentSimilarity,
+		result.ContentDrift,
+		nullString(result.Content),
+		result.CertMismatch,
+		result.CTEntries,
+		int64(result.CTPosition),
+		int64(result.CTBacklog),
 	)
 	return err
 }
 
+// writeCertChain stores the certificate chain of a result under its leaf
+// fingerprint, unless it is the chain stored last for that leaf
+func (s *PostgresStorage) writeCertChain(result checker.Result) error {
+	if result.CertFingerprint == "" || len(result.CertChain) == 0 {
+		return nil
+	}
+	fingerprints := make([]string, len(result.CertChain))
+	for i, cert := range result.CertChain {
+		fingerprints[i] = cert.Fingerprint
+	}
+	key := strings.Join(fingerprints, ",")
+
+	s.mu.Lock()
+	stored := s.chains[result.CertFingerprint] == key
+	s.mu.Unlock()
+	if stored {
+		return nil
+	}
+
+	_, err := s.pool.Exec(context.Background(), `
+		INSERT INTO cert_chains (fingerprint, chain, updated_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (fingerprint) DO UPDATE SET chain = EXCLUDED.chain, updated_at = EXCLUDED.updated_at
+		WHERE cert_chains.chain IS DISTINCT FROM EXCLUDED.chain
+	`, result.CertFingerprint, result.CertChain, result.Timestamp)
+	if err != nil {
+		return fmt.Errorf("failed to store certificate chain: %w", err)
+	}
+
+	s.mu.Lock()
+	s.chains[result.CertFingerprint] = key
+	s.mu.Unlock()
+	return nil
+}
+
 func (s *PostgresStorage) QueryHistory(ctx context.Context, endpointID string, from, to time.Time) ([]storage.Metric, error) {
 	rows, err := s.pool.Query(ctx, `
 		SELECT
@@ -138,8 +275,45 @@ func (s *PostgresStorage) QueryHistory(ctx context.Context, endpointID string, f
 			cert_expiry,
 			cert_issuer,
 			cert_subject,
-			satellite_id
+			satellite_id,
+			COALESCE(tls_version, ''),
+			COALESCE(cipher_suite, ''),
+			COALESCE(alpn, ''),
+			cert_sans,
+			COALESCE(cert_key_type, ''),
+			COALESCE(cert_key_size, 0),
+			COALESCE(cert_fingerprint, ''),
+			COALESCE(cert_chains.chain, cert_chain),
+			COALESCE(ocsp_status, ''),
+			COALESCE(check_type, 'http'),
+			COALESCE(failed_step, ''),
+			steps,
+			COALESCE(dns_ns, 0),
+			COALESCE(conn_ns, 0),
+			COALESCE(tls_ns, 0),
+			COALESCE(write_ns, 0),
+			COALESCE(ttfb_ns, 0),
+			COALESCE(transfer_ns, 0),
+			COALESCE(conn_reused, false),
+			COALESCE(protocol, ''),
+			COALESCE(ip_version, ''),
+			COALESCE(proxy, ''),
+			snapshot,
+			COALESCE(body_truncated, false),
+			metrics,
+			assets,
+			COALESCE(page_weight, 0),
+			COALESCE(page_load_ns, 0),
+			COALESCE(domain_expiry, '0001-01-01 00:00:00+00'),
+			COALESCE(registrar, ''),
+			COALESCE(content_hash, ''),
+			COALESCE(content_similarity, 0),
+			COALESCE(content_drift, false),
+			COALESCE(cert_mismatch, false),
+			ct_entries,
+			COALESCE(ct_backlog, 0)
 		FROM http_checks
+		LEFT JOIN cert_chains ON cert_chains.fingerprint = http_checks.cert_fingerprint
 		WHERE
 			endpoint_id = $1
 			AND time >= $2
@@ -157,6 +331,15 @@ func (s *PostgresStorage) QueryHistory(ctx context.Context, endpointID string, f
 		err := rows.Scan(
 			&m.Timestamp, &m.DurationNS, &m.StatusCode, &m.Success, &m.Error,
 			&m.CertExpiry, &m.CertIssuer, &m.CertSubject, &m.SatelliteID,
+			&m.TLSVersion, &m.CipherSuite, &m.ALPN, &m.CertSANs, &m.CertKeyType, &m.CertKeySize,
+			&m.CertFingerprint, &m.CertChain, &m.OCSPStatus, &m.Type,
+			&m.FailedStep, &m.Steps,
+			&m.DNSNS, &m.ConnNS, &m.TLSNS, &m.WriteNS, &m.TTFBNS, &m.TransferNS, &m.ConnReused,
+			&m.Protocol, &m.IPVersion, &m.Proxy, &m.Snapshot, &m.BodyTruncated, &m.Metrics,
+			&m.Assets, &m.PageWeight, &m.PageLoadNS,
+			&m.DomainExpiry, &m.Registrar,
+			&m.ContentHash, &m.ContentSimilarity, &m.ContentDrift,
+			&m.CertMismatch, &m.CTEntries, &m.CTBacklog,
 		)
 		if err != nil {
 			return nil, err
@@ -166,3 +349,84 @@ func (s *PostgresStorage) QueryHistory(ctx context.Context, endpointID string, f
 
 	return metrics, nil
 }
+
+func (s *PostgresStorage) QueryContent(ctx context.Context, endpointID, satelliteID string, limit int) ([]storage.ContentSnapshot, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT
+			time,
+			COALESCE(satellite_id, ''),
+			COALESCE(content_hash, ''),
+			COALESCE(content_similarity, 0),
+			content
+		FROM http_checks
+		WHERE
+			endpoint_id = $1
+			AND COALESCE(satellite_id, '') = $2
+			AND content IS NOT NULL
+		ORDER BY time DESC
+		LIMIT $3
+	`, endpointID, satelliteID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var snapshots []storage.ContentSnapshot
+	for rows.Next() {
+		var c storage.ContentSnapshot
+		if err := rows.Scan(&c.Timestamp, &c.SatelliteID, &c.ContentHash, &c.Similarity, &c.Content); err != nil {
+			return nil, err
+		}
+		snapshots = append(snapshots, c)
+	}
+	return snapshots, rows.Err()
+}
+
+func (s *PostgresStorage) QueryState(ctx context.Context, endpointID, ipVersion string) (storage.CheckState, error) {
+	// The drift reference is the last body reported as a change, or the
+	// first body kept when none was
+	var state storage.CheckState
+	var position int64
+	err := s.pool.QueryRow(ctx, `
+		SELECT COALESCE((
+			SELECT content_hash
+			FROM http_checks
+			WHERE
+				endpoint_id = $1
+				AND COALESCE(satellite_id, '') = ''
+				AND COALESCE(ip_version, '') = $2
+				AND content_hash <> ''
+			ORDER BY COALESCE(content_drift, false) DESC, CASE WHEN content_drift THEN time END DESC, time ASC
+			LIMIT 1
+		), ''), COALESCE((
+			SELECT content_hash
+			FROM http_checks
+			WHERE
+				endpoint_id = $1
+				AND COALESCE(satellite_id, '') = ''
+				AND COALESCE(ip_version, '') = $2
+				AND content_hash <> ''
+			ORDER BY time DESC
+			LIMIT 1
+		), ''), COALESCE((
+			SELECT ct_position
+			FROM http_checks
+			WHERE
+				endpoint_id = $1
+				AND COALESCE(satellite_id, '') = ''
+				AND ct_position > 0
+			ORDER BY time DESC
+			LIMIT 1
+		), 0)
+	`, endpointID, ipVersion).Scan(&state.ContentReference, &state.ContentHash, &position)
+	state.CTPosition = uint64(position)
+	return state, err
+}
+
+// nullString stores an empty string as NULL
+func nullString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
@@ -10,5 +10,12 @@ import (
 type Provider interface {
 	WriteResult(result checker.Result) error
 	QueryHistory(ctx context.Context, endpointID string, from, to time.Time) ([]Metric, error)
+	// QueryContent returns the latest normalized bodies kept by drift
+	// detection for an endpoint checked by a satellite ("" for the master),
+	// newest first
+	QueryContent(ctx context.Context, endpointID, satelliteID string, limit int) ([]ContentSnapshot, error)
+	// QueryState returns the state last recorded by the master's checks of
+	// an endpoint over an IP version ("" when not pinned)
+	QueryState(ctx context.Context, endpointID, ipVersion string) (CheckState, error)
 	Close()
 }
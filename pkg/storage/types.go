@@ -1,6 +1,10 @@
 package storage
 
-import "time"
+import (
+	"time"
+
+	"github.com/manu/octo/pkg/checker"
+)
 
 type Metric struct {
 	Timestamp   time.Time `json:"timestamp"`
@@ -12,4 +16,78 @@ type Metric struct {
 	CertIssuer  string    `json:"cert_issuer,omitempty"`
 	CertSubject string    `json:"cert_subject,omitempty"`
 	SatelliteID string    `json:"satellite_id,omitempty"`
+	Type        string    `json:"type,omitempty"`
+
+	// Timing breakdown of the check
+	DNSNS      int64 `json:"dns_ns,omitempty"`
+	ConnNS     int64 `json:"conn_ns,omitempty"`
+	TLSNS      int64 `json:"tls_ns,omitempty"`
+	WriteNS    int64 `json:"write_ns,omitempty"`
+	TTFBNS     int64 `json:"ttfb_ns,omitempty"`
+	TransferNS int64 `json:"transfer_ns,omitempty"`
+	ConnReused bool  `json:"conn_reused,omitempty"`
+
+	BodyTruncated bool `json:"body_truncated,omitempty"`
+
+	Protocol  string `json:"protocol,omitempty"`
+	IPVersion string `json:"ip_version,omitempty"`
+	Proxy     string `json:"proxy,omitempty"`
+
+	TLSVersion      string             `json:"tls_version,omitempty"`
+	CipherSuite     string             `json:"cipher_suite,omitempty"`
+	ALPN            string             `json:"alpn,omitempty"`
+	CertSANs        []string           `json:"cert_sans,omitempty"`
+	CertKeyType     string             `json:"cert_key_type,omitempty"`
+	CertKeySize     int                `json:"cert_key_size,omitempty"`
+	CertFingerprint string             `json:"cert_fingerprint,omitempty"`
+	CertChain       []checker.CertInfo `json:"cert_chain,omitempty"`
+
+	OCSPStatus   string `json:"ocsp_status,omitempty"`
+	CertMismatch bool   `json:"cert_mismatch,omitempty"`
+
+	FailedStep string               `json:"failed_step,omitempty"`
+	Steps      []checker.StepResult `json:"steps,omitempty"`
+
+	// Subresources of a page check, with the page weight in bytes and load time
+	Assets     []checker.AssetResult `json:"assets,omitempty"`
+	PageWeight int64                 `json:"page_weight,omitempty"`
+	PageLoadNS int64                 `json:"page_load_ns,omitempty"`
+
+	// Registration of the domain of a domain check
+	DomainExpiry time.Time `json:"domain_expiry,omitzero"`
+	Registrar    string    `json:"registrar,omitempty"`
+
+	// Certificates for the watched domains read from a Certificate
+	// Transparency log
+	CTEntries []checker.CTEntry `json:"ct_entries,omitempty"`
+	CTBacklog uint64            `json:"ct_backlog,omitempty"`
+
+	// Drift detection of the body
+	ContentHash       string  `json:"content_hash,omitempty"`
+	ContentSimilarity float64 `json:"content_similarity,omitempty"`
+	ContentDrift      bool    `json:"content_drift,omitempty"`
+
+	// Custom metrics returned by a validation script
+	Metrics map[string]float64 `json:"metrics,omitempty"`
+
+	// Response of a failed check
+	Snapshot *checker.Snapshot `json:"snapshot,omitempty"`
+}
+
+// CheckState is what the checks of an endpoint last recorded that later
+// checks build on, to resume after a restart
+type CheckState struct {
+	ContentReference string // Content hash drift is measured against
+	ContentHash      string // Content hash of the last body
+	CTPosition       uint64 // Next Certificate Transparency log entry to read
+}
+
+// ContentSnapshot is a normalized response body kept by drift detection
+// when it changed
+type ContentSnapshot struct {
+	Timestamp   time.Time `json:"timestamp"`
+	SatelliteID string    `json:"satellite_id,omitempty"`
+	ContentHash string    `json:"content_hash"`
+	Similarity  float64   `json:"similarity"` // To the reference body
+	Content     string    `json:"content"`
 }
@@ -1,10 +1,31 @@
 import { useEffect, useState } from "react";
 import { useNavigate, useParams } from "react-router-dom";
-import { AlertTriangle, ArrowLeft, CheckCircle, Clock, Settings, Shield } from "lucide-react";
+import { AlertTriangle, ArrowLeft, CheckCircle, Clock, Globe, Play, Settings, Shield } from "lucide-react";
 import { Bar, BarChart, CartesianGrid, Cell, Line, LineChart, ResponsiveContainer, Tooltip, XAxis, YAxis } from 'recharts';
-import type { Config, Endpoint, Metric } from "../types";
+import type { Config, ContentDiff, Endpoint, Metric, RunResponse } from "../types";
 import { useAuth } from "../context/AuthContext";
 
+// formatPhases renders the timing breakdown of a check, e.g. "DNS 3ms · Connect 12ms · TTFB 80ms"
+function formatPhases(m: Metric): string {
+    const phases: [string, number | undefined][] = [
+        ["DNS", m.dns_ns],
+        ["Connect", m.conn_ns],
+        ["TLS", m.tls_ns],
+        ["Write", m.write_ns],
+        ["TTFB", m.ttfb_ns],
+        ["Transfer", m.transfer_ns],
+    ];
+    const parts = phases
+        .filter(([, ns]) => ns)
+        .map(([label, ns]) => `${label} ${((ns as number) / 1_000_000).toFixed(1)}ms`);
+    if (m.protocol) parts.unshift(m.protocol);
+    if (m.conn_reused) parts.push("reused connection");
+    if (m.body_truncated) parts.push("body truncated");
+    if (m.page_weight) parts.push(`page ${(m.page_weight / 1024).toFixed(0)} KiB in ${((m.page_load_ns || 0) / 1_000_000).toFixed(0)}ms`);
+    if (m.proxy) parts.push(`via ${m.proxy}`);
+    return parts.join(" · ");
+}
+
 export function EndpointDetails() {
     const { id } = useParams();
     const navigate = useNavigate();
@@ -19,6 +40,37 @@ export function EndpointDetails() {
     const [customStart, setCustomStart] = useState("");
     const [customEnd, setCustomEnd] = useState("");
     const [isCustom, setIsCustom] = useState(false);
+    const [running, setRunning] = useState(false);
+    const [runMessage, setRunMessage] = useState("");
+    const [refresh, setRefresh] = useState(0);
+    const [contentDiff, setContentDiff] = useState<ContentDiff | null>(null);
+
+    // runNow checks the endpoint out of schedule, on its satellites too, and reloads the history
+    const runNow = async () => {
+        setRunning(true);
+        setRunMessage("");
+        try {
+            const res = await fetch(`/api/v1/endpoints/${id}/run?satellites=true`, { method: "POST" });
+            if (!res.ok) {
+                throw new Error((await res.text()) || "Failed to run check");
+            }
+            const data: RunResponse = await res.json();
+            const failed = data.results.filter(r => !r.success);
+            const parts = [];
+            if (data.results.length > 0) {
+                parts.push(failed.length > 0 ? `Check failed: ${failed[0].error}` : "Check passed");
+            }
+            if (data.satellites?.length) {
+                parts.push(`queued on ${data.satellites.join(", ")}`);
+            }
+            setRunMessage(parts.join(" · "));
+            setRefresh(n => n + 1);
+        } catch (err: any) {
+            setRunMessage(err.message);
+        } finally {
+            setRunning(false);
+        }
+    };
 
     const ranges = [
         { label: 'Last 1 Hour', value: '1h' },
@@ -69,14 +121,24 @@ export function EndpointDetails() {
                 console.error(err);
                 setLoading(false);
             });
-    }, [id, timeRange, isCustom, customStart, customEnd]); // Trigger on any change
+    }, [id, timeRange, isCustom, customStart, customEnd, refresh]); // Trigger on any change
+
+    useEffect(() => {
+        // Fetch the last content change, if drift detection kept any
+        if (!id || !endpoint?.drift?.enabled) return;
+        fetch(`/api/v1/endpoints/${id}/diff`)
+            .then((res) => (res.ok ? res.json() : null))
+            .then((data: ContentDiff | null) => setContentDiff(data))
+            .catch(err => console.error(err));
+    }, [id, endpoint, refresh]);
 
     if (loading) return <div className="p-8">Loading details...</div>;
     if (!endpoint) return <div className="p-8">Endpoint not found</div>;
 
     // Process data for charts
-    // 1. Identify all unique satellites
-    const satelliteIDs = Array.from(new Set(metrics.map(m => m.satellite_id || 'master')));
+    // 1. Identify all unique satellites, split by address family for ip_version "both"
+    const seriesName = (m: Metric) => (m.satellite_id || 'master') + (m.ip_version ? ` IPv${m.ip_version}` : '');
+    const satelliteIDs = Array.from(new Set(metrics.map(seriesName)));
     const colors = ['#8884d8', '#82ca9d', '#ffc658', '#ff7300', '#00C49F', '#FFBB28'];
 
     // 2. Prepare chart data
@@ -85,20 +147,43 @@ export function EndpointDetails() {
     const chartData = metrics.map((m: Metric) => ({
         timestamp: new Date(m.timestamp).toISOString(),
         time: new Date(m.timestamp).toLocaleTimeString(),
-        [`duration_${m.satellite_id || 'master'}`]: m.duration_ns / 1_000_000,
+        [`duration_${seriesName(m)}`]: m.duration_ns / 1_000_000,
         success: m.success ? 1 : 0,
         status: m.success ? 'success' : 'failure',
         error: m.error || 'Unknown error',
-        satellite: m.satellite_id || 'master' // for tooltip
+        failedStep: m.failed_step,
+        phases: formatPhases(m),
+        satellite: seriesName(m) // for tooltip
     }));
 
+    // Custom metrics returned by a validation script, one line per name
+    const customMetricNames = Array.from(new Set(metrics.flatMap(m => Object.keys(m.metrics || {})))).sort();
+    const customMetricData = metrics
+        .filter(m => m.metrics)
+        .map(m => ({ time: new Date(m.timestamp).toLocaleTimeString(), ...m.metrics }));
+
     const lastMetric = metrics.length > 0 ? metrics[metrics.length - 1] : null;
+    // Each CT log check reports only the entries added since the previous one
+    const ctEntries = metrics.flatMap(m => m.ct_entries || []).reverse();
+    const lastFailure = [...metrics].reverse().find(m => !m.success && m.snapshot);
     const isHealthy = lastMetric?.success;
 
     const totalRequests = metrics.length;
     const successfulRequests = metrics.filter(m => m.success).length;
     const availability = totalRequests > 0 ? (successfulRequests / totalRequests) * 100 : 0;
 
+    // Certificate changes over time: one entry per consecutive run of the same leaf certificate
+    const certHistory: { fingerprint: string; firstSeen: string; lastSeen: string; metric: Metric }[] = [];
+    metrics.forEach((m) => {
+        if (!m.cert_fingerprint) return;
+        const last = certHistory[certHistory.length - 1];
+        if (last && last.fingerprint === m.cert_fingerprint) {
+            last.lastSeen = m.timestamp;
+        } else {
+            certHistory.push({ fingerprint: m.cert_fingerprint, firstSeen: m.timestamp, lastSeen: m.timestamp, metric: m });
+        }
+    });
+
     const durations = metrics.map(m => m.duration_ns / 1_000_000);
     const avgDuration = durations.length > 0 ? durations.reduce((a, b) => a + b, 0) / durations.length : 0;
     const minDuration = durations.length > 0 ? Math.min(...durations) : 0;
@@ -123,7 +208,18 @@ export function EndpointDetails() {
                                     <Settings className="h-4 w-4" />
                                 </button>
                             )}
+                            {isAdmin && endpoint.type !== "push" && (
+                                <button
+                                    onClick={runNow}
+                                    disabled={running}
+                                    className="inline-flex items-center justify-center rounded-md text-sm font-medium transition-colors hover:bg-muted h-8 w-8 text-muted-foreground disabled:opacity-50"
+                                    title="Run Now"
+                                >
+                                    <Play className="h-4 w-4" />
+                                </button>
+                            )}
                         </div>
+                        {runMessage && <p className="text-xs text-muted-foreground mt-1">{runMessage}</p>}
                         <p className="text-sm text-muted-foreground mt-1">
                             <a href={endpoint.url} target="_blank" rel="noopener noreferrer" className="hover:underline hover:text-primary">
                                 {endpoint.url}
@@ -228,6 +324,74 @@ export function EndpointDetails() {
                 </div>
             </div>
 
+            {lastMetric?.domain_expiry && (
+                <div className="rounded-xl border bg-card text-card-foreground shadow p-6">
+                    <div className="flex items-center gap-2 mb-4">
+                        <Globe className="h-5 w-5 text-primary" />
+                        <h3 className="font-semibold">Domain Registration</h3>
+                    </div>
+                    <div className="grid gap-6 md:grid-cols-3">
+                        <div>
+                            <p className="text-sm font-medium text-muted-foreground mb-1">Expiration</p>
+                            <div className="flex items-center gap-2">
+                                <Clock className="h-4 w-4 text-muted-foreground" />
+                                <span className="font-medium">
+                                    {new Date(lastMetric.domain_expiry).toLocaleDateString()}
+                                </span>
+                            </div>
+                            <p className="text-xs text-muted-foreground mt-1">
+                                Expires in {Math.floor((new Date(lastMetric.domain_expiry).getTime() - Date.now()) / (1000 * 60 * 60 * 24))} days
+                            </p>
+                        </div>
+                        <div className="md:col-span-2">
+                            <p className="text-sm font-medium text-muted-foreground mb-1">Registrar</p>
+                            <p className="text-sm font-medium truncate" title={lastMetric.registrar}>
+                                {lastMetric.registrar || "Unknown"}
+                            </p>
+                        </div>
+                    </div>
+                </div>
+            )}
+
+            {endpoint.type === "ct" && (
+                <div className="rounded-xl border bg-card text-card-foreground shadow p-6">
+                    <div className="flex items-center gap-2 mb-1">
+                        <Shield className="h-5 w-5 text-primary" />
+                        <h3 className="font-semibold">Certificate Transparency</h3>
+                    </div>
+                    <p className="text-sm text-muted-foreground mb-4">
+                        {ctEntries.length} certificates logged for {endpoint.ct?.domains?.join(", ") || "the endpoint domains"} in this period
+                        {lastMetric?.ct_backlog ? `, ${lastMetric.ct_backlog} log entries not read yet` : ""}
+                    </p>
+                    {ctEntries.length > 0 && (
+                        <table className="w-full text-sm">
+                            <thead>
+                                <tr className="text-left text-muted-foreground">
+                                    <th className="font-medium pb-1">Names</th>
+                                    <th className="font-medium pb-1">Issuer</th>
+                                    <th className="font-medium pb-1">Logged</th>
+                                    <th className="font-medium pb-1">Valid Until</th>
+                                </tr>
+                            </thead>
+                            <tbody>
+                                {ctEntries.map((entry) => (
+                                    <tr key={entry.index}>
+                                        <td className="truncate max-w-[300px]" title={entry.dns_names.join(", ")}>
+                                            {entry.dns_names.join(", ")}{entry.precert && " (precert)"}
+                                        </td>
+                                        <td className={`truncate max-w-[300px] ${entry.allowed ? "" : "text-red-600 font-medium"}`} title={entry.issuer}>
+                                            {entry.issuer}
+                                        </td>
+                                        <td>{new Date(entry.timestamp).toLocaleString()}</td>
+                                        <td>{new Date(entry.not_after).toLocaleDateString()}</td>
+                                    </tr>
+                                ))}
+                            </tbody>
+                        </table>
+                    )}
+                </div>
+            )}
+
             {(lastMetric?.cert_expiry) && (
                 <div className="rounded-xl border bg-card text-card-foreground shadow p-6">
                     <div className="flex items-center gap-2 mb-4">
@@ -261,9 +425,190 @@ export function EndpointDetails() {
                                         {lastMetric.cert_subject}
                                     </p>
                                 </div>
+                                <div>
+                                    <p className="text-sm font-medium text-muted-foreground mb-1">Protocol</p>
+                                    <p className="text-sm font-medium truncate" title={lastMetric.cipher_suite}>
+                                        {lastMetric.tls_version} {lastMetric.cipher_suite}
+                                        {lastMetric.alpn && ` (${lastMetric.alpn})`}
+                                    </p>
+                                </div>
+                                <div>
+                                    <p className="text-sm font-medium text-muted-foreground mb-1">Key</p>
+                                    <p className="text-sm font-medium">
+                                        {lastMetric.cert_key_type} {lastMetric.cert_key_size ? `${lastMetric.cert_key_size} bits` : ''}
+                                    </p>
+                                </div>
+                                {lastMetric.ocsp_status && (
+                                    <div>
+                                        <p className="text-sm font-medium text-muted-foreground mb-1">Revocation (OCSP)</p>
+                                        <p className={`text-sm font-medium capitalize ${lastMetric.ocsp_status === 'revoked' ? 'text-red-600' : ''}`}>
+                                            {lastMetric.ocsp_status}
+                                        </p>
+                                    </div>
+                                )}
+                                {lastMetric.cert_sans && lastMetric.cert_sans.length > 0 && (
+                                    <div className="md:col-span-2">
+                                        <p className="text-sm font-medium text-muted-foreground mb-1">Subject Alternative Names</p>
+                                        <p className="text-sm font-medium break-all">
+                                            {lastMetric.cert_sans.join(", ")}
+                                        </p>
+                                    </div>
+                                )}
                             </div>
                         </div>
                     </div>
+                    {lastMetric.cert_chain && lastMetric.cert_chain.length > 1 && (
+                        <div className="mt-6">
+                            <p className="text-sm font-medium text-muted-foreground mb-2">Chain</p>
+                            <ol className="space-y-1 text-sm">
+                                {lastMetric.cert_chain.map((c, index) => (
+                                    <li key={c.fingerprint} className="truncate" title={c.issuer}>
+                                        <span className="text-muted-foreground">{index}.</span> {c.subject}
+                                        <span className="text-xs text-muted-foreground"> (expires {new Date(c.not_after).toLocaleDateString()})</span>
+                                    </li>
+                                ))}
+                            </ol>
+                        </div>
+                    )}
+                    {certHistory.length > 1 && (
+                        <div className="mt-6">
+                            <p className="text-sm font-medium text-muted-foreground mb-2">Certificate Changes</p>
+                            <table className="w-full text-sm">
+                                <thead>
+                                    <tr className="text-left text-muted-foreground">
+                                        <th className="font-medium pb-1">First Seen</th>
+                                        <th className="font-medium pb-1">Last Seen</th>
+                                        <th className="font-medium pb-1">Issuer</th>
+                                        <th className="font-medium pb-1">Expires</th>
+                                        <th className="font-medium pb-1">Fingerprint</th>
+                                    </tr>
+                                </thead>
+                                <tbody>
+                                    {certHistory.map((entry) => (
+                                        <tr key={`${entry.fingerprint}-${entry.firstSeen}`}>
+                                            <td>{new Date(entry.firstSeen).toLocaleString()}</td>
+                                            <td>{new Date(entry.lastSeen).toLocaleString()}</td>
+                                            <td className="truncate max-w-[200px]" title={entry.metric.cert_issuer}>{entry.metric.cert_issuer}</td>
+                                            <td>{entry.metric.cert_expiry && new Date(entry.metric.cert_expiry).toLocaleDateString()}</td>
+                                            <td className="font-mono text-xs">{entry.fingerprint.slice(0, 16)}…</td>
+                                        </tr>
+                                    ))}
+                                </tbody>
+                            </table>
+                        </div>
+                    )}
+                </div>
+            )}
+
+            {lastFailure?.snapshot && (
+                <div className="rounded-xl border bg-card text-card-foreground shadow p-6">
+                    <h3 className="font-semibold mb-1">Last Failure Response</h3>
+                    <p className="text-sm text-muted-foreground mb-4">
+                        {new Date(lastFailure.timestamp).toLocaleString()}
+                        {lastFailure.status_code > 0 && ` · HTTP ${lastFailure.status_code}`}
+                        {lastFailure.snapshot.remote_ip && ` · ${lastFailure.snapshot.remote_ip}`}
+                        {lastFailure.error && ` · ${lastFailure.error}`}
+                    </p>
+                    {lastFailure.snapshot.headers && (
+                        <pre className="text-xs font-mono bg-muted rounded-md p-3 mb-3 overflow-x-auto">
+                            {Object.entries(lastFailure.snapshot.headers)
+                                .sort(([a], [b]) => a.localeCompare(b))
+                                .map(([name, values]) => `${name}: ${values.join(", ")}`)
+                                .join("\n")}
+                        </pre>
+                    )}
+                    {lastFailure.snapshot.body && (
+                        <pre className="text-xs font-mono bg-muted rounded-md p-3 overflow-x-auto whitespace-pre-wrap break-all">
+                            {lastFailure.snapshot.body}
+                            {lastFailure.snapshot.truncated && "\n… (truncated)"}
+                        </pre>
+                    )}
+                </div>
+            )}
+
+            {lastMetric?.assets && lastMetric.assets.length > 0 && (
+                <div className="rounded-xl border bg-card text-card-foreground shadow p-6">
+                    <h3 className="font-semibold mb-1">Page Assets</h3>
+                    <p className="text-sm text-muted-foreground mb-4">
+                        {lastMetric.assets.length} assets · {((lastMetric.page_weight || 0) / 1024).toFixed(0)} KiB total · loaded in {((lastMetric.page_load_ns || 0) / 1_000_000).toFixed(0)}ms
+                    </p>
+                    <table className="w-full text-sm">
+                        <thead>
+                            <tr className="text-left text-muted-foreground">
+                                <th className="font-medium pb-1">Asset</th>
+                                <th className="font-medium pb-1">Type</th>
+                                <th className="font-medium pb-1">Status</th>
+                                <th className="font-medium pb-1">Size</th>
+                                <th className="font-medium pb-1">Duration</th>
+                            </tr>
+                        </thead>
+                        <tbody>
+                            {lastMetric.assets.map((asset) => (
+                                <tr key={asset.url}>
+                                    <td className="truncate max-w-[400px]" title={asset.url}>{new URL(asset.url).pathname}</td>
+                                    <td>{asset.type}</td>
+                                    <td className={asset.error ? "text-red-600" : "text-green-600"}>
+                                        {asset.error || asset.status_code}
+                                    </td>
+                                    <td>{(asset.bytes / 1024).toFixed(1)} KiB</td>
+                                    <td>{(asset.duration / 1_000_000).toFixed(0)}ms</td>
+                                </tr>
+                            ))}
+                        </tbody>
+                    </table>
+                </div>
+            )}
+
+            {lastMetric?.steps && lastMetric.steps.length > 0 && (
+                <div className="rounded-xl border bg-card text-card-foreground shadow p-6">
+                    <h3 className="font-semibold mb-4">Transaction Steps</h3>
+                    <table className="w-full text-sm">
+                        <thead>
+                            <tr className="text-left text-muted-foreground">
+                                <th className="font-medium pb-1">Step</th>
+                                <th className="font-medium pb-1">Request</th>
+                                <th className="font-medium pb-1">Status</th>
+                                <th className="font-medium pb-1">Duration</th>
+                                <th className="font-medium pb-1">Result</th>
+                            </tr>
+                        </thead>
+                        <tbody>
+                            {lastMetric.steps.map((step) => (
+                                <tr key={step.name}>
+                                    <td className="font-medium">{step.name}</td>
+                                    <td className="truncate max-w-[300px]" title={step.url}>{step.method} {step.url}</td>
+                                    <td>{step.status_code || "-"}</td>
+                                    <td>{(step.duration / 1_000_000).toFixed(0)}ms</td>
+                                    <td className={step.success ? "text-green-600" : "text-red-600"}>
+                                        {step.success ? "OK" : step.error}
+                                    </td>
+                                </tr>
+                            ))}
+                        </tbody>
+                    </table>
+                </div>
+            )}
+
+            {contentDiff && (
+                <div className="rounded-xl border bg-card text-card-foreground shadow p-6">
+                    <h3 className="font-semibold mb-1">Content Changes</h3>
+                    <p className="text-sm text-muted-foreground mb-4">
+                        {contentDiff.from
+                            ? `${new Date(contentDiff.from.timestamp).toLocaleString()} → ${new Date(contentDiff.to.timestamp).toLocaleString()} · similarity to the reference ${(contentDiff.to.similarity * 100).toFixed(0)}%`
+                            : `Baseline recorded ${new Date(contentDiff.to.timestamp).toLocaleString()}, no change since`}
+                    </p>
+                    {contentDiff.diff && (
+                        <pre className="text-xs font-mono overflow-auto max-h-[400px] rounded-md bg-muted p-3">
+                            {contentDiff.diff.split("\n").map((line, index) => (
+                                <div
+                                    key={index}
+                                    className={line.startsWith("+") ? "text-green-600" : line.startsWith("-") ? "text-red-600" : line.startsWith("@@") ? "text-blue-600" : ""}
+                                >
+                                    {line || " "}
+                                </div>
+                            ))}
+                        </pre>
+                    )}
                 </div>
             )}
 
@@ -286,6 +631,12 @@ export function EndpointDetails() {
                                                     <div className={data.success ? "text-green-500" : "text-red-500"}>
                                                         {data.success ? "Success" : `Error: ${data.error}`}
                                                     </div>
+                                                    {data.failedStep && (
+                                                        <div className="text-muted-foreground">Failed step: {data.failedStep}</div>
+                                                    )}
+                                                    {data.phases && (
+                                                        <div className="text-muted-foreground">{data.phases}</div>
+                                                    )}
                                                 </div>
                                             );
                                         }
@@ -340,6 +691,37 @@ export function EndpointDetails() {
                         </ResponsiveContainer>
                     </div>
                 </div>
+
+                {customMetricNames.length > 0 && (
+                    <div className="rounded-xl border bg-card text-card-foreground shadow p-6">
+                        <h3 className="font-semibold mb-4">Custom Metrics</h3>
+                        <div className="h-[200px] w-full">
+                            <ResponsiveContainer width="100%" height="100%">
+                                <LineChart data={customMetricData}>
+                                    <CartesianGrid strokeDasharray="3 3" vertical={false} />
+                                    <XAxis dataKey="time" hide />
+                                    <YAxis />
+                                    <Tooltip
+                                        contentStyle={{ backgroundColor: 'var(--color-card)', borderColor: 'var(--color-border)' }}
+                                        itemStyle={{ color: 'var(--color-foreground)' }}
+                                        labelStyle={{ color: 'var(--color-foreground)' }}
+                                    />
+                                    {customMetricNames.map((name, index) => (
+                                        <Line
+                                            key={name}
+                                            type="monotone"
+                                            dataKey={name}
+                                            stroke={colors[index % colors.length]}
+                                            strokeWidth={2}
+                                            dot={false}
+                                            connectNulls={true}
+                                        />
+                                    ))}
+                                </LineChart>
+                            </ResponsiveContainer>
+                        </div>
+                    </div>
+                )}
             </div>
         </div>
     );
@@ -1,7 +1,46 @@
 import { useEffect, useState } from "react";
 import { useNavigate, useParams } from "react-router-dom";
-import { ArrowLeft, Save, Trash, Plus, X } from "lucide-react";
-import type { Config, Endpoint } from "../types";
+import { ArrowLeft, Play, Save, Trash, Plus, X } from "lucide-react";
+import type { CheckResult, Config, Endpoint, RunResponse } from "../types";
+
+const contentMatchPlaceholders: Record<string, string> = {
+    regex: "Regex pattern",
+    xpath: "//Status = 'OK'",
+    css: "button#checkout",
+};
+
+const NS_PER_SEC = 1_000_000_000;
+
+// Push durations are edited in seconds but stored in nanoseconds
+function scalePushDurations(push: Endpoint["push"], factor: number): Endpoint["push"] {
+    if (!push) return push;
+    return {
+        ...push,
+        period: (push.period || 0) * factor,
+        grace: (push.grace || 0) * factor,
+        max_runtime: (push.max_runtime || 0) * factor,
+    };
+}
+
+const targetPlaceholders: Record<string, string> = {
+    http: "https://example.com",
+    tcp: "db.internal:5432",
+    dns: "api.example.com",
+    tls: "mail.example.com:465",
+    grpc: "grpc://orders.internal:50051",
+    websocket: "wss://example.com/ws",
+    sse: "https://example.com/events",
+    postgres: "db.internal:5432",
+    mysql: "mysql.internal:3306",
+    redis: "cache.internal:6379",
+    smtp: "mail.example.com:25",
+    imap: "imaps://mail.example.com",
+    ftp: "ftp.example.com:21",
+    sftp: "files.example.com:22",
+    page: "https://example.com",
+    domain: "example.com",
+    ct: "https://ct.googleapis.com/logs/us1/argon2025h2/",
+};
 
 // Helper component for Key-Value pairs (Headers, Tags)
 function KeyValueEditor({
@@ -107,6 +146,8 @@ export function EndpointForm() {
     });
     const [loading, setLoading] = useState(false);
     const [error, setError] = useState("");
+    const [testing, setTesting] = useState(false);
+    const [testResults, setTestResults] = useState<CheckResult[] | null>(null);
 
     useEffect(() => {
         if (isEditMode && id) {
@@ -121,6 +162,7 @@ export function EndpointForm() {
                             ...ep,
                             interval: ep.interval / 1_000_000_000,
                             timeout: ep.timeout / 1_000_000_000,
+                            push: scalePushDurations(ep.push, 1 / NS_PER_SEC),
                             validation: {
                                 ...ep.validation,
                                 content_match: ep.validation.content_match || { type: "", pattern: "" }
@@ -158,16 +200,74 @@ export function EndpointForm() {
         }));
     };
 
+    const handlePushChange = (field: string, value: string | number) => {
+        setFormData(prev => ({
+            ...prev,
+            push: {
+                ...prev.push,
+                [field]: value
+            }
+        }));
+    };
+
     const handleSSLChange = (days: string) => {
         const daysArray = days.split(',').map(s => parseInt(s.trim())).filter(n => !isNaN(n));
         setFormData(prev => ({
             ...prev,
             ssl: {
+                ...prev.ssl,
                 expiration_alert_days: daysArray
             }
         }));
     };
 
+    // splitList parses a comma separated input
+    const splitList = (value: string) => value.split(",").map(s => s.trim()).filter(Boolean);
+
+    // buildPayload converts the form to the endpoint config sent to the API
+    const buildPayload = () => ({
+        ...formData,
+        interval: (formData.interval || 60) * 1_000_000_000,
+        timeout: (formData.timeout || 10) * 1_000_000_000,
+        push: formData.type === "push" ? scalePushDurations(formData.push || {}, NS_PER_SEC) : undefined,
+        // Clean up empty optional fields
+        validation: {
+            ...formData.validation,
+            content_match: (formData.validation?.content_match?.pattern)
+                ? formData.validation.content_match
+                : undefined
+        }
+    });
+
+    // handleTest runs the endpoint as configured in the form without saving it
+    const handleTest = async () => {
+        setTesting(true);
+        setError("");
+        setTestResults(null);
+
+        try {
+            const res = await fetch("/api/v1/check", {
+                method: "POST",
+                headers: {
+                    "Content-Type": "application/json",
+                },
+                body: JSON.stringify(buildPayload()),
+            });
+
+            if (!res.ok) {
+                const errText = await res.text();
+                throw new Error(errText || "Failed to run check");
+            }
+
+            const data: RunResponse = await res.json();
+            setTestResults(data.results);
+        } catch (err: any) {
+            setError(err.message);
+        } finally {
+            setTesting(false);
+        }
+    };
+
     const handleSubmit = async (e: React.FormEvent) => {
         e.preventDefault();
         setLoading(true);
@@ -178,20 +278,7 @@ export function EndpointForm() {
                 ? `/api/v1/config/endpoints/${id}`
                 : "/api/v1/config/endpoints";
             const method = isEditMode ? "PUT" : "POST";
-
-            // Prepare payload
-            const payload = {
-                ...formData,
-                interval: (formData.interval || 60) * 1_000_000_000,
-                timeout: (formData.timeout || 10) * 1_000_000_000,
-                // Clean up empty optional fields
-                validation: {
-                    ...formData.validation,
-                    content_match: (formData.validation?.content_match?.pattern)
-                        ? formData.validation.content_match
-                        : undefined
-                }
-            };
+            const payload = buildPayload();
 
             const res = await fetch(url, {
                 method,
@@ -236,6 +323,9 @@ export function EndpointForm() {
 
     if (loading && isEditMode && !formData.id) return <div className="p-8">Loading...</div>;
 
+    const isHTTP = !formData.type || formData.type === "http" || formData.type === "page";
+    const isPush = formData.type === "push";
+
     return (
         <div className="max-w-3xl mx-auto space-y-6 pb-12">
             <div className="flex items-center space-x-4">
@@ -278,19 +368,139 @@ export function EndpointForm() {
                     </div>
 
                     <div className="space-y-2">
-                        <label className="text-sm font-medium leading-none">URL</label>
+                        <label className="text-sm font-medium leading-none">Check Type</label>
+                        <select
+                            name="type"
+                            value={formData.type || "http"}
+                            onChange={handleChange}
+                            className="flex h-10 w-full rounded-md border border-input bg-transparent px-3 py-2 text-sm shadow-sm transition-colors focus-visible:outline-none focus-visible:ring-1 focus-visible:ring-ring"
+                        >
+                            <option value="http">HTTP(S)</option>
+                            <option value="tcp">TCP Connect</option>
+                            <option value="dns">DNS Resolution</option>
+                            <option value="tls">TLS Handshake</option>
+                            <option value="grpc">gRPC Health</option>
+                            <option value="websocket">WebSocket</option>
+                            <option value="sse">Server-Sent Events</option>
+                            <option value="push">Push (Heartbeat)</option>
+                            <option value="postgres">PostgreSQL</option>
+                            <option value="mysql">MySQL</option>
+                            <option value="redis">Redis</option>
+                            <option value="smtp">SMTP</option>
+                            <option value="imap">IMAP</option>
+                            <option value="ftp">FTP(S)</option>
+                            <option value="sftp">SFTP</option>
+                            <option value="page">Page (HTML + assets)</option>
+                            <option value="domain">Domain Registration</option>
+                            <option value="ct">Certificate Transparency Log</option>
+                        </select>
+                    </div>
+
+                    {!isPush && <div className="space-y-2">
+                        <label className="text-sm font-medium leading-none">{isHTTP ? "URL" : "Target"}</label>
                         <input
                             name="url"
                             value={formData.url}
                             onChange={handleChange}
                             required
-                            type="url"
+                            type={isHTTP ? "url" : "text"}
                             className="flex h-10 w-full rounded-md border border-input bg-transparent px-3 py-2 text-sm shadow-sm transition-colors focus-visible:outline-none focus-visible:ring-1 focus-visible:ring-ring"
-                            placeholder="https://example.com"
+                            placeholder={targetPlaceholders[formData.type || "http"]}
                         />
-                    </div>
+                    </div>}
                 </div>
 
+                {isPush && (
+                    <div className="space-y-4 bg-card p-6 rounded-xl border shadow">
+                        <h2 className="text-lg font-semibold">Heartbeat Settings</h2>
+                        {formData.push?.token ? (
+                            <div className="space-y-1">
+                                <p className="text-sm text-muted-foreground">
+                                    Call this URL when the job finishes. Optional parameters: status, duration, msg. Append /start when it begins.
+                                </p>
+                                <code className="block rounded bg-muted px-3 py-2 text-sm break-all">
+                                    {`${window.location.origin}/api/v1/push/${formData.push.token}`}
+                                </code>
+                            </div>
+                        ) : (
+                            <p className="text-sm text-muted-foreground">The push URL is issued when the endpoint is saved.</p>
+                        )}
+                        <div className="grid grid-cols-2 gap-4">
+                            <div className="space-y-2">
+                                <label className="text-sm font-medium leading-none">Period (sec)</label>
+                                <input
+                                    type="number"
+                                    min="0"
+                                    value={formData.push?.period || ""}
+                                    onChange={(e) => handlePushChange("period", Number(e.target.value))}
+                                    className="flex h-10 w-full rounded-md border border-input bg-transparent px-3 py-2 text-sm shadow-sm transition-colors focus-visible:outline-none focus-visible:ring-1 focus-visible:ring-ring"
+                                    placeholder="86400"
+                                />
+                            </div>
+                            <div className="space-y-2">
+                                <label className="text-sm font-medium leading-none">Cron Schedule (overrides period)</label>
+                                <input
+                                    value={formData.push?.schedule || ""}
+                                    onChange={(e) => handlePushChange("schedule", e.target.value)}
+                                    className="flex h-10 w-full rounded-md border border-input bg-transparent px-3 py-2 text-sm shadow-sm transition-colors focus-visible:outline-none focus-visible:ring-1 focus-visible:ring-ring"
+                                    placeholder="0 2 * * *"
+                                />
+                            </div>
+                            <div className="space-y-2">
+                                <label className="text-sm font-medium leading-none">Grace (sec)</label>
+                                <input
+                                    type="number"
+                                    min="0"
+                                    value={formData.push?.grace || ""}
+                                    onChange={(e) => handlePushChange("grace", Number(e.target.value))}
+                                    className="flex h-10 w-full rounded-md border border-input bg-transparent px-3 py-2 text-sm shadow-sm transition-colors focus-visible:outline-none focus-visible:ring-1 focus-visible:ring-ring"
+                                    placeholder="1800"
+                                />
+                            </div>
+                            <div className="space-y-2">
+                                <label className="text-sm font-medium leading-none">Max Runtime (sec)</label>
+                                <input
+                                    type="number"
+                                    min="0"
+                                    value={formData.push?.max_runtime || ""}
+                                    onChange={(e) => handlePushChange("max_runtime", Number(e.target.value))}
+                                    className="flex h-10 w-full rounded-md border border-input bg-transparent px-3 py-2 text-sm shadow-sm transition-colors focus-visible:outline-none focus-visible:ring-1 focus-visible:ring-ring"
+                                    placeholder="Only checked after a /start ping"
+                                />
+                            </div>
+                        </div>
+                    </div>
+                )}
+
+                {formData.type === "ct" && (
+                    <div className="space-y-4 bg-card p-6 rounded-xl border shadow">
+                        <h2 className="text-lg font-semibold">Certificate Transparency</h2>
+                        <p className="text-sm text-muted-foreground">
+                            Each check reads the entries added to the log since the previous one and fails on a certificate for the domains from another issuer.
+                        </p>
+                        <div className="grid grid-cols-2 gap-4">
+                            <div className="space-y-2">
+                                <label className="text-sm font-medium leading-none">Domains (comma separated)</label>
+                                <input
+                                    value={formData.ct?.domains?.join(", ") || ""}
+                                    onChange={(e) => setFormData(prev => ({ ...prev, ct: { ...prev.ct, domains: splitList(e.target.value) } }))}
+                                    className="flex h-10 w-full rounded-md border border-input bg-transparent px-3 py-2 text-sm shadow-sm transition-colors focus-visible:outline-none focus-visible:ring-1 focus-visible:ring-ring"
+                                    placeholder="example.com, example.org"
+                                />
+                            </div>
+                            <div className="space-y-2">
+                                <label className="text-sm font-medium leading-none">Allowed Issuers (comma separated)</label>
+                                <input
+                                    value={formData.ct?.allowed_issuers?.join(", ") || ""}
+                                    onChange={(e) => setFormData(prev => ({ ...prev, ct: { ...prev.ct, allowed_issuers: splitList(e.target.value) } }))}
+                                    className="flex h-10 w-full rounded-md border border-input bg-transparent px-3 py-2 text-sm shadow-sm transition-colors focus-visible:outline-none focus-visible:ring-1 focus-visible:ring-ring"
+                                    placeholder="Let's Encrypt, DigiCert Inc"
+                                />
+                            </div>
+                        </div>
+                    </div>
+                )}
+
                 {/* Request Settings */}
                 <div className="space-y-4 bg-card p-6 rounded-xl border shadow">
                     <h2 className="text-lg font-semibold">Request Settings</h2>
@@ -336,6 +546,96 @@ export function EndpointForm() {
                         </div>
                     </div>
 
+                    {isHTTP && (
+                        <div className="grid grid-cols-3 gap-4">
+                            <div className="space-y-2">
+                                <label className="text-sm font-medium leading-none">HTTP Version</label>
+                                <select
+                                    name="http_version"
+                                    value={formData.http_version || "auto"}
+                                    onChange={handleChange}
+                                    className="flex h-10 w-full rounded-md border border-input bg-transparent px-3 py-2 text-sm shadow-sm transition-colors focus-visible:outline-none focus-visible:ring-1 focus-visible:ring-ring"
+                                >
+                                    <option value="auto">Auto (HTTP/2, fallback to 1.1)</option>
+                                    <option value="1.1">HTTP/1.1 only</option>
+                                    <option value="2">HTTP/2 only</option>
+                                    <option value="h2c">h2c (HTTP/2 without TLS)</option>
+                                </select>
+                            </div>
+                            <div className="space-y-2">
+                                <label className="text-sm font-medium leading-none">Expected Protocol</label>
+                                <select
+                                    value={formData.validation?.http_version || ""}
+                                    onChange={(e) => handleValidationChange("http_version", e.target.value || undefined)}
+                                    className="flex h-10 w-full rounded-md border border-input bg-transparent px-3 py-2 text-sm shadow-sm transition-colors focus-visible:outline-none focus-visible:ring-1 focus-visible:ring-ring"
+                                >
+                                    <option value="">Any</option>
+                                    <option value="1.1">HTTP/1.1</option>
+                                    <option value="2">HTTP/2</option>
+                                </select>
+                            </div>
+                            <div className="space-y-2">
+                                <label className="text-sm font-medium leading-none">Cookie Jar</label>
+                                <select
+                                    value={formData.cookie_jar || ""}
+                                    onChange={(e) => setFormData(prev => ({ ...prev, cookie_jar: (e.target.value || undefined) as Endpoint["cookie_jar"] }))}
+                                    className="flex h-10 w-full rounded-md border border-input bg-transparent px-3 py-2 text-sm shadow-sm transition-colors focus-visible:outline-none focus-visible:ring-1 focus-visible:ring-ring"
+                                >
+                                    <option value="">None</option>
+                                    <option value="check">Within a check (redirects and steps)</option>
+                                    <option value="persistent">Persistent across checks</option>
+                                </select>
+                            </div>
+                        </div>
+                    )}
+
+                    {!isPush && (
+                        <div className="grid grid-cols-3 gap-4">
+                            <div className="space-y-2">
+                                <label className="text-sm font-medium leading-none">IP Version</label>
+                                <select
+                                    name="ip_version"
+                                    value={formData.ip_version || ""}
+                                    onChange={handleChange}
+                                    className="flex h-10 w-full rounded-md border border-input bg-transparent px-3 py-2 text-sm shadow-sm transition-colors focus-visible:outline-none focus-visible:ring-1 focus-visible:ring-ring"
+                                >
+                                    <option value="">Any</option>
+                                    <option value="4">IPv4 only</option>
+                                    <option value="6">IPv6 only</option>
+                                    <option value="both">Both (checked separately)</option>
+                                </select>
+                            </div>
+                            <div className="space-y-2">
+                                <label className="text-sm font-medium leading-none">DNS Server</label>
+                                <input
+                                    name="dns_server"
+                                    value={formData.dns_server || ""}
+                                    onChange={handleChange}
+                                    placeholder="System resolver"
+                                    className="flex h-10 w-full rounded-md border border-input bg-transparent px-3 py-2 text-sm shadow-sm transition-colors focus-visible:outline-none focus-visible:ring-1 focus-visible:ring-ring"
+                                />
+                            </div>
+                            <div className="space-y-2">
+                                <label className="text-sm font-medium leading-none">Proxy</label>
+                                <input
+                                    value={formData.proxy?.url || ""}
+                                    onChange={(e) => setFormData(prev => ({ ...prev, proxy: { ...prev.proxy, url: e.target.value } }))}
+                                    placeholder="Satellite or global default"
+                                    className="flex h-10 w-full rounded-md border border-input bg-transparent px-3 py-2 text-sm shadow-sm transition-colors focus-visible:outline-none focus-visible:ring-1 focus-visible:ring-ring"
+                                />
+                            </div>
+                            <div className="space-y-2">
+                                <label className="text-sm font-medium leading-none">Resolve (comma separated)</label>
+                                <input
+                                    value={formData.resolve?.join(", ") || ""}
+                                    onChange={(e) => setFormData(prev => ({ ...prev, resolve: e.target.value.split(',').map(s => s.trim()).filter(Boolean) }))}
+                                    placeholder="api.example.com:443:10.0.0.12"
+                                    className="flex h-10 w-full rounded-md border border-input bg-transparent px-3 py-2 text-sm shadow-sm transition-colors focus-visible:outline-none focus-visible:ring-1 focus-visible:ring-ring"
+                                />
+                            </div>
+                        </div>
+                    )}
+
                     <div className="pt-2">
                         <KeyValueEditor
                             title="Request Headers"
@@ -369,6 +669,8 @@ export function EndpointForm() {
                                 <option value="">None</option>
                                 <option value="exact">Exact Match</option>
                                 <option value="regex">Regular Expression</option>
+                                <option value="xpath">XPath (XML)</option>
+                                <option value="css">CSS Selector (HTML)</option>
                             </select>
                         </div>
                         <div className="col-span-2 space-y-2">
@@ -378,10 +680,51 @@ export function EndpointForm() {
                                 onChange={(e) => handleValidationChange("content_match", { ...formData.validation?.content_match, pattern: e.target.value })}
                                 disabled={!formData.validation?.content_match?.type}
                                 className="flex h-10 w-full rounded-md border border-input bg-transparent px-3 py-2 text-sm shadow-sm transition-colors focus-visible:outline-none focus-visible:ring-1 focus-visible:ring-ring disabled:opacity-50"
-                                placeholder={formData.validation?.content_match?.type === 'regex' ? 'Regex pattern' : 'Exact string'}
+                                placeholder={contentMatchPlaceholders[formData.validation?.content_match?.type || ""] || "Exact string"}
                             />
                         </div>
                     </div>
+
+                    {isHTTP && (
+                        <div className="grid grid-cols-3 gap-4">
+                            <div className="space-y-2">
+                                <label className="text-sm font-medium leading-none">Content Drift</label>
+                                <label className="flex h-10 items-center gap-2 text-sm">
+                                    <input
+                                        type="checkbox"
+                                        checked={formData.drift?.enabled || false}
+                                        onChange={(e) => setFormData(prev => ({ ...prev, drift: { ...prev.drift, enabled: e.target.checked } }))}
+                                    />
+                                    Flag unexpected body changes
+                                </label>
+                            </div>
+                            <div className="space-y-2">
+                                <label className="text-sm font-medium leading-none">Minimum Similarity (0-1)</label>
+                                <input
+                                    type="number"
+                                    min="0"
+                                    max="1"
+                                    step="0.01"
+                                    value={formData.drift?.threshold || ""}
+                                    onChange={(e) => setFormData(prev => ({ ...prev, drift: { ...prev.drift, enabled: prev.drift?.enabled || false, threshold: Number(e.target.value) || undefined } }))}
+                                    disabled={!formData.drift?.enabled}
+                                    className="flex h-10 w-full rounded-md border border-input bg-transparent px-3 py-2 text-sm shadow-sm transition-colors focus-visible:outline-none focus-visible:ring-1 focus-visible:ring-ring disabled:opacity-50"
+                                    placeholder="0.9"
+                                />
+                            </div>
+                            <div className="space-y-2">
+                                <label className="text-sm font-medium leading-none">Ignored Regions (regex per line)</label>
+                                <textarea
+                                    value={formData.drift?.ignore?.join("\n") || ""}
+                                    onChange={(e) => setFormData(prev => ({ ...prev, drift: { ...prev.drift, enabled: prev.drift?.enabled || false, ignore: e.target.value.split("\n").filter(Boolean) } }))}
+                                    disabled={!formData.drift?.enabled}
+                                    rows={2}
+                                    className="flex w-full rounded-md border border-input bg-transparent px-3 py-2 text-sm font-mono shadow-sm transition-colors focus-visible:outline-none focus-visible:ring-1 focus-visible:ring-ring disabled:opacity-50"
+                                    placeholder={'name="csrf" value="[^"]*"'}
+                                />
+                            </div>
+                        </div>
+                    )}
                 </div>
 
                 {/* Advanced & Tags */}
@@ -398,6 +741,27 @@ export function EndpointForm() {
                         />
                     </div>
 
+                    <div className="grid grid-cols-2 gap-4">
+                        <div className="space-y-2">
+                            <label className="text-sm font-medium leading-none">Allowed Certificate Issuers (comma separated)</label>
+                            <input
+                                value={formData.ssl?.allowed_issuers?.join(", ") || ""}
+                                onChange={(e) => setFormData(prev => ({ ...prev, ssl: { ...prev.ssl, allowed_issuers: splitList(e.target.value) } }))}
+                                className="flex h-10 w-full rounded-md border border-input bg-transparent px-3 py-2 text-sm shadow-sm transition-colors focus-visible:outline-none focus-visible:ring-1 focus-visible:ring-ring"
+                                placeholder="Let's Encrypt, DigiCert Inc"
+                            />
+                        </div>
+                        <div className="space-y-2">
+                            <label className="text-sm font-medium leading-none">SPKI Pins (comma separated)</label>
+                            <input
+                                value={formData.ssl?.spki_pins?.join(", ") || ""}
+                                onChange={(e) => setFormData(prev => ({ ...prev, ssl: { ...prev.ssl, spki_pins: splitList(e.target.value) } }))}
+                                className="flex h-10 w-full rounded-md border border-input bg-transparent px-3 py-2 text-sm font-mono shadow-sm transition-colors focus-visible:outline-none focus-visible:ring-1 focus-visible:ring-ring"
+                                placeholder="sha256/YLh1dUR9y6Kja30RrAn7JKnbQG/uEtLMkBgFF2Fuihg="
+                            />
+                        </div>
+                    </div>
+
                     <div className="pt-2">
                         <KeyValueEditor
                             title="Tags"
@@ -407,7 +771,39 @@ export function EndpointForm() {
                     </div>
                 </div>
 
-                <div className="flex justify-end pt-4">
+                {testResults && (
+                    <div className="space-y-2 bg-card p-6 rounded-xl border shadow">
+                        <h2 className="text-lg font-semibold">Test Result</h2>
+                        {testResults.map((r, i) => (
+                            <div key={i} className={`rounded-md p-3 text-sm ${r.success ? "bg-green-50 text-green-800" : "bg-red-50 text-red-700"}`}>
+                                <p className="font-medium">
+                                    {r.success ? "Passed" : "Failed"}
+                                    {r.status_code ? ` · ${r.status_code}` : ""}
+                                    {` · ${(r.duration / 1_000_000).toFixed(0)}ms`}
+                                    {r.ip_version && ` · IPv${r.ip_version}`}
+                                </p>
+                                {r.error && <p className="mt-1 break-all">{r.error}</p>}
+                                {r.snapshot?.body && (
+                                    <pre className="mt-2 text-xs font-mono bg-muted rounded-md p-3 overflow-x-auto whitespace-pre-wrap break-all text-foreground">
+                                        {r.snapshot.body}
+                                    </pre>
+                                )}
+                            </div>
+                        ))}
+                    </div>
+                )}
+
+                <div className="flex justify-end gap-2 pt-4">
+                    {!isPush && (
+                        <button
+                            type="button"
+                            onClick={handleTest}
+                            disabled={testing || !formData.url}
+                            className="inline-flex items-center justify-center rounded-md text-sm font-medium ring-offset-background transition-colors focus-visible:outline-none focus-visible:ring-2 focus-visible:ring-ring focus-visible:ring-offset-2 disabled:pointer-events-none disabled:opacity-50 border border-input bg-background hover:bg-accent hover:text-accent-foreground h-11 px-6 py-2"
+                        >
+                            {testing ? "Testing..." : <><Play className="mr-2 h-4 w-4" /> Test</>}
+                        </button>
+                    )}
                     <button
                         type="submit"
                         disabled={loading}
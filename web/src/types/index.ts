@@ -1,6 +1,7 @@
 export interface Endpoint {
     id: string;
     name: string;
+    type?: string;
     url: string;
     method: string;
     interval: number;
@@ -12,16 +13,154 @@ export interface Endpoint {
             type: string;
             pattern: string;
         };
+        json_assertions?: string[];
+        json_schema?: string;
+        headers?: {
+            name: string;
+            equals?: string;
+            matches?: string;
+        }[];
+        body_size?: {
+            min?: number;
+            max?: number;
+        };
+        checksum?: {
+            algorithm?: string;
+            value?: string;
+        };
+        content_not_match?: {
+            type: string;
+            pattern: string;
+        };
+        http_version?: "1.1" | "2"; // negotiated protocol the response must use
+        cookies?: {
+            name?: string; // every cookie when empty
+            secure?: boolean;
+            http_only?: boolean;
+            same_site?: "Strict" | "Lax" | "None";
+        }[];
+        script?: string; // JavaScript function body given the response
+        script_timeout?: number; // nanoseconds, default 100ms, at most 5s
+        script_memory?: number; // bytes, default 64 MiB, at most 256 MiB
     };
     ssl: {
         expiration_alert_days: number[];
+        min_tls_version?: string;
+        required_sans?: string[];
+        issuer_pattern?: string;
+        ocsp_check?: boolean;
+        allowed_issuers?: string[]; // common name, organization or DN of the leaf issuer
+        spki_pins?: string[]; // "sha256/<base64>" of a key in the chain
     };
     tags: Record<string, string>;
+    tcp?: {
+        send?: string;
+        expect?: string;
+    };
+    dns?: {
+        record_type?: string;
+        expected?: string[];
+        resolver?: string;
+    };
+    grpc?: {
+        service?: string;
+        tls?: boolean;
+        metadata?: Record<string, string>;
+        expected_status?: string;
+    };
+    websocket?: {
+        send?: string;
+        expect?: string;
+        subprotocols?: string[];
+    };
+    sse?: {
+        event?: string;
+        expect?: string;
+    };
+    push?: {
+        token?: string;
+        period?: number; // nanoseconds
+        schedule?: string;
+        grace?: number; // nanoseconds
+        max_runtime?: number; // nanoseconds
+    };
+    database?: {
+        username?: string;
+        password?: string; // env:OCTO_NAME or file:path under the secrets directory
+        database?: string;
+        query?: string;
+        expect?: string;
+        tls?: boolean;
+    };
+    fresh_connection?: boolean; // defaults to true
+    max_body_bytes?: number; // defaults to 10 MiB
+    cookie_jar?: "check" | "persistent";
+    page?: {
+        concurrency?: number; // assets fetched at once, default 6
+        max_assets?: number; // default 100
+    };
+    drift?: {
+        enabled: boolean;
+        threshold?: number; // minimum similarity, default 0.9
+        ignore?: string[]; // regexes removed before comparing
+    };
+    ct?: {
+        domains?: string[]; // subdomains included
+        allowed_issuers?: string[];
+        max_entries?: number; // asked per request, default 1000
+    };
+    domain?: {
+        rdap_server?: string; // from the IANA registry if empty
+        whois_server?: string; // host[:port], used when RDAP fails
+    };
+    http_version?: "auto" | "1.1" | "2" | "h2c";
+    resolve?: string[]; // host:port:address, like curl --resolve
+    dns_server?: string;
+    ip_version?: "4" | "6" | "both";
+    proxy?: ProxyConfig;
+    snapshot?: {
+        max_body?: number; // bytes, default 4096
+        redact_headers?: string[];
+    };
+    service?: {
+        username?: string;
+        password?: string; // env:OCTO_NAME or file:path under the secrets directory
+        tls?: boolean;
+        starttls?: boolean;
+        expect?: string;
+        path?: string;
+        host_key?: string;
+    };
+    body?: string;
+    steps?: EndpointStep[];
+}
+
+export interface EndpointStep {
+    name: string;
+    url: string;
+    method?: string;
+    headers?: Record<string, string>;
+    body?: string;
+    validation?: Partial<Endpoint["validation"]>;
+    extract?: {
+        name: string;
+        json_path?: string;
+        regex?: string;
+        header?: string;
+    }[];
+}
+
+export interface ProxyConfig {
+    url?: string; // http, https, socks5 or socks5h URL, or "direct"
+    username?: string;
+    password?: string; // env:OCTO_NAME or file:path under the secrets directory
+    no_proxy?: string[];
 }
 
 export interface GlobalConfig {
     check_interval: number;
     request_timeout: number;
+    proxy?: ProxyConfig;
 }
 
 export interface Config {
@@ -31,8 +170,20 @@ export interface Config {
 
 export interface Metric {
     endpoint_id: string;
+    type?: string;
     timestamp: string;
     duration_ns: number;
+    dns_ns?: number;
+    conn_ns?: number;
+    tls_ns?: number;
+    write_ns?: number;
+    ttfb_ns?: number;
+    transfer_ns?: number;
+    conn_reused?: boolean;
+    body_truncated?: boolean;
+    protocol?: string;
+    ip_version?: string;
+    proxy?: string;
     status_code: number;
     success: boolean;
     error?: string;
@@ -40,9 +191,127 @@ export interface Metric {
     cert_issuer?: string;
     cert_subject?: string;
     satellite_id?: string;
+    tls_version?: string;
+    cipher_suite?: string;
+    alpn?: string;
+    cert_sans?: string[];
+    cert_key_type?: string;
+    cert_key_size?: number;
+    cert_fingerprint?: string;
+    cert_chain?: CertInfo[];
+    ocsp_status?: string;
+    cert_mismatch?: boolean; // unexpected issuer or SPKI pin
+    failed_step?: string;
+    steps?: StepResult[];
+    snapshot?: Snapshot;
+    metrics?: Record<string, number>; // returned by a validation script
+    assets?: AssetResult[];
+    page_weight?: number; // bytes, page and assets
+    page_load_ns?: number;
+    domain_expiry?: string;
+    registrar?: string;
+    ct_entries?: CTEntry[];
+    ct_backlog?: number; // Log entries left to read by the next check
+    content_hash?: string;
+    content_similarity?: number;
+    content_drift?: boolean;
+}
+
+export interface ContentSnapshot {
+    timestamp: string;
+    satellite_id?: string;
+    content_hash: string;
+    similarity: number;
+    content: string;
+}
+
+// ContentDiff compares the last two bodies kept by drift detection
+export interface ContentDiff {
+    from?: ContentSnapshot;
+    to: ContentSnapshot;
+    diff: string; // unified diff
+}
+
+// CheckResult is a check result as returned by the run endpoints
+export interface CheckResult {
+    endpoint_id: string;
+    satellite_id?: string;
+    type?: string;
+    timestamp: string;
+    url: string;
+    status_code: number;
+    duration: number; // nanoseconds
+    success: boolean;
+    error: string;
+    ip_version?: string;
+    protocol?: string;
+    snapshot?: Snapshot;
+    metrics?: Record<string, number>;
+}
+
+export interface RunResponse {
+    results: CheckResult[];
+    satellites?: string[]; // asked to run the check, results arrive later
+}
+
+export interface CertInfo {
+    subject: string;
+    issuer: string;
+    serial_number: string;
+    not_before: string;
+    not_after: string;
+    sans?: string[];
+    key_type: string;
+    key_size: number;
+    signature_algorithm: string;
+    fingerprint: string;
+    spki: string; // sha256/<base64> pin of the public key
+    is_ca: boolean;
+}
+
+// CTEntry is a certificate for a watched domain found in a Certificate Transparency log
+export interface CTEntry {
+    index: number;
+    timestamp: string;
+    precert?: boolean;
+    subject: string;
+    issuer: string;
+    serial_number: string;
+    dns_names: string[];
+    not_before: string;
+    not_after: string;
+    allowed: boolean;
 }
 
 export interface User {
     username: string;
     role: string;
 }
+
+export interface Snapshot {
+    remote_ip?: string;
+    headers?: Record<string, string[]>;
+    body?: string;
+    truncated?: boolean;
+}
+
+export interface AssetResult {
+    url: string;
+    type: "script" | "stylesheet" | "image";
+    status_code: number;
+    bytes: number;
+    duration: number; // nanoseconds
+    error?: string;
+}
+
+export interface StepResult {
+    name: string;
+    url: string;
+    method: string;
+    status_code: number;
+    duration: number; // nanoseconds
+    ttfb: number; // nanoseconds
+    bytes_received: number;
+    success: boolean;
+    error?: string;
+}